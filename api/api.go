@@ -12,6 +12,8 @@ type Video struct {
 	CoverUrl      string `json:"cover_url,omitempty"`
 	FavoriteCount int64  `json:"favorite_count,omitempty"`
 	CommentCount  int64  `json:"comment_count,omitempty"`
+	PlayCount     int64  `json:"play_count,omitempty"`
+	AvgWatchTime  int64  `json:"avg_watch_time,omitempty"` // 平均观看时长(毫秒)
 	IsFavorite    bool   `json:"is_favorite,omitempty"`
 }
 
@@ -47,10 +49,109 @@ type MessagePushEvent struct {
 	MsgContent string `json:"msg_content,omitempty"`
 }
 
+type FavoriteEvent struct {
+	Version   int32  `json:"version"`
+	EventId   string `json:"event_id"`
+	UserId    int64  `json:"user_id"`
+	VideoId   int64  `json:"video_id"`
+	Action    int32  `json:"action"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// CommentEvent 发表或删除评论的事件，由dao层在写入评论的事务中通过发件箱发送
+type CommentEvent struct {
+	Version   int32  `json:"version"`
+	EventId   string `json:"event_id"`
+	CommentId int64  `json:"comment_id"`
+	UserId    int64  `json:"user_id"`
+	VideoId   int64  `json:"video_id"`
+	Action    int32  `json:"action"` // CommentAddAction或CommentDeleteAction
+	Timestamp int64  `json:"timestamp"`
+}
+
+// FollowEvent 关注或取消关注的事件，由dao层在写入关注记录的事务中通过发件箱发送
+type FollowEvent struct {
+	Version   int32  `json:"version"`
+	EventId   string `json:"event_id"`
+	UserId    int64  `json:"user_id"`
+	ToUserId  int64  `json:"to_user_id"`
+	Action    int32  `json:"action"` // FollowAction或UnFollowAction
+	Timestamp int64  `json:"timestamp"`
+}
+
+type UserRegisteredEvent struct {
+	EventId   string `json:"event_id"`
+	UserId    int64  `json:"user_id"`
+	UserName  string `json:"user_name"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type VideoPublishedEvent struct {
+	EventId   string `json:"event_id"`
+	VideoId   int64  `json:"video_id"`
+	UserId    int64  `json:"user_id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// EntityChangedEvent 数据库中实体变更的事件，各实例收到后删除对应的缓存
+type EntityChangedEvent struct {
+	Version   int32    `json:"version"`
+	EventId   string   `json:"event_id"`
+	Entity    string   `json:"entity"`
+	EntityId  int64    `json:"entity_id"`
+	UserName  string   `json:"user_name,omitempty"` // 实体为用户时的用户名，用于删除登录缓存
+	OwnerId   int64    `json:"owner_id,omitempty"`  // 实体为视频时的作者，用于删除发布列表缓存
+	Fields    []string `json:"fields,omitempty"`    // 变更的字段，为空表示新建
+	Timestamp int64    `json:"timestamp"`
+}
+
+type PlayEvent struct {
+	UserId        int64 `json:"user_id"`
+	VideoId       int64 `json:"video_id"`
+	EventType     int32 `json:"event_type"`
+	WatchDuration int64 `json:"watch_duration,omitempty"`
+	Timestamp     int64 `json:"timestamp"`
+}
+
+type DailyStat struct {
+	Date         string `json:"date"`
+	Views        int64  `json:"views"`
+	Favorites    int64  `json:"favorites"`
+	Comments     int64  `json:"comments"`
+	NewFollowers int64  `json:"new_followers,omitempty"`
+	AvgWatchTime int64  `json:"avg_watch_time"`
+}
+
+type VideoStatSeries struct {
+	VideoId int64       `json:"video_id"`
+	Series  []DailyStat `json:"series"`
+}
+
 type UserLoginResponse struct {
 	Response
-	UserId int64  `json:"user_id,omitempty"`
-	Token  string `json:"token"`
+	UserId       int64  `json:"user_id,omitempty"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// TooManyRequestsResponse 请求被限流时的返回，RetryAfterMs为客户端需要等待的时长
+type TooManyRequestsResponse struct {
+	Response
+	RetryAfterMs int64 `json:"retry_after_ms"`
+}
+
+type Session struct {
+	SessionId  string `json:"session_id"`
+	DeviceName string `json:"device_name"`
+	Ip         string `json:"ip"`
+	CreateTime int64  `json:"create_time"` // 登录时间，毫秒时间戳
+	LastSeen   int64  `json:"last_seen"`   // 最近一次请求的时间，毫秒时间戳，精确到分钟
+	Current    bool   `json:"current"`     // 是否为发起请求的会话
+}
+
+type SessionListResponse struct {
+	Response
+	SessionList []Session `json:"session_list"`
 }
 
 type UserResponse struct {
@@ -62,3 +163,36 @@ const (
 	FavoriteAction   = 1
 	UnFavoriteAction = 2
 )
+
+const (
+	CommentAddAction    = 1
+	CommentDeleteAction = 2
+)
+
+const CommentDateLayout = "01-02" // 评论日期的格式
+
+const (
+	FollowAction   = 1
+	UnFollowAction = 2
+)
+
+const FavoriteEventVersion = 1 // 点赞事件的格式版本，格式不兼容地变更时递增
+
+const EntityChangedEventVersion = 1 // 实体变更事件的格式版本
+
+const CommentEventVersion = 1 // 评论事件的格式版本
+
+const FollowEventVersion = 1 // 关注事件的格式版本
+
+const (
+	EntityUser  = "user"
+	EntityVideo = "video"
+)
+
+const (
+	PlayStartEvent    = 1 // 开始播放
+	PlayWatchEvent    = 2 // 上报观看时长
+	PlayCompleteEvent = 3 // 完整播放，同时上报观看时长
+)
+
+const StatDateLayout = "2006-01-02" // 创作者数据中日期的格式
@@ -23,11 +23,16 @@ const (
 	RecordNotExistErr     ErrorType = 10111
 	RecordAlreadyExistErr ErrorType = 10112
 	RecordNotMatchErr     ErrorType = 10113
+	ServiceUnavailableErr ErrorType = 10114
 
 	LogicErr            ErrorType = 10201
 	UnKnownActionType   ErrorType = 10202
 	InputFormatCheckErr ErrorType = 10203
 	GetDataErr          ErrorType = 10204
+	InvalidCursorErr    ErrorType = 10205
+	PermissionDeniedErr ErrorType = 10206
+	AccountLockedErr    ErrorType = 10207
+	TooManyRequestsErr  ErrorType = 10208
 )
 
 var ErrorCodeToMsg = map[ErrorType]string{
@@ -50,9 +55,14 @@ var ErrorCodeToMsg = map[ErrorType]string{
 	RecordNotExistErr:     "数据不存在",
 	RecordAlreadyExistErr: "数据已存在",
 	RecordNotMatchErr:     "Record doesn't match",
+	ServiceUnavailableErr: "服务繁忙，请稍后再试",
 
 	LogicErr:            "Inner logic error",
 	UnKnownActionType:   "Unknown Action Type",
 	InputFormatCheckErr: "Input formation error",
 	GetDataErr:          "Fail to get data from context",
+	InvalidCursorErr:    "Invalid page cursor",
+	PermissionDeniedErr: "Permission denied",
+	AccountLockedErr:    "登录失败次数过多，请稍后再试",
+	TooManyRequestsErr:  "请求过于频繁，请稍后再试",
 }
@@ -24,6 +24,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type VideoServiceInfoClient interface {
 	PublishVideoInfo(ctx context.Context, in *VideoServicePost, opts ...grpc.CallOption) (*wrapperspb.BoolValue, error)
+	GetPublishListInfo(ctx context.Context, in *UserPost, opts ...grpc.CallOption) (VideoServiceInfo_GetPublishListInfoClient, error)
 }
 
 type videoServiceInfoClient struct {
@@ -43,11 +44,44 @@ func (c *videoServiceInfoClient) PublishVideoInfo(ctx context.Context, in *Video
 	return out, nil
 }
 
+func (c *videoServiceInfoClient) GetPublishListInfo(ctx context.Context, in *UserPost, opts ...grpc.CallOption) (VideoServiceInfo_GetPublishListInfoClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VideoServiceInfo_ServiceDesc.Streams[0], "/video.VideoServiceInfo/getPublishListInfo", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &videoServiceInfoGetPublishListInfoClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VideoServiceInfo_GetPublishListInfoClient interface {
+	Recv() (*VideoServiceResp, error)
+	grpc.ClientStream
+}
+
+type videoServiceInfoGetPublishListInfoClient struct {
+	grpc.ClientStream
+}
+
+func (x *videoServiceInfoGetPublishListInfoClient) Recv() (*VideoServiceResp, error) {
+	m := new(VideoServiceResp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // VideoServiceInfoServer is the server API for VideoServiceInfo service.
 // All implementations must embed UnimplementedVideoServiceInfoServer
 // for forward compatibility
 type VideoServiceInfoServer interface {
 	PublishVideoInfo(context.Context, *VideoServicePost) (*wrapperspb.BoolValue, error)
+	GetPublishListInfo(*UserPost, VideoServiceInfo_GetPublishListInfoServer) error
 	mustEmbedUnimplementedVideoServiceInfoServer()
 }
 
@@ -58,6 +92,9 @@ type UnimplementedVideoServiceInfoServer struct {
 func (UnimplementedVideoServiceInfoServer) PublishVideoInfo(context.Context, *VideoServicePost) (*wrapperspb.BoolValue, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PublishVideoInfo not implemented")
 }
+func (UnimplementedVideoServiceInfoServer) GetPublishListInfo(*UserPost, VideoServiceInfo_GetPublishListInfoServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetPublishListInfo not implemented")
+}
 func (UnimplementedVideoServiceInfoServer) mustEmbedUnimplementedVideoServiceInfoServer() {}
 
 // UnsafeVideoServiceInfoServer may be embedded to opt out of forward compatibility for this service.
@@ -89,6 +126,27 @@ func _VideoServiceInfo_PublishVideoInfo_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VideoServiceInfo_GetPublishListInfo_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(UserPost)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VideoServiceInfoServer).GetPublishListInfo(m, &videoServiceInfoGetPublishListInfoServer{stream})
+}
+
+type VideoServiceInfo_GetPublishListInfoServer interface {
+	Send(*VideoServiceResp) error
+	grpc.ServerStream
+}
+
+type videoServiceInfoGetPublishListInfoServer struct {
+	grpc.ServerStream
+}
+
+func (x *videoServiceInfoGetPublishListInfoServer) Send(m *VideoServiceResp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // VideoServiceInfo_ServiceDesc is the grpc.ServiceDesc for VideoServiceInfo service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -101,6 +159,12 @@ var VideoServiceInfo_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _VideoServiceInfo_PublishVideoInfo_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "getPublishListInfo",
+			Handler:       _VideoServiceInfo_GetPublishListInfo_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "video_cs.proto",
 }
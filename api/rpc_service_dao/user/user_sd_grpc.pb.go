@@ -27,6 +27,7 @@ type UserDaoInfoClient interface {
 	GetUserInfoByUserName(ctx context.Context, in *UserDaoPost, opts ...grpc.CallOption) (*UserDaoInfoResp, error)
 	GetUserInfoByUserId(ctx context.Context, in *UserDaoPost, opts ...grpc.CallOption) (*UserDaoInfoResp, error)
 	GetUserInfoByUserNameAndPassword(ctx context.Context, in *UserDaoPost, opts ...grpc.CallOption) (*UserDaoInfoResp, error)
+	UpdatePassword(ctx context.Context, in *UserDaoPost, opts ...grpc.CallOption) (*wrapperspb.BoolValue, error)
 }
 
 type userDaoInfoClient struct {
@@ -73,6 +74,15 @@ func (c *userDaoInfoClient) GetUserInfoByUserNameAndPassword(ctx context.Context
 	return out, nil
 }
 
+func (c *userDaoInfoClient) UpdatePassword(ctx context.Context, in *UserDaoPost, opts ...grpc.CallOption) (*wrapperspb.BoolValue, error) {
+	out := new(wrapperspb.BoolValue)
+	err := c.cc.Invoke(ctx, "/user.UserDaoInfo/updatePassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserDaoInfoServer is the server API for UserDaoInfo service.
 // All implementations must embed UnimplementedUserDaoInfoServer
 // for forward compatibility
@@ -81,6 +91,7 @@ type UserDaoInfoServer interface {
 	GetUserInfoByUserName(context.Context, *UserDaoPost) (*UserDaoInfoResp, error)
 	GetUserInfoByUserId(context.Context, *UserDaoPost) (*UserDaoInfoResp, error)
 	GetUserInfoByUserNameAndPassword(context.Context, *UserDaoPost) (*UserDaoInfoResp, error)
+	UpdatePassword(context.Context, *UserDaoPost) (*wrapperspb.BoolValue, error)
 	mustEmbedUnimplementedUserDaoInfoServer()
 }
 
@@ -100,6 +111,9 @@ func (UnimplementedUserDaoInfoServer) GetUserInfoByUserId(context.Context, *User
 func (UnimplementedUserDaoInfoServer) GetUserInfoByUserNameAndPassword(context.Context, *UserDaoPost) (*UserDaoInfoResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUserInfoByUserNameAndPassword not implemented")
 }
+func (UnimplementedUserDaoInfoServer) UpdatePassword(context.Context, *UserDaoPost) (*wrapperspb.BoolValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePassword not implemented")
+}
 func (UnimplementedUserDaoInfoServer) mustEmbedUnimplementedUserDaoInfoServer() {}
 
 // UnsafeUserDaoInfoServer may be embedded to opt out of forward compatibility for this service.
@@ -185,6 +199,24 @@ func _UserDaoInfo_GetUserInfoByUserNameAndPassword_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserDaoInfo_UpdatePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserDaoPost)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserDaoInfoServer).UpdatePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user.UserDaoInfo/updatePassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserDaoInfoServer).UpdatePassword(ctx, req.(*UserDaoPost))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserDaoInfo_ServiceDesc is the grpc.ServiceDesc for UserDaoInfo service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -208,6 +240,10 @@ var UserDaoInfo_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "getUserInfoByUserNameAndPassword",
 			Handler:    _UserDaoInfo_GetUserInfoByUserNameAndPassword_Handler,
 		},
+		{
+			MethodName: "updatePassword",
+			Handler:    _UserDaoInfo_UpdatePassword_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "user_sd.proto",
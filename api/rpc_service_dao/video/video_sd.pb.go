@@ -71,6 +71,9 @@ type VideoDaoMsg struct {
 	CommentCount  int32  `protobuf:"varint,5,opt,name=CommentCount,proto3" json:"CommentCount,omitempty"`
 	PlayURL       string `protobuf:"bytes,6,opt,name=playURL,proto3" json:"playURL,omitempty"`
 	CoverURL      string `protobuf:"bytes,7,opt,name=coverURL,proto3" json:"coverURL,omitempty"`
+	PlayCount     int64  `protobuf:"varint,8,opt,name=playCount,proto3" json:"playCount,omitempty"`
+	WatchTime     int64  `protobuf:"varint,9,opt,name=watchTime,proto3" json:"watchTime,omitempty"`
+	WatchCount    int64  `protobuf:"varint,10,opt,name=watchCount,proto3" json:"watchCount,omitempty"`
 }
 
 func (x *VideoDaoMsg) Reset() {
@@ -154,6 +157,145 @@ func (x *VideoDaoMsg) GetCoverURL() string {
 	return ""
 }
 
+func (x *VideoDaoMsg) GetPlayCount() int64 {
+	if x != nil {
+		return x.PlayCount
+	}
+	return 0
+}
+
+func (x *VideoDaoMsg) GetWatchTime() int64 {
+	if x != nil {
+		return x.WatchTime
+	}
+	return 0
+}
+
+func (x *VideoDaoMsg) GetWatchCount() int64 {
+	if x != nil {
+		return x.WatchCount
+	}
+	return 0
+}
+
+type PublishListPost struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UserId int64 `protobuf:"varint,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Seq    int64 `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"` //上一页最后一个视频的主键，为0时从头开始
+	Limit  int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *PublishListPost) Reset() {
+	*x = PublishListPost{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_video_sd_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PublishListPost) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishListPost) ProtoMessage() {}
+
+func (x *PublishListPost) ProtoReflect() protoreflect.Message {
+	mi := &file_video_sd_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishListPost.ProtoReflect.Descriptor instead.
+func (*PublishListPost) Descriptor() ([]byte, []int) {
+	return file_video_sd_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PublishListPost) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *PublishListPost) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *PublishListPost) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type PublishIdMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	VideoId int64 `protobuf:"varint,1,opt,name=videoId,proto3" json:"videoId,omitempty"`
+	Seq     int64 `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"` //视频的主键，用于keyset分页
+}
+
+func (x *PublishIdMsg) Reset() {
+	*x = PublishIdMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_video_sd_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PublishIdMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishIdMsg) ProtoMessage() {}
+
+func (x *PublishIdMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_video_sd_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishIdMsg.ProtoReflect.Descriptor instead.
+func (*PublishIdMsg) Descriptor() ([]byte, []int) {
+	return file_video_sd_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PublishIdMsg) GetVideoId() int64 {
+	if x != nil {
+		return x.VideoId
+	}
+	return 0
+}
+
+func (x *PublishIdMsg) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
 type VideoDaoPost struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -169,7 +311,7 @@ type VideoDaoPost struct {
 func (x *VideoDaoPost) Reset() {
 	*x = VideoDaoPost{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_video_sd_proto_msgTypes[2]
+		mi := &file_video_sd_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -182,7 +324,7 @@ func (x *VideoDaoPost) String() string {
 func (*VideoDaoPost) ProtoMessage() {}
 
 func (x *VideoDaoPost) ProtoReflect() protoreflect.Message {
-	mi := &file_video_sd_proto_msgTypes[2]
+	mi := &file_video_sd_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -195,7 +337,7 @@ func (x *VideoDaoPost) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VideoDaoPost.ProtoReflect.Descriptor instead.
 func (*VideoDaoPost) Descriptor() ([]byte, []int) {
-	return file_video_sd_proto_rawDescGZIP(), []int{2}
+	return file_video_sd_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *VideoDaoPost) GetVideoId() int64 {
@@ -240,7 +382,7 @@ var file_video_sd_proto_rawDesc = []byte{
 	0x12, 0x04, 0x75, 0x73, 0x65, 0x72, 0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70,
 	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x77, 0x72, 0x61, 0x70, 0x70, 0x65, 0x72, 0x73,
 	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x0a, 0x0a, 0x08, 0x54, 0x69, 0x6d, 0x65, 0x50, 0x6f,
-	0x73, 0x74, 0x22, 0xdd, 0x01, 0x0a, 0x0b, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x4d,
+	0x73, 0x74, 0x22, 0xb9, 0x02, 0x0a, 0x0b, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x4d,
 	0x73, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09,
 	0x76, 0x69, 0x64, 0x65, 0x6f, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
@@ -254,41 +396,55 @@ var file_video_sd_proto_rawDesc = []byte{
 	0x70, 0x6c, 0x61, 0x79, 0x55, 0x52, 0x4c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70,
 	0x6c, 0x61, 0x79, 0x55, 0x52, 0x4c, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x55,
 	0x52, 0x4c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x55,
-	0x52, 0x4c, 0x22, 0x94, 0x01, 0x0a, 0x0c, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x50,
-	0x6f, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x12, 0x16, 0x0a,
-	0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x75,
-	0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x4e, 0x61,
-	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x4e,
-	0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x6c, 0x61, 0x79, 0x55, 0x52, 0x4c, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x6c, 0x61, 0x79, 0x55, 0x52, 0x4c, 0x12, 0x1a, 0x0a,
-	0x08, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x08, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x32, 0xb0, 0x02, 0x0a, 0x0c, 0x56, 0x69,
-	0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x3a, 0x0a, 0x08, 0x61, 0x64,
-	0x64, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x12, 0x12, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x56, 0x69,
-	0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x50, 0x6f, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x42, 0x6f, 0x6f,
-	0x6c, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x4e, 0x0a, 0x10, 0x67, 0x65, 0x74, 0x50, 0x75, 0x62,
-	0x6c, 0x69, 0x73, 0x68, 0x49, 0x64, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x1b, 0x2e, 0x67, 0x6f, 0x6f,
-	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x49, 0x6e, 0x74,
-	0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x1a, 0x1b, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x52, 0x4c, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x6c, 0x61, 0x79, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x70, 0x6c, 0x61, 0x79, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x12, 0x1c, 0x0a, 0x09, 0x77, 0x61, 0x74, 0x63, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x77, 0x61, 0x74, 0x63, 0x68, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1e,
+	0x0a, 0x0a, 0x77, 0x61, 0x74, 0x63, 0x68, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0a, 0x77, 0x61, 0x74, 0x63, 0x68, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x51,
+	0x0a, 0x0f, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x6f, 0x73,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x65, 0x71,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x65, 0x71, 0x12, 0x14, 0x0a, 0x05, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69,
+	0x74, 0x22, 0x3a, 0x0a, 0x0c, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x49, 0x64, 0x4d, 0x73,
+	0x67, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x73,
+	0x65, 0x71, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x73, 0x65, 0x71, 0x22, 0x94, 0x01,
+	0x0a, 0x0c, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x50, 0x6f, 0x73, 0x74, 0x12, 0x18,
+	0x0a, 0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x07, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x75, 0x73, 0x65, 0x72,
+	0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64,
+	0x12, 0x1c, 0x0a, 0x09, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x70, 0x6c, 0x61, 0x79, 0x55, 0x52, 0x4c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x70, 0x6c, 0x61, 0x79, 0x55, 0x52, 0x4c, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x76, 0x65,
+	0x72, 0x55, 0x52, 0x4c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x76, 0x65,
+	0x72, 0x55, 0x52, 0x4c, 0x32, 0xa1, 0x02, 0x0a, 0x0c, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61,
+	0x6f, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x3a, 0x0a, 0x08, 0x61, 0x64, 0x64, 0x56, 0x69, 0x64, 0x65,
+	0x6f, 0x12, 0x12, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61,
+	0x6f, 0x50, 0x6f, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x42, 0x6f, 0x6f, 0x6c, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x3f, 0x0a, 0x10, 0x67, 0x65, 0x74, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x49,
+	0x64, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x15, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x50, 0x75, 0x62,
+	0x6c, 0x69, 0x73, 0x68, 0x4c, 0x69, 0x73, 0x74, 0x50, 0x6f, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x75,
+	0x73, 0x65, 0x72, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x49, 0x64, 0x4d, 0x73, 0x67,
+	0x30, 0x01, 0x12, 0x43, 0x0a, 0x11, 0x67, 0x65, 0x74, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x42, 0x79,
+	0x56, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x12, 0x1b, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
 	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56,
-	0x61, 0x6c, 0x75, 0x65, 0x30, 0x01, 0x12, 0x43, 0x0a, 0x11, 0x67, 0x65, 0x74, 0x56, 0x69, 0x64,
-	0x65, 0x6f, 0x42, 0x79, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64, 0x12, 0x1b, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x49, 0x6e,
-	0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x1a, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e,
-	0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61, 0x6f, 0x4d, 0x73, 0x67, 0x12, 0x4f, 0x0a, 0x19, 0x67,
-	0x65, 0x74, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x42, 0x79, 0x56, 0x69, 0x64,
-	0x65, 0x6f, 0x49, 0x64, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x1b, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
-	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x49, 0x6e, 0x74, 0x36, 0x34,
-	0x56, 0x61, 0x6c, 0x75, 0x65, 0x1a, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x56, 0x69, 0x64,
-	0x65, 0x6f, 0x44, 0x61, 0x6f, 0x4d, 0x73, 0x67, 0x28, 0x01, 0x30, 0x01, 0x42, 0x4f, 0x5a, 0x4d,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x59, 0x4f, 0x4a, 0x49, 0x41,
-	0x2d, 0x79, 0x75, 0x6b, 0x69, 0x6e, 0x6f, 0x2f, 0x73, 0x69, 0x6d, 0x70, 0x6c, 0x65, 0x2d, 0x64,
-	0x6f, 0x75, 0x79, 0x69, 0x6e, 0x2d, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2f, 0x61, 0x70,
-	0x69, 0x2f, 0x72, 0x70, 0x63, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x6c, 0x65, 0x72,
-	0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2f, 0x75, 0x73, 0x65, 0x72, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x61, 0x6c, 0x75, 0x65, 0x1a, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x56, 0x69, 0x64, 0x65,
+	0x6f, 0x44, 0x61, 0x6f, 0x4d, 0x73, 0x67, 0x12, 0x4f, 0x0a, 0x19, 0x67, 0x65, 0x74, 0x56, 0x69,
+	0x64, 0x65, 0x6f, 0x4c, 0x69, 0x73, 0x74, 0x42, 0x79, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x49, 0x64,
+	0x4c, 0x69, 0x73, 0x74, 0x12, 0x1b, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x1a, 0x11, 0x2e, 0x75, 0x73, 0x65, 0x72, 0x2e, 0x56, 0x69, 0x64, 0x65, 0x6f, 0x44, 0x61,
+	0x6f, 0x4d, 0x73, 0x67, 0x28, 0x01, 0x30, 0x01, 0x42, 0x4f, 0x5a, 0x4d, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x59, 0x4f, 0x4a, 0x49, 0x41, 0x2d, 0x79, 0x75, 0x6b,
+	0x69, 0x6e, 0x6f, 0x2f, 0x73, 0x69, 0x6d, 0x70, 0x6c, 0x65, 0x2d, 0x64, 0x6f, 0x75, 0x79, 0x69,
+	0x6e, 0x2d, 0x62, 0x61, 0x63, 0x6b, 0x65, 0x6e, 0x64, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x72, 0x70,
+	0x63, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x6c, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2f, 0x75, 0x73, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -303,21 +459,23 @@ func file_video_sd_proto_rawDescGZIP() []byte {
 	return file_video_sd_proto_rawDescData
 }
 
-var file_video_sd_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_video_sd_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_video_sd_proto_goTypes = []interface{}{
 	(*TimePost)(nil),              // 0: user.TimePost
 	(*VideoDaoMsg)(nil),           // 1: user.VideoDaoMsg
-	(*VideoDaoPost)(nil),          // 2: user.VideoDaoPost
-	(*wrapperspb.Int64Value)(nil), // 3: google.protobuf.Int64Value
-	(*wrapperspb.BoolValue)(nil),  // 4: google.protobuf.BoolValue
+	(*PublishListPost)(nil),       // 2: user.PublishListPost
+	(*PublishIdMsg)(nil),          // 3: user.PublishIdMsg
+	(*VideoDaoPost)(nil),          // 4: user.VideoDaoPost
+	(*wrapperspb.Int64Value)(nil), // 5: google.protobuf.Int64Value
+	(*wrapperspb.BoolValue)(nil),  // 6: google.protobuf.BoolValue
 }
 var file_video_sd_proto_depIdxs = []int32{
-	2, // 0: user.VideoDaoInfo.addVideo:input_type -> user.VideoDaoPost
-	3, // 1: user.VideoDaoInfo.getPublishIdList:input_type -> google.protobuf.Int64Value
-	3, // 2: user.VideoDaoInfo.getVideoByVideoId:input_type -> google.protobuf.Int64Value
-	3, // 3: user.VideoDaoInfo.getVideoListByVideoIdList:input_type -> google.protobuf.Int64Value
-	4, // 4: user.VideoDaoInfo.addVideo:output_type -> google.protobuf.BoolValue
-	3, // 5: user.VideoDaoInfo.getPublishIdList:output_type -> google.protobuf.Int64Value
+	4, // 0: user.VideoDaoInfo.addVideo:input_type -> user.VideoDaoPost
+	2, // 1: user.VideoDaoInfo.getPublishIdList:input_type -> user.PublishListPost
+	5, // 2: user.VideoDaoInfo.getVideoByVideoId:input_type -> google.protobuf.Int64Value
+	5, // 3: user.VideoDaoInfo.getVideoListByVideoIdList:input_type -> google.protobuf.Int64Value
+	6, // 4: user.VideoDaoInfo.addVideo:output_type -> google.protobuf.BoolValue
+	3, // 5: user.VideoDaoInfo.getPublishIdList:output_type -> user.PublishIdMsg
 	1, // 6: user.VideoDaoInfo.getVideoByVideoId:output_type -> user.VideoDaoMsg
 	1, // 7: user.VideoDaoInfo.getVideoListByVideoIdList:output_type -> user.VideoDaoMsg
 	4, // [4:8] is the sub-list for method output_type
@@ -358,6 +516,30 @@ func file_video_sd_proto_init() {
 			}
 		}
 		file_video_sd_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PublishListPost); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_video_sd_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PublishIdMsg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_video_sd_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*VideoDaoPost); i {
 			case 0:
 				return &v.state
@@ -376,7 +558,7 @@ func file_video_sd_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_video_sd_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
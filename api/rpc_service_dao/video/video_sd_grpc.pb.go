@@ -24,7 +24,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type VideoDaoInfoClient interface {
 	AddVideo(ctx context.Context, in *VideoDaoPost, opts ...grpc.CallOption) (*wrapperspb.BoolValue, error)
-	GetPublishIdList(ctx context.Context, in *wrapperspb.Int64Value, opts ...grpc.CallOption) (VideoDaoInfo_GetPublishIdListClient, error)
+	GetPublishIdList(ctx context.Context, in *PublishListPost, opts ...grpc.CallOption) (VideoDaoInfo_GetPublishIdListClient, error)
 	GetVideoByVideoId(ctx context.Context, in *wrapperspb.Int64Value, opts ...grpc.CallOption) (*VideoDaoMsg, error)
 	GetVideoListByVideoIdList(ctx context.Context, opts ...grpc.CallOption) (VideoDaoInfo_GetVideoListByVideoIdListClient, error)
 }
@@ -46,7 +46,7 @@ func (c *videoDaoInfoClient) AddVideo(ctx context.Context, in *VideoDaoPost, opt
 	return out, nil
 }
 
-func (c *videoDaoInfoClient) GetPublishIdList(ctx context.Context, in *wrapperspb.Int64Value, opts ...grpc.CallOption) (VideoDaoInfo_GetPublishIdListClient, error) {
+func (c *videoDaoInfoClient) GetPublishIdList(ctx context.Context, in *PublishListPost, opts ...grpc.CallOption) (VideoDaoInfo_GetPublishIdListClient, error) {
 	stream, err := c.cc.NewStream(ctx, &VideoDaoInfo_ServiceDesc.Streams[0], "/user.VideoDaoInfo/getPublishIdList", opts...)
 	if err != nil {
 		return nil, err
@@ -62,7 +62,7 @@ func (c *videoDaoInfoClient) GetPublishIdList(ctx context.Context, in *wrappersp
 }
 
 type VideoDaoInfo_GetPublishIdListClient interface {
-	Recv() (*wrapperspb.Int64Value, error)
+	Recv() (*PublishIdMsg, error)
 	grpc.ClientStream
 }
 
@@ -70,8 +70,8 @@ type videoDaoInfoGetPublishIdListClient struct {
 	grpc.ClientStream
 }
 
-func (x *videoDaoInfoGetPublishIdListClient) Recv() (*wrapperspb.Int64Value, error) {
-	m := new(wrapperspb.Int64Value)
+func (x *videoDaoInfoGetPublishIdListClient) Recv() (*PublishIdMsg, error) {
+	m := new(PublishIdMsg)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
@@ -123,7 +123,7 @@ func (x *videoDaoInfoGetVideoListByVideoIdListClient) Recv() (*VideoDaoMsg, erro
 // for forward compatibility
 type VideoDaoInfoServer interface {
 	AddVideo(context.Context, *VideoDaoPost) (*wrapperspb.BoolValue, error)
-	GetPublishIdList(*wrapperspb.Int64Value, VideoDaoInfo_GetPublishIdListServer) error
+	GetPublishIdList(*PublishListPost, VideoDaoInfo_GetPublishIdListServer) error
 	GetVideoByVideoId(context.Context, *wrapperspb.Int64Value) (*VideoDaoMsg, error)
 	GetVideoListByVideoIdList(VideoDaoInfo_GetVideoListByVideoIdListServer) error
 	mustEmbedUnimplementedVideoDaoInfoServer()
@@ -136,11 +136,11 @@ type UnimplementedVideoDaoInfoServer struct {
 func (UnimplementedVideoDaoInfoServer) AddVideo(context.Context, *VideoDaoPost) (*wrapperspb.BoolValue, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddVideo not implemented")
 }
-func (UnimplementedVideoDaoInfoServer) GetPublishIdList(*wrapperspb.Int64Value, VideoDaoInfo_GetPublishIdListServer) error {
+func (UnimplementedVideoDaoInfoServer) GetPublishIdList(*PublishListPost, VideoDaoInfo_GetPublishIdListServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetPublishIdList not implemented")
 }
 func (UnimplementedVideoDaoInfoServer) GetVideoByVideoId(context.Context, *wrapperspb.Int64Value) (*VideoDaoMsg, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetVideoByVideoIdInfo not implemented")
+	return nil, status.Errorf(codes.Unimplemented, "method GetVideoByVideoId not implemented")
 }
 func (UnimplementedVideoDaoInfoServer) GetVideoListByVideoIdList(VideoDaoInfo_GetVideoListByVideoIdListServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetVideoListByVideoIdList not implemented")
@@ -177,7 +177,7 @@ func _VideoDaoInfo_AddVideo_Handler(srv interface{}, ctx context.Context, dec fu
 }
 
 func _VideoDaoInfo_GetPublishIdList_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(wrapperspb.Int64Value)
+	m := new(PublishListPost)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -185,7 +185,7 @@ func _VideoDaoInfo_GetPublishIdList_Handler(srv interface{}, stream grpc.ServerS
 }
 
 type VideoDaoInfo_GetPublishIdListServer interface {
-	Send(*wrapperspb.Int64Value) error
+	Send(*PublishIdMsg) error
 	grpc.ServerStream
 }
 
@@ -193,7 +193,7 @@ type videoDaoInfoGetPublishIdListServer struct {
 	grpc.ServerStream
 }
 
-func (x *videoDaoInfoGetPublishIdListServer) Send(m *wrapperspb.Int64Value) error {
+func (x *videoDaoInfoGetPublishIdListServer) Send(m *PublishIdMsg) error {
 	return x.ServerStream.SendMsg(m)
 }
 
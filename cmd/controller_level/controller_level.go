@@ -6,25 +6,36 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/init/router"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"time"
 )
 
 // initAll 初始化所有的部分
 func initAll() {
 	//Init basic operators
 	initialization.InitConfig()
+	initialization.InitRDB()
 
 	//Init Utils
 	logger.InitLogger(initialization.LogConf)
-	jwt.InitJwt()
+	jwt.InitJwt(initialization.JwtConf, initialization.GetRDB())
 	cronUtils.InitCron()
 }
 
 func main() {
 	initAll()
-	hServer := server.Default(server.WithHostPorts(fmt.Sprintf("127.0.0.1:%s", initialization.Port)))
+	lifecycle.InitLifecycle(initialization.ShutdownConf)
+	lifecycle.OnShutdown(lifecycle.PhaseTelemetry, "tracing", initialization.InitTracing("controller"))
+	metrics.Serve(initialization.MetricsConf.ControllerPort)
+	hServer := server.Default(server.WithHostPorts(fmt.Sprintf("127.0.0.1:%s", initialization.Port)),
+		server.WithExitWaitTime(time.Duration(initialization.ShutdownConf.Timeout)*time.Second))
 
 	router.InitRouter(hServer)
+	// 收到SIGTERM后Spin停止接收请求并等待处理中的请求完成，之后执行其余的关闭步骤
+	hServer.SetCustomSignalWaiter(lifecycle.Wait)
 	hServer.Spin()
+	lifecycle.Shutdown()
 }
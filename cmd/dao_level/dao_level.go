@@ -6,67 +6,47 @@ import (
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"google.golang.org/grpc"
-	"net"
-	"sync"
 )
 
 func initAll() {
 	initialization.InitConfig()
 	initialization.InitDB()
-	initialization.InitKafkaClient()
 
 	//Init Utils
 	logger.InitLogger(initialization.LogConf)
+	if err := mqUtils.InitMessageQueue(initialization.MQConf); err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("fail to init message queue")
+	}
 	cronUtils.InitCron()
 
 	//Init lower Levels
 	dao.DaoInitialization()
 }
 
-var wg sync.WaitGroup
-
 func main() {
 	initAll()
+	lifecycle.InitLifecycle(initialization.ShutdownConf)
+	lifecycle.OnShutdown(lifecycle.PhaseTelemetry, "tracing", initialization.InitTracing("dao"))
+	metrics.Serve(initialization.MetricsConf.DaoPort)
 
-	// 开一个协程监听UserDao端口
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		lis, err := net.Listen("tcp", initialization.RpcSDConf.UserServicePort)
-		if err != nil {
-			logger.GlobalLogger.Fatal().Err(err)
-		} else {
-			logger.GlobalLogger.Printf("Successfully Listen At port %v", initialization.RpcSDConf.UserServicePort)
-		}
-		s := grpc.NewServer()
+	errCh := make(chan error, 2)
+	rpcUtils.Serve("userDao", initialization.RpcSDConf.UserServicePort, func(s *grpc.Server) {
 		pbuser.RegisterUserDaoInfoServer(s, dao.GetUserDaoInstance())
-		logger.GlobalLogger.Printf("Successfully register userInfo Server")
-		if err = s.Serve(lis); err != nil {
-			logger.GlobalLogger.Printf("Serving userInfo error")
-			panic(err)
-		}
-	}()
-
-	// 开一个协程监听videoDao端口
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		lis, err := net.Listen("tcp", initialization.RpcSDConf.VideoServicePort)
-		if err != nil {
-			logger.GlobalLogger.Fatal().Err(err)
-		} else {
-			logger.GlobalLogger.Printf("Successfully Listen At port %v", initialization.RpcSDConf.VideoServicePort)
-		}
-		s := grpc.NewServer()
+	}, errCh)
+	rpcUtils.Serve("videoDao", initialization.RpcSDConf.VideoServicePort, func(s *grpc.Server) {
 		pbvideo.RegisterVideoDaoInfoServer(s, dao.GetVideoDaoInstance())
-		logger.GlobalLogger.Printf("Successfully register videoInfo Server")
-		if err = s.Serve(lis); err != nil {
-			logger.GlobalLogger.Printf("Serving videoInfo error")
-			panic(err)
-		}
-	}()
-
-	wg.Wait()
+	}, errCh)
+
+	// 收到SIGTERM后等待处理中的请求完成，再关闭消息队列的消费者与数据库连接
+	err := lifecycle.Wait(errCh)
+	lifecycle.Shutdown()
+	if err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("Serving error")
+	}
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"os"
+)
+
+// 手动执行一次点赞数对账，以点赞记录为准检查videos表与redis中的点赞数
+// 用法：reconcile_favorite [-dry-run]
+
+func initAll() {
+	initialization.InitConfig()
+	initialization.InitDB()
+	initialization.InitRDB()
+
+	//Init Utils
+	logger.InitLogger(initialization.LogConf)
+
+	//只需要数据库，不启动消息队列的消费者
+	dao.DaoDataBaseInitialization()
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只报告不一致的点赞数，不进行修复")
+	flag.Parse()
+
+	initAll()
+	report, err := service.GetFavoriteServiceInstance().ReconcileFavoriteCounts(!*dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconcile failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("scanned=%d skipped=%d db_drift=%d redis_drift=%d total_drift=%d repaired=%d duration=%v\n",
+		report.Scanned, report.Skipped, report.DBDrift, report.RedisDrift, report.TotalDrift, report.Repaired, report.Duration)
+}
@@ -6,11 +6,14 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/idGenerator"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"google.golang.org/grpc"
-	"net"
-	"sync"
 )
 
 func initAll() {
@@ -18,41 +21,36 @@ func initAll() {
 	initialization.InitDB()
 	initialization.InitOSS()
 	initialization.InitRDB()
-	initialization.InitKafkaServer()
-	initialization.InitKafkaClient()
 
 	//Init Utils
 	logger.InitLogger(initialization.LogConf)
-	jwt.InitJwt()
+	if err := mqUtils.InitMessageQueue(initialization.MQConf); err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("fail to init message queue")
+	}
+	idGenerator.InitIdGenerator(initialization.IdConf, initialization.GetRDB())
+	jwt.InitJwt(initialization.JwtConf, initialization.GetRDB())
 	cronUtils.InitCron()
 
 	//Init lower Levels
 	dao.DaoInitialization()
+	service.ServiceInitialization()
 }
 
-var wg sync.WaitGroup
-
 func main() {
 	initAll()
+	lifecycle.InitLifecycle(initialization.ShutdownConf)
+	lifecycle.OnShutdown(lifecycle.PhaseTelemetry, "tracing", initialization.InitTracing("service-dao"))
+	metrics.Serve(initialization.MetricsConf.ServiceDaoPort)
 
-	// 开一个协程监听UserService端口
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		lis, err := net.Listen("tcp", initialization.RpcCSConf.UserServicePort)
-		if err != nil {
-			logger.GlobalLogger.Fatal().Err(err)
-		} else {
-			logger.GlobalLogger.Printf("Successfully Listen At port %v", initialization.RpcCSConf.UserServicePort)
-		}
-		s := grpc.NewServer()
+	errCh := make(chan error, 1)
+	rpcUtils.Serve("userService", initialization.RpcCSConf.UserServicePort, func(s *grpc.Server) {
 		pbuser.RegisterUserServiceInfoServer(s, service.GetUserServiceInstance())
-		logger.GlobalLogger.Printf("Successfully register userInfo Server")
-		if err = s.Serve(lis); err != nil {
-			logger.GlobalLogger.Printf("Serving userInfo error")
-			panic(err)
-		}
-	}()
-
-	wg.Wait()
+	}, errCh)
+
+	// 收到SIGTERM后等待处理中的请求完成，将redis中的计数写入数据库，再关闭消息队列与连接
+	err := lifecycle.Wait(errCh)
+	lifecycle.Shutdown()
+	if err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("Serving error")
+	}
 }
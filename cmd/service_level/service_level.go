@@ -4,66 +4,56 @@ import (
 	pbuser "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_controller_service/user"
 	pbvideo "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_controller_service/video"
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/idGenerator"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"google.golang.org/grpc"
-	"net"
-	"sync"
 )
 
 func initAll() {
 	initialization.InitConfig()
+	initialization.InitDB()
 	initialization.InitOSS()
 	initialization.InitRDB()
-	initialization.InitKafkaServer()
 
 	//Init Utils
 	logger.InitLogger(initialization.LogConf)
+	if err := mqUtils.InitMessageQueue(initialization.MQConf); err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("fail to init message queue")
+	}
+	idGenerator.InitIdGenerator(initialization.IdConf, initialization.GetRDB())
 	cronUtils.InitCron()
-}
 
-var wg sync.WaitGroup
+	//Init background tasks
+	// 消费者与定时任务(热榜、播放统计、创作者统计、写入点赞数等)直接读写数据库，因此需要获取DB
+	dao.DaoDataBaseInitialization()
+	service.ServiceInitialization()
+}
 
 func main() {
 	initAll()
+	lifecycle.InitLifecycle(initialization.ShutdownConf)
+	lifecycle.OnShutdown(lifecycle.PhaseTelemetry, "tracing", initialization.InitTracing("service"))
+	metrics.Serve(initialization.MetricsConf.ServicePort)
 
-	// 开一个协程监听UserService端口
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		lis, err := net.Listen("tcp", initialization.RpcCSConf.UserServicePort)
-		if err != nil {
-			logger.GlobalLogger.Fatal().Err(err)
-		} else {
-			logger.GlobalLogger.Printf("Successfully Listen At port %v", initialization.RpcCSConf.UserServicePort)
-		}
-		s := grpc.NewServer()
+	errCh := make(chan error, 2)
+	rpcUtils.Serve("userService", initialization.RpcCSConf.UserServicePort, func(s *grpc.Server) {
 		pbuser.RegisterUserServiceInfoServer(s, service.GetUserServiceInstance())
-		logger.GlobalLogger.Printf("Successfully register userServiceInfo Server")
-		if err = s.Serve(lis); err != nil {
-			logger.GlobalLogger.Printf("Serving userInfo error")
-			panic(err)
-		}
-	}()
-
-	//开一个协程监听VideoService端口
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		lis, err := net.Listen("tcp", initialization.RpcCSConf.VideoServicePort)
-		if err != nil {
-			logger.GlobalLogger.Fatal().Err(err)
-		} else {
-			logger.GlobalLogger.Printf("Successfully Listen At port %v", initialization.RpcCSConf.VideoServicePort)
-		}
-		s := grpc.NewServer()
+	}, errCh)
+	rpcUtils.Serve("videoService", initialization.RpcCSConf.VideoServicePort, func(s *grpc.Server) {
 		pbvideo.RegisterVideoServiceInfoServer(s, service.GetVideoServiceInstance())
-		logger.GlobalLogger.Printf("Successfully register VideoServiceInfo Server")
-		if err = s.Serve(lis); err != nil {
-			logger.GlobalLogger.Printf("Serving videoInfo error")
-			panic(err)
-		}
-	}()
-	wg.Wait()
+	}, errCh)
+
+	// 收到SIGTERM后等待处理中的请求完成，将redis中的计数写入数据库，再关闭消息队列与连接
+	err := lifecycle.Wait(errCh)
+	lifecycle.Shutdown()
+	if err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("Serving error")
+	}
 }
@@ -5,13 +5,20 @@ import (
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/init/router"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/idGenerator"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"time"
 )
 
 // 用于单机的极简版抖音后端程序,应用了redis和kafka,尚未拓展为微服务
+// 配置[mq] Driver = memory时使用进程内的消息队列，不需要kafka即可运行
 
 // initAll 初始化所有的部分
 func initAll() {
@@ -20,22 +27,32 @@ func initAll() {
 	initialization.InitDB()
 	initialization.InitOSS()
 	initialization.InitRDB()
-	initialization.InitKafkaServer()
-	initialization.InitKafkaClient()
 
 	//Init Utils
 	logger.InitLogger(initialization.LogConf)
-	jwt.InitJwt()
+	if err := mqUtils.InitMessageQueue(initialization.MQConf); err != nil {
+		logger.GlobalLogger.Fatal().Err(err).Msg("fail to init message queue")
+	}
+	idGenerator.InitIdGenerator(initialization.IdConf, initialization.GetRDB())
+	jwt.InitJwt(initialization.JwtConf, initialization.GetRDB())
 	cronUtils.InitCron()
 
 	//Init lower Levels
 	dao.DaoInitialization()
+	service.ServiceInitialization()
 }
 
 func main() {
 	initAll()
-	hServer := server.Default(server.WithHostPorts(fmt.Sprintf("127.0.0.1:%s", initialization.Port)))
+	lifecycle.InitLifecycle(initialization.ShutdownConf)
+	lifecycle.OnShutdown(lifecycle.PhaseTelemetry, "tracing", initialization.InitTracing("backend"))
+	metrics.Serve(initialization.MetricsConf.BackendPort)
+	hServer := server.Default(server.WithHostPorts(fmt.Sprintf("127.0.0.1:%s", initialization.Port)),
+		server.WithExitWaitTime(time.Duration(initialization.ShutdownConf.Timeout)*time.Second))
 
 	router.InitRouter(hServer)
+	// 收到SIGTERM后Spin停止接收请求并等待处理中的请求完成，之后执行其余的关闭步骤
+	hServer.SetCustomSignalWaiter(lifecycle.Wait)
 	hServer.Spin()
+	lifecycle.Shutdown()
 }
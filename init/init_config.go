@@ -28,8 +28,85 @@ type kafkaProducerConfig struct {
 }
 
 type KafkaConsumerConfig struct {
-	Host string
-	Port string
+	Host          string
+	Port          string
+	Version       string // kafka集群的版本，ConsumerGroup要求不低于0.10.2
+	InitialOffset string // 消费组没有提交过offset时从何处开始消费：oldest或newest
+}
+
+// MQConfig 消息队列的配置
+type MQConfig struct {
+	Driver      string // kafka或memory，memory为进程内的实现，只适用于单机版本与测试
+	BufferSize  int    // memory实现中每个订阅者的待处理消息数
+	GroupPrefix string // service层消费者的消费组前缀，消费组为前缀加上功能名
+}
+
+// IdGeneratorConfig 雪花ID生成器的配置
+type IdGeneratorConfig struct {
+	WorkerId    int64     // 机器ID，0~1023，为-1时通过redis租用
+	Epoch       time.Time // ID中时间戳的起点，设定后不能修改
+	WorkerLease int       // 租用机器ID的有效期(秒)
+	MaxBackward int       // 时钟回拨不超过该时长(毫秒)时等待时钟追上，超过时继续使用上一次的时间戳
+}
+
+// JwtConfig 签发与校验token的配置
+type JwtConfig struct {
+	Algorithm      string            // 签名算法，HS256或RS256
+	KeyId          string            // 签发token使用的密钥ID(kid)
+	Keys           map[string]string // 校验token使用的密钥，HS256时kid对应密钥，RS256时kid对应公钥文件
+	PrivateKeyFile string            // RS256签发token使用的私钥文件，对应KeyId的公钥
+	AccessTimeout  int               // access token的有效期(分钟)
+	RefreshTimeout int               // refresh token的有效期(小时)
+	MaxSessions    int               // 每个用户同时登录的会话数上限，为0时不限制
+}
+
+// LoginConfig 防止暴力破解密码与批量注册的配置，次数为0时不限制
+type LoginConfig struct {
+	UserMaxFailures int // 同一用户名连续登录失败该次数后锁定
+	IpMaxFailures   int // 同一IP登录失败该次数后锁定
+	FailureWindow   int // 登录失败次数的统计窗口(分钟)
+	LockoutBase     int // 第一次锁定的时长(秒)，再次锁定时翻倍
+	LockoutMax      int // 最大锁定时长(秒)
+	RegisterLimit   int // 同一IP在RegisterWindow内最多注册的次数
+	RegisterWindow  int // 注册次数的统计窗口(分钟)
+}
+
+type rateLimitConfig struct {
+	Enabled bool              // 是否开启网关限流
+	Store   string            // local为每个实例单独计数，redis为所有实例共用限额
+	Default string            // 所有接口的默认规则，为空时不限制
+	Routes  map[string]string // 接口路径对应的规则，在默认规则之外额外检查
+}
+
+type favoriteConsumerConfig struct {
+	GroupId           string // 点赞消息的消费组
+	Workers           int    // 每个分区处理消息的worker数
+	QueueSize         int    // 每个worker的待处理消息队列长度
+	MaxRetries        int    // 单条消息处理失败的最大重试次数，超过后写入死信topic
+	RetryBackoff      int    // 首次重试的等待时间(毫秒)，之后每次翻倍
+	DeadLetterSuffix  string // 死信topic的后缀
+	DeadLetterRetries int    // 写入死信topic失败时的最大重试次数，超过后记录日志并提交offset
+	EventRetention    int    // 已处理的点赞事件保留的天数，用于去重与乱序检测
+}
+
+type outboxConfig struct {
+	Interval      int // 发件箱relay轮询的间隔(毫秒)
+	BatchSize     int // 每次发送的消息数
+	ClaimTimeout  int // 取出的消息在该时间(秒)内没有发送完成时可以被重新取出
+	MaxBackoff    int // 发送失败后重试的最大等待时间(秒)
+	RetentionDays int // 已发送的消息保留的天数
+}
+
+type jobConfig struct {
+	PollInterval      int    // 续约leader以及检查手动触发请求的间隔(秒)
+	LeaderLease       int    // leader租约的有效期(秒)，leader宕机后最迟经过该时间由其他实例接替
+	HistoryLength     int    // 每个任务保留的运行记录数
+	FavoriteCleanSpec string // 硬删除被取消的点赞记录的定时任务
+	FavoriteFlushSpec string // 将redis中的点赞数写入数据库的定时任务
+}
+
+type adminConfig struct {
+	UserIds []int64 // 可以使用管理接口的用户
 }
 
 type ossConfig struct {
@@ -46,13 +123,100 @@ type videoConfig struct {
 	UploadMaxSize int64
 }
 
+type cacheConfig struct {
+	LocalSize          int     // 每种缓存在进程内的容量
+	LocalTTL           int     // 进程内缓存的有效期(秒)
+	BloomCapacity      int     // 布隆过滤器预计的元素个数
+	BloomFalsePositive float64 // 布隆过滤器的误判率
+	BloomLoadSpec      string  // 重新加载布隆过滤器的定时任务
+	DoubleDeleteDelay  int     // 收到实体变更事件后第二次删除缓存的延时(毫秒)
+}
+
 type userConfig struct {
-	PasswordEncrypted bool
+	PasswordEncrypted bool   // 旧版本的密码是否以MD5入库，用于校验尚未升级的密码
+	PasswordHash      string // 密码的哈希算法，bcrypt或argon2id
+	BcryptCost        int    // bcrypt的计算代价
+	Argon2Time        uint32 // argon2id的迭代次数
+	Argon2Memory      uint32 // argon2id使用的内存(KiB)
+	Argon2Threads     uint8  // argon2id的并行度
+}
+
+type hotConfig struct {
+	Gravity        float64 // 时间衰减的重力系数
+	FavoriteWeight float64 // 点赞的权重
+	CommentWeight  float64 // 评论的权重
+	PlayWeight     float64 // 播放的权重
+	MaxAgeHours    int     // 超过该时长的视频将被移出热榜
+	RecomputeSpec  string  // 重新计算热度的定时任务
+	ListLength     int     // 热榜每页的默认长度
+	FeedRerank     bool    // Feed流是否按照热度重新排序
+}
+
+type playConfig struct {
+	DedupWindow    int    // 同一用户对同一视频的播放在该窗口(分钟)内只计一次
+	MaxWatchMillis int64  // 单次上报观看时长的上限(毫秒)，超出的部分视为异常数据
+	FlushSpec      string // 将redis中的播放统计写入数据库的定时任务
+}
+
+type pageConfig struct {
+	DefaultLimit int // 列表接口未指定limit时每页的数量
+	MaxLimit     int // 列表接口每页的最大数量
+}
+
+type reconcileConfig struct {
+	Spec         string // 点赞数对账的定时任务
+	BatchSize    int    // 每批检查的视频数
+	GraceMinutes int    // 最近有点赞操作的视频跳过对账的时长(分钟)，避免覆盖尚在消息队列中的点赞
+	Repair       bool   // 定时对账时是否修复不一致的点赞数
+}
+
+type creatorConfig struct {
+	AggregateSpec   string // 将redis中的每日统计聚合写入数据库的定时任务
+	MaxRangeDays    int    // 一次查询允许的最大天数
+	DailyExpireDays int    // redis中每日统计的保留天数
 }
 
 type LogConfig struct {
 	LogFileWritten bool
 	LogFilePath    string
+	Level          string // debug、info、warn或error，低于该级别的日志不输出
+	MaxSize        int    // 日志文件超过该大小(MB)后切割，0表示不按大小切割
+	MaxAge         int    // 日志文件写入超过该时长(小时)后切割，0表示不按时间切割
+	MaxBackups     int    // 保留的切割后的日志文件数，0表示全部保留
+}
+
+// RpcClientConfig gRPC客户端熔断与重试的配置
+type RpcClientConfig struct {
+	BreakerErrorRate        float64 // 窗口内错误率达到该值时熔断
+	BreakerMinRequests      int     // 窗口内请求数达到该值时才判断错误率
+	BreakerWindow           int     // 统计错误率的窗口(秒)
+	BreakerOpenTimeout      int     // 熔断后经过该时长进入半开状态(毫秒)
+	BreakerHalfOpenRequests int     // 半开状态下放行的探测请求数
+	MaxRetries              int     // 读请求连接失败时的最大重试次数
+	RetryBackoff            int     // 第一次重试前等待的时长(毫秒)，之后每次翻倍
+}
+
+// TracingConfig 链路追踪的配置
+type TracingConfig struct {
+	Exporter    string  // otlp、stdout或none，none时不记录链路
+	Endpoint    string  // otlp导出的地址(gRPC)，例如jaeger的4317端口
+	SampleRatio float64 // 新链路的采样比例，已采样的上游链路总是继续采样
+	ServiceName string  // 服务名的前缀，各层的程序在其后加上层的名称
+}
+
+// MetricsConfig 各程序导出Prometheus指标的地址，为空时不导出
+type MetricsConfig struct {
+	ControllerPort string
+	ServicePort    string
+	DaoPort        string
+	ServiceDaoPort string
+	BackendPort    string
+}
+
+// ShutdownConfig 优雅关闭的配置
+type ShutdownConfig struct {
+	Timeout int // 关闭的超时时间(秒)，包括等待处理中的请求、写入计数、关闭连接，超时后强制退出
+	Delay   int // 收到SIGTERM后、停止接收请求前等待的时间(秒)，期间就绪检查失败，便于负载均衡摘除实例
 }
 
 type RpcConfig struct {
@@ -80,24 +244,64 @@ var (
 	dbName     string // 数据库名
 	dbLogLevel string // 数据库日志打印级别
 
+	TrustedProxies []string // 可信的反向代理网段，只有来自这些地址的请求才信任X-Forwarded-For与X-Real-IP
+
 	rdbHost string // redis主机
 	rdbPort string // redis端口
 
 	FeedListLength int
 
+	PageConf pageConfig
+
+	HotConf hotConfig
+
+	PlayConf playConfig
+
+	CreatorConf creatorConfig
+
+	ReconcileConf reconcileConfig
+
+	MQConf MQConfig
+
+	IdConf IdGeneratorConfig
+
+	JwtConf JwtConfig
+
+	LoginConf LoginConfig
+
+	RateLimitConf rateLimitConfig
+
 	kafkaServerConf kafkaProducerConfig
 	kafkaClientConf KafkaConsumerConfig
 
+	FavoriteConsumerConf favoriteConsumerConfig
+
+	OutboxConf outboxConfig
+
+	JobConf jobConfig
+
+	AdminConf adminConfig
+
 	OssConf ossConfig
 
 	VideoConf videoConfig
 
 	UserConf userConfig
 
+	CacheConf cacheConfig
+
 	LogConf LogConfig
 
 	RpcCSConf RpcConfig
 	RpcSDConf RpcConfig
+
+	RpcClientConf RpcClientConfig
+
+	TracingConf TracingConfig
+
+	MetricsConf MetricsConfig
+
+	ShutdownConf ShutdownConfig
 )
 
 func InitConfig() {
@@ -111,21 +315,41 @@ func InitConfig() {
 	loadServer(f)
 	loadDb(f)
 	loadRdb(f)
+	loadMQ(f)
+	loadIdGenerator(f)
 	loadKafkaServer(f)
 	loadKafkaClient(f)
+	loadFavoriteConsumer(f)
+	loadOutbox(f)
 	loadFeed(f)
+	loadPage(f)
+	loadHot(f)
+	loadPlay(f)
+	loadCreator(f)
+	loadReconcile(f)
+	loadJob(f)
+	loadAdmin(f)
 	loadOss(f)
 	loadVideo(f)
 	loadUser(f)
+	loadJwt(f)
+	loadLogin(f)
+	loadRateLimit(f)
+	loadCache(f)
 	loadLog(f)
 	loadRpcCSConf(f)
 	loadRpcSDConf(f)
+	loadRpcClient(f)
+	loadTracing(f)
+	loadMetrics(f)
+	loadShutdown(f)
 }
 
 // loadServer 加载服务器配置
 func loadServer(file *ini.File) {
 	s := file.Section("server")
 	Port = s.Key("Port").MustString("8888")
+	TrustedProxies = s.Key("TrustedProxies").Strings(",")
 }
 
 // loadDb 加载数据库相关配置
@@ -145,19 +369,57 @@ func loadRdb(file *ini.File) {
 	rdbPort = s.Key("Port").MustString("6379")
 }
 
+func loadMQ(file *ini.File) {
+	s := file.Section("mq")
+	MQConf.Driver = s.Key("Driver").MustString("kafka")
+	MQConf.BufferSize = s.Key("BufferSize").MustInt(1024)
+	MQConf.GroupPrefix = s.Key("GroupPrefix").MustString("douyin")
+}
+
+func loadIdGenerator(file *ini.File) {
+	s := file.Section("idGenerator")
+	IdConf.WorkerId = s.Key("WorkerId").MustInt64(-1)
+	IdConf.Epoch = s.Key("Epoch").MustTimeFormat("2006-01-02", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	IdConf.WorkerLease = s.Key("WorkerLease").MustInt(60)
+	IdConf.MaxBackward = s.Key("MaxBackward").MustInt(10)
+}
+
 func loadKafkaServer(file *ini.File) {
 	s := file.Section("kafkaProducer")
 	kafkaServerConf.Host = s.Key("UserServiceHost").MustString("127.0.0.1")
 	kafkaServerConf.Port = s.Key("Port").MustString("9092")
 	kafkaServerConf.RequireACKs = s.Key("RequireACKs").MustString("WaitForAll")
-	kafkaServerConf.Partitioner = s.Key("ProducerPartitioner").MustString("NewRandomPartitioner")
-	kafkaServerConf.ReturnSuccesses = s.Key("ProducerReturnSuccesses").MustBool(true)
+	kafkaServerConf.Partitioner = s.Key("Partitioner").MustString("NewHashPartitioner")
+	kafkaServerConf.ReturnSuccesses = s.Key("ReturnSuccesses").MustBool(true)
 }
 
 func loadKafkaClient(file *ini.File) {
 	s := file.Section("kafkaConsumer")
 	kafkaClientConf.Host = s.Key("UserServiceHost").MustString("127.0.0.1")
 	kafkaClientConf.Port = s.Key("Port").MustString("9092")
+	kafkaClientConf.Version = s.Key("Version").MustString("2.1.0")
+	kafkaClientConf.InitialOffset = s.Key("InitialOffset").MustString("oldest")
+}
+
+func loadFavoriteConsumer(file *ini.File) {
+	s := file.Section("favoriteConsumer")
+	FavoriteConsumerConf.GroupId = s.Key("GroupId").MustString("douyin_favorite")
+	FavoriteConsumerConf.Workers = s.Key("Workers").MustInt(8)
+	FavoriteConsumerConf.QueueSize = s.Key("QueueSize").MustInt(64)
+	FavoriteConsumerConf.MaxRetries = s.Key("MaxRetries").MustInt(5)
+	FavoriteConsumerConf.RetryBackoff = s.Key("RetryBackoff").MustInt(200)
+	FavoriteConsumerConf.DeadLetterSuffix = s.Key("DeadLetterSuffix").MustString("_dlq")
+	FavoriteConsumerConf.DeadLetterRetries = s.Key("DeadLetterRetries").MustInt(3)
+	FavoriteConsumerConf.EventRetention = s.Key("EventRetention").MustInt(7)
+}
+
+func loadOutbox(file *ini.File) {
+	s := file.Section("outbox")
+	OutboxConf.Interval = s.Key("Interval").MustInt(500)
+	OutboxConf.BatchSize = s.Key("BatchSize").MustInt(100)
+	OutboxConf.ClaimTimeout = s.Key("ClaimTimeout").MustInt(30)
+	OutboxConf.MaxBackoff = s.Key("MaxBackoff").MustInt(60)
+	OutboxConf.RetentionDays = s.Key("RetentionDays").MustInt(3)
 }
 
 func loadFeed(file *ini.File) {
@@ -165,6 +427,60 @@ func loadFeed(file *ini.File) {
 	FeedListLength = s.Key("ListLength").MustInt(30)
 }
 
+func loadPage(file *ini.File) {
+	s := file.Section("page")
+	PageConf.DefaultLimit = s.Key("DefaultLimit").MustInt(20)
+	PageConf.MaxLimit = s.Key("MaxLimit").MustInt(100)
+}
+
+func loadHot(file *ini.File) {
+	s := file.Section("hot")
+	HotConf.Gravity = s.Key("Gravity").MustFloat64(1.8)
+	HotConf.FavoriteWeight = s.Key("FavoriteWeight").MustFloat64(1)
+	HotConf.CommentWeight = s.Key("CommentWeight").MustFloat64(2)
+	HotConf.PlayWeight = s.Key("PlayWeight").MustFloat64(0.1)
+	HotConf.MaxAgeHours = s.Key("MaxAgeHours").MustInt(72)
+	HotConf.RecomputeSpec = s.Key("RecomputeSpec").MustString("@every 10m")
+	HotConf.ListLength = s.Key("ListLength").MustInt(30)
+	HotConf.FeedRerank = s.Key("FeedRerank").MustBool(false)
+}
+
+func loadPlay(file *ini.File) {
+	s := file.Section("play")
+	PlayConf.DedupWindow = s.Key("DedupWindow").MustInt(30)
+	PlayConf.MaxWatchMillis = s.Key("MaxWatchMillis").MustInt64(int64(3 * time.Hour / time.Millisecond))
+	PlayConf.FlushSpec = s.Key("FlushSpec").MustString("@every 5m")
+}
+
+func loadCreator(file *ini.File) {
+	s := file.Section("creator")
+	CreatorConf.AggregateSpec = s.Key("AggregateSpec").MustString("@every 10m")
+	CreatorConf.MaxRangeDays = s.Key("MaxRangeDays").MustInt(90)
+	CreatorConf.DailyExpireDays = s.Key("DailyExpireDays").MustInt(3)
+}
+
+func loadReconcile(file *ini.File) {
+	s := file.Section("reconcile")
+	ReconcileConf.Spec = s.Key("Spec").MustString("@every 6h")
+	ReconcileConf.BatchSize = s.Key("BatchSize").MustInt(500)
+	ReconcileConf.GraceMinutes = s.Key("GraceMinutes").MustInt(5)
+	ReconcileConf.Repair = s.Key("Repair").MustBool(true)
+}
+
+func loadJob(file *ini.File) {
+	s := file.Section("job")
+	JobConf.PollInterval = s.Key("PollInterval").MustInt(2)
+	JobConf.LeaderLease = s.Key("LeaderLease").MustInt(15)
+	JobConf.HistoryLength = s.Key("HistoryLength").MustInt(50)
+	JobConf.FavoriteCleanSpec = s.Key("FavoriteCleanSpec").MustString("@every 30m")
+	JobConf.FavoriteFlushSpec = s.Key("FavoriteFlushSpec").MustString("@every 15m")
+}
+
+func loadAdmin(file *ini.File) {
+	s := file.Section("admin")
+	AdminConf.UserIds = s.Key("UserIds").Int64s(",")
+}
+
 func loadOss(file *ini.File) {
 	s := file.Section("oss")
 	OssConf.Url = s.Key("Url").MustString("")
@@ -185,12 +501,71 @@ func loadVideo(file *ini.File) {
 func loadUser(file *ini.File) {
 	s := file.Section("user")
 	UserConf.PasswordEncrypted = s.Key("PasswordEncrypted").MustBool(false)
+	UserConf.PasswordHash = s.Key("PasswordHash").MustString("bcrypt")
+	UserConf.BcryptCost = s.Key("BcryptCost").MustInt(10)
+	UserConf.Argon2Time = uint32(s.Key("Argon2Time").MustUint(1))
+	UserConf.Argon2Memory = uint32(s.Key("Argon2Memory").MustUint(64 * 1024))
+	UserConf.Argon2Threads = uint8(s.Key("Argon2Threads").MustUint(2))
+}
+
+// loadJwt 加载token的配置，Keys的格式为"kid:密钥,kid:密钥"，轮换密钥时保留旧的kid直到旧token过期
+func loadJwt(file *ini.File) {
+	s := file.Section("jwt")
+	JwtConf.Algorithm = s.Key("Algorithm").MustString("HS256")
+	JwtConf.KeyId = s.Key("KeyId").MustString("")
+	JwtConf.Keys = make(map[string]string)
+	for _, pair := range s.Key("Keys").Strings(",") {
+		if idx := strings.Index(pair, ":"); idx > 0 {
+			JwtConf.Keys[pair[:idx]] = pair[idx+1:]
+		}
+	}
+	JwtConf.PrivateKeyFile = s.Key("PrivateKeyFile").MustString("")
+	JwtConf.AccessTimeout = s.Key("AccessTimeout").MustInt(60)
+	JwtConf.RefreshTimeout = s.Key("RefreshTimeout").MustInt(7 * 24)
+	JwtConf.MaxSessions = s.Key("MaxSessions").MustInt(0)
+}
+
+func loadLogin(file *ini.File) {
+	s := file.Section("login")
+	LoginConf.UserMaxFailures = s.Key("UserMaxFailures").MustInt(5)
+	LoginConf.IpMaxFailures = s.Key("IpMaxFailures").MustInt(50)
+	LoginConf.FailureWindow = s.Key("FailureWindow").MustInt(15)
+	LoginConf.LockoutBase = s.Key("LockoutBase").MustInt(60)
+	LoginConf.LockoutMax = s.Key("LockoutMax").MustInt(3600)
+	LoginConf.RegisterLimit = s.Key("RegisterLimit").MustInt(10)
+	LoginConf.RegisterWindow = s.Key("RegisterWindow").MustInt(60)
+}
+
+// loadRateLimit 加载限流配置，接口的规则在[ratelimit.routes]中，key为接口路径
+func loadRateLimit(file *ini.File) {
+	s := file.Section("ratelimit")
+	RateLimitConf.Enabled = s.Key("Enabled").MustBool(true)
+	RateLimitConf.Store = s.Key("Store").In("redis", []string{"local", "redis"})
+	RateLimitConf.Default = s.Key("Default").MustString("ip token_bucket 50/1s 100")
+	RateLimitConf.Routes = make(map[string]string)
+	for _, key := range file.Section("ratelimit.routes").Keys() {
+		RateLimitConf.Routes[key.Name()] = key.String()
+	}
+}
+
+func loadCache(file *ini.File) {
+	s := file.Section("cache")
+	CacheConf.LocalSize = s.Key("LocalSize").MustInt(10000)
+	CacheConf.LocalTTL = s.Key("LocalTTL").MustInt(5)
+	CacheConf.BloomCapacity = s.Key("BloomCapacity").MustInt(1000000)
+	CacheConf.BloomFalsePositive = s.Key("BloomFalsePositive").MustFloat64(0.01)
+	CacheConf.BloomLoadSpec = s.Key("BloomLoadSpec").MustString("@every 24h")
+	CacheConf.DoubleDeleteDelay = s.Key("DoubleDeleteDelay").MustInt(1000)
 }
 
 func loadLog(file *ini.File) {
 	s := file.Section("log")
 	LogConf.LogFileWritten = s.Key("FileLogWritten").MustBool(false)
 	LogConf.LogFilePath = s.Key("LogFilePath").MustString("./logdata/logFile.txt")
+	LogConf.Level = s.Key("Level").MustString("info")
+	LogConf.MaxSize = s.Key("MaxSize").MustInt(100)
+	LogConf.MaxAge = s.Key("MaxAge").MustInt(24)
+	LogConf.MaxBackups = s.Key("MaxBackups").MustInt(7)
 }
 
 func loadRpcCSConf(file *ini.File) {
@@ -235,3 +610,37 @@ func GetDB() *gorm.DB {
 func GetStdOutLogger() zerolog.Logger {
 	return stdOutLogger
 }
+
+func loadRpcClient(file *ini.File) {
+	s := file.Section("rpcClient")
+	RpcClientConf.BreakerErrorRate = s.Key("BreakerErrorRate").MustFloat64(0.5)
+	RpcClientConf.BreakerMinRequests = s.Key("BreakerMinRequests").MustInt(20)
+	RpcClientConf.BreakerWindow = s.Key("BreakerWindow").MustInt(10)
+	RpcClientConf.BreakerOpenTimeout = s.Key("BreakerOpenTimeout").MustInt(5000)
+	RpcClientConf.BreakerHalfOpenRequests = s.Key("BreakerHalfOpenRequests").MustInt(3)
+	RpcClientConf.MaxRetries = s.Key("MaxRetries").MustInt(2)
+	RpcClientConf.RetryBackoff = s.Key("RetryBackoff").MustInt(50)
+}
+
+func loadTracing(file *ini.File) {
+	s := file.Section("tracing")
+	TracingConf.Exporter = s.Key("Exporter").MustString("none")
+	TracingConf.Endpoint = s.Key("Endpoint").MustString("127.0.0.1:4317")
+	TracingConf.SampleRatio = s.Key("SampleRatio").MustFloat64(1)
+	TracingConf.ServiceName = s.Key("ServiceName").MustString("simple-douyin")
+}
+
+func loadMetrics(file *ini.File) {
+	s := file.Section("metrics")
+	MetricsConf.ControllerPort = s.Key("ControllerPort").MustString(":9090")
+	MetricsConf.ServicePort = s.Key("ServicePort").MustString(":9091")
+	MetricsConf.DaoPort = s.Key("DaoPort").MustString(":9092")
+	MetricsConf.ServiceDaoPort = s.Key("ServiceDaoPort").MustString(":9093")
+	MetricsConf.BackendPort = s.Key("BackendPort").MustString(":9094")
+}
+
+func loadShutdown(file *ini.File) {
+	s := file.Section("shutdown")
+	ShutdownConf.Timeout = s.Key("Timeout").MustInt(30)
+	ShutdownConf.Delay = s.Key("Delay").MustInt(0)
+}
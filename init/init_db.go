@@ -3,6 +3,8 @@ package init
 import (
 	"fmt"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -11,7 +13,7 @@ import (
 )
 
 func InitDB() {
-	stdOutLogger.Print("In InitDataBase")
+	stdOutLogger.Debug().Msg("In InitDataBase")
 	dns := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		dbUser,
 		dbPassWord,
@@ -41,7 +43,12 @@ func InitDB() {
 		stdOutLogger.Panic().Caller().Str("数据库初始化失败", err.Error())
 	}
 
-	err = db.AutoMigrate(&model.Video{}, &model.User{}, &model.Follow{}, &model.Comment{}, &model.Favourite{}, &model.Message{}) //数据库自动迁移
+	if err = db.Use(tracing.GormPlugin{}); err != nil {
+		stdOutLogger.Panic().Caller().Str("数据库链路追踪初始化失败", err.Error())
+	}
+
+	err = db.AutoMigrate(&model.Video{}, &model.User{}, &model.Follow{}, &model.Comment{}, &model.Favourite{}, &model.FavouriteEvent{}, &model.Outbox{}, &model.Message{},
+		&model.VideoDailyStat{}, &model.CreatorDailyStat{}) //数据库自动迁移
 
 	if err != nil {
 		stdOutLogger.Panic().Caller().Str("数据库自动迁移失败", err.Error())
@@ -52,4 +59,8 @@ func InitDB() {
 	sqlDb.SetMaxIdleConns(50)                  // 连接池中的最大闲置连接数
 	sqlDb.SetMaxOpenConns(100)                 // 数据库的最大连接数量
 	sqlDb.SetConnMaxLifetime(10 * time.Second) // 连接的最大可复用时间
+
+	if err = metrics.RegisterDB(sqlDb, dbName); err != nil {
+		stdOutLogger.Error().Caller().Err(err).Msg("fail to register metrics of database")
+	}
 }
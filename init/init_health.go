@@ -0,0 +1,85 @@
+package init
+
+import (
+	"context"
+	"errors"
+)
+
+// HealthCheckers 已初始化的依赖的就绪检查，只包含当前程序连接的依赖
+func HealthCheckers() map[string]func(ctx context.Context) error {
+	checkers := make(map[string]func(ctx context.Context) error)
+	if db != nil {
+		checkers["mysql"] = func(ctx context.Context) error {
+			sqlDb, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDb.PingContext(ctx)
+		}
+	}
+	if rdb != nil {
+		checkers["redis"] = func(ctx context.Context) error {
+			return rdb.Ping(ctx).Err()
+		}
+	}
+	if kafkaServerClient != nil {
+		checkers["kafka"] = func(ctx context.Context) error {
+			// 刷新元数据需要访问broker，不能只看本地缓存的连接状态
+			return withContext(ctx, func() error {
+				if err := kafkaServerClient.RefreshMetadata(); err != nil {
+					return err
+				}
+				if len(kafkaServerClient.Brokers()) == 0 {
+					return errors.New("no available kafka broker")
+				}
+				return nil
+			})
+		}
+	}
+	if bucket != nil {
+		checkers["oss"] = func(ctx context.Context) error {
+			// 对象不存在时返回false与nil，网络或鉴权失败时返回错误
+			return withContext(ctx, func() error {
+				_, err := bucket.IsObjectExist("healthz")
+				return err
+			})
+		}
+	}
+	return checkers
+}
+
+// withContext 在ctx结束时返回，用于不支持context的客户端，超时后check仍在后台运行直到返回
+func withContext(ctx context.Context, check func() error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- check()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseStorage 关闭数据库、redis以及kafka生产者的连接，在消息队列关闭之后调用
+func CloseStorage(ctx context.Context) error {
+	var errs []error
+	if kafkaServerClient != nil && !kafkaServerClient.Closed() {
+		errs = append(errs, kafkaServerClient.Close())
+	}
+	if rdb != nil {
+		errs = append(errs, rdb.Close())
+	}
+	if db != nil {
+		if sqlDb, err := db.DB(); err == nil {
+			errs = append(errs, sqlDb.Close())
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,7 +8,11 @@ import (
 var kafkaServer sarama.SyncProducer
 var kafkaClient sarama.Consumer
 
-func InitKafkaServer() {
+// kafkaServerClient kafkaServer使用的连接，用于就绪检查，关闭kafkaServer时不会关闭
+var kafkaServerClient sarama.Client
+
+// InitKafkaServer 连接kafka并创建同步的producer，连接失败时返回错误
+func InitKafkaServer() error {
 	var err error
 	config := sarama.NewConfig()
 	switch kafkaServerConf.RequireACKs {
@@ -22,20 +26,51 @@ func InitKafkaServer() {
 	switch kafkaServerConf.Partitioner {
 	case "NewRandomPartitioner":
 		config.Producer.Partitioner = sarama.NewRandomPartitioner
+	case "NewHashPartitioner":
+		// 按Key的哈希值选择分区，使同一Key的消息有序，没有Key的消息随机分区
+		config.Producer.Partitioner = sarama.NewHashPartitioner
 	}
 	config.Producer.Return.Successes = kafkaServerConf.ReturnSuccesses
-	kafkaServer, err = sarama.NewSyncProducer([]string{fmt.Sprintf("%s:%s", kafkaServerConf.Host, kafkaServerConf.Port)}, config)
+	kafkaServerClient, err = sarama.NewClient([]string{fmt.Sprintf("%s:%s", kafkaServerConf.Host, kafkaServerConf.Port)}, config)
+	if err != nil {
+		return fmt.Errorf("connect kafka producer: %w", err)
+	}
+	kafkaServer, err = sarama.NewSyncProducerFromClient(kafkaServerClient)
 	if err != nil {
-		stdOutLogger.Panic().Caller().Str("Error occurs in InitKafkaServer,", err.Error())
+		kafkaServerClient.Close()
+		kafkaServerClient = nil
+		return fmt.Errorf("create kafka producer: %w", err)
 	}
+	return nil
 }
 
-func InitKafkaClient() {
+// InitKafkaClient 连接kafka并创建广播消费使用的consumer，连接失败时返回错误
+func InitKafkaClient() error {
 	var err error
 	kafkaClient, err = sarama.NewConsumer([]string{fmt.Sprintf("%s:%s", kafkaClientConf.Host, kafkaClientConf.Port)}, nil)
 	if err != nil {
-		stdOutLogger.Panic().Caller().Str("Error occurs in InitKafkaClient,", err.Error())
+		return fmt.Errorf("connect kafka consumer: %w", err)
+	}
+	return nil
+}
+
+// NewKafkaConsumerGroup 创建一个加入groupId消费组的ConsumerGroup，已处理的offset提交到kafka
+func NewKafkaConsumerGroup(groupId string) (sarama.ConsumerGroup, error) {
+	config := sarama.NewConfig()
+	version, err := sarama.ParseKafkaVersion(kafkaClientConf.Version)
+	if err != nil {
+		return nil, err
+	}
+	config.Version = version
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.AutoCommit.Enable = true
+	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategySticky
+	if kafkaClientConf.InitialOffset == "newest" {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	} else {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
 	}
+	return sarama.NewConsumerGroup([]string{fmt.Sprintf("%s:%s", kafkaClientConf.Host, kafkaClientConf.Port)}, groupId, config)
 }
 
 func GetKafkaServer() sarama.SyncProducer {
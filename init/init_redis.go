@@ -1,8 +1,10 @@
 package init
 
 import (
+	"context"
 	"fmt"
-	"github.com/go-redis/redis"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+	"github.com/go-redis/redis/v8"
 )
 
 var rdb *redis.Client
@@ -14,8 +16,9 @@ func InitRDB() {
 		DB:       0,
 		PoolSize: 100,
 	})
+	rdb.AddHook(tracing.RedisHook{})
 
-	_, err := rdb.Ping().Result()
+	_, err := rdb.Ping(context.Background()).Result()
 	if err != nil {
 		stdOutLogger.Panic().Caller().Str("Redis启动失败", err.Error())
 	}
@@ -0,0 +1,21 @@
+package init
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+)
+
+// InitTracing 按配置初始化链路追踪，layer为程序所在的层，上报的服务名为ServiceName-layer
+// 返回的函数在程序退出前调用，导出尚未发送的span
+func InitTracing(layer string) func(context.Context) error {
+	shutdown, err := tracing.Init(tracing.Options{
+		Exporter:    TracingConf.Exporter,
+		Endpoint:    TracingConf.Endpoint,
+		SampleRatio: TracingConf.SampleRatio,
+		ServiceName: TracingConf.ServiceName + "-" + layer,
+	})
+	if err != nil {
+		stdOutLogger.Panic().Caller().Err(err).Msg("链路追踪初始化失败")
+	}
+	return shutdown
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/cloudwego/hertz/pkg/app"
+	"net"
+	"strings"
+)
+
+// parseTrustedProxies 解析可信代理的网段，单个IP视为只包含该IP的网段，无法解析的配置被忽略
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil {
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+				continue
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			logger.GlobalLogger.Warn().Err(err).Msgf("ignore invalid trusted proxy %v", proxy)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// clientIP 获取请求的客户端IP，替换hertz默认的实现，默认实现总是信任请求头，客户端可以任意伪造IP绕过登录锁定与限流
+// 只有连接的对端是可信代理时才读取X-Forwarded-For，从右向左跳过可信代理，取第一个不可信的地址；
+// 没有X-Forwarded-For时读取X-Real-IP；对端不是可信代理或请求头无法解析时使用对端地址
+func clientIP(trusted []*net.IPNet) app.ClientIP {
+	isTrusted := func(ip net.IP) bool {
+		for _, ipNet := range trusted {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(c *app.RequestContext) string {
+		remote := remoteIP(c)
+		if remote == nil {
+			return ""
+		}
+		if !isTrusted(remote) {
+			return remote.String()
+		}
+		if forwarded := string(c.GetHeader("X-Forwarded-For")); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				ip := net.ParseIP(strings.TrimSpace(hops[i]))
+				if ip == nil {
+					break
+				}
+				if !isTrusted(ip) {
+					return ip.String()
+				}
+			}
+			return remote.String()
+		}
+		if ip := net.ParseIP(strings.TrimSpace(string(c.GetHeader("X-Real-IP")))); ip != nil {
+			return ip.String()
+		}
+		return remote.String()
+	}
+}
+
+// remoteIP 连接的对端地址
+func remoteIP(c *app.RequestContext) net.IP {
+	addr := c.RemoteAddr()
+	if addr == nil {
+		return nil
+	}
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(addr.String()))
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,61 @@
+package router
+
+import (
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/network"
+	"net"
+	"testing"
+)
+
+// addrConn 只提供对端地址的连接
+type addrConn struct {
+	network.Conn
+	addr net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func newRequest(remote string, headers map[string]string) *app.RequestContext {
+	c := app.NewContext(0)
+	c.SetConn(&addrConn{addr: &net.TCPAddr{IP: net.ParseIP(remote), Port: 40000}})
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	return c
+}
+
+func TestClientIP(t *testing.T) {
+	getIP := clientIP(parseTrustedProxies([]string{"10.0.0.0/8", " 127.0.0.1 ", "not-a-cidr", ""}))
+	cases := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"direct", "203.0.113.7", nil, "203.0.113.7"},
+		{"untrusted peer spoofs headers", "203.0.113.7",
+			map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "2.2.2.2"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		// 客户端自带的X-Forwarded-For在最左侧，取最右侧不可信的地址
+		{"client prepends hops", "10.0.0.2",
+			map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"real ip from trusted proxy", "127.0.0.1", map[string]string{"X-Real-IP": "198.51.100.2"}, "198.51.100.2"},
+		{"garbage header", "10.0.0.2", map[string]string{"X-Forwarded-For": "unknown"}, "10.0.0.2"},
+		{"trusted proxy without headers", "10.0.0.2", nil, "10.0.0.2"},
+	}
+	for _, tc := range cases {
+		if got := getIP(newRequest(tc.remote, tc.headers)); got != tc.want {
+			t.Errorf("%s: clientIP = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	getIP := clientIP(parseTrustedProxies(nil))
+	c := newRequest("127.0.0.1", map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "2.2.2.2"})
+	if got := getIP(c); got != "127.0.0.1" {
+		t.Fatalf("clientIP = %v, want remote address", got)
+	}
+}
@@ -0,0 +1,28 @@
+package router
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// healthz 存活检查，进程能处理请求即返回200
+func healthz(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, healthResponse{Status: lifecycle.StatusOk})
+}
+
+// readyz 就绪检查，所有依赖可用时返回200，否则返回503以及每个依赖的状态，开始关闭后总是返回503
+func readyz(ctx context.Context, c *app.RequestContext) {
+	ready, checks := lifecycle.Ready(ctx)
+	if !ready {
+		c.JSON(consts.StatusServiceUnavailable, healthResponse{Status: "not ready", Checks: checks})
+		return
+	}
+	c.JSON(consts.StatusOK, healthResponse{Status: lifecycle.StatusOk, Checks: checks})
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/controller"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
 	"github.com/cloudwego/hertz/pkg/app/server"
@@ -8,19 +9,35 @@ import (
 
 // InitRouter 初始化hertz服务器路由
 func InitRouter(hertz *server.Hertz) {
+	// 登录锁定、注册与限流按客户端IP计数，只信任来自可信代理的X-Forwarded-For
+	hertz.SetClientIPFunc(clientIP(parseTrustedProxies(initialization.TrustedProxies)))
+	// 健康检查不经过链路追踪、指标统计与限流
+	hertz.GET("/healthz", healthz)
+	hertz.GET("/readyz", readyz)
+	hertz.Use(tracingMiddleware(), requestIdMiddleware(), metricsMiddleware())
+	if initialization.RateLimitConf.Enabled {
+		hertz.Use(rateLimit())
+	}
 
 	// 用户注册与登录需要进行鉴权, Feed可授权可不授权
 	hertz.POST("/douyin/user/register/", controller.Register)
-	hertz.POST("/douyin/user/login/", jwt.JwtMiddleware.LoginHandler)
+	hertz.POST("/douyin/user/login/", jwt.LoginHandler)
+	hertz.POST("/douyin/user/refresh/", jwt.RefreshHandler)
 	hertz.GET("/douyin/feed/", controller.Feed)
+	hertz.GET("/douyin/feed/hot/", controller.HotFeed)
 
 	// 鉴权authorization
-	auth := hertz.Group("/douyin", jwt.JwtMiddleware.MiddlewareFunc())
+	auth := hertz.Group("/douyin", jwt.JwtMiddleware.MiddlewareFunc(), userIdMiddleware())
 
 	// basic apis
 	auth.GET("/user/", controller.UserInfo)
+	auth.POST("/user/logout/", jwt.LogoutHandler)
+	auth.GET("/user/sessions/", controller.SessionList)
+	auth.POST("/user/sessions/revoke/", controller.SessionRevoke)
 	auth.POST("/publish/action/", controller.Publish)
 	auth.GET("/publish/list/", controller.PublishList)
+	auth.POST("/video/play/", controller.PlayAction)
+	auth.GET("/creator/stats/", controller.CreatorStats)
 
 	// extra apis - I
 	auth.POST("/favorite/action/", controller.FavoriteAction)
@@ -35,4 +52,9 @@ func InitRouter(hertz *server.Hertz) {
 	auth.GET("/relation/friend/list/", controller.FriendList)
 	auth.GET("/message/chat/", controller.MessageChat)
 	auth.POST("/message/action/", controller.MessageAction)
+
+	// admin apis，只有配置的管理员可以使用
+	auth.GET("/admin/jobs/", controller.JobList)
+	auth.GET("/admin/jobs/history/", controller.JobHistory)
+	auth.POST("/admin/jobs/trigger/", controller.JobTrigger)
 }
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/cloudwego/hertz/pkg/app"
+	"strconv"
+	"time"
+)
+
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware 按路由模板统计请求数与耗时，未匹配路由的请求合并统计，避免标签的取值无限增长
+func metricsMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		start := time.Now()
+		c.Next(ctx)
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := string(c.Method())
+		metrics.HttpRequests.WithLabelValues(route, method, strconv.Itoa(c.Response.StatusCode())).Inc()
+		metrics.HttpDuration.WithLabelValues(route, method).Observe(metrics.Since(start))
+	}
+}
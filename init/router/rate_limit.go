@@ -0,0 +1,84 @@
+package router
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rateLimiter"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+)
+
+const defaultRoute = "*"
+
+type routeLimiter struct {
+	route   string
+	rule    rateLimiter.Rule
+	limiter rateLimiter.Limiter
+}
+
+// rateLimit 按配置创建限流中间件，请求需要同时满足默认规则与接口的规则，redis不可用时不拦截请求
+func rateLimit() app.HandlerFunc {
+	conf := initialization.RateLimitConf
+	var client *redis.Client
+	if conf.Store == "redis" {
+		client = initialization.GetRDB()
+	}
+	newRouteLimiter := func(route, s string) *routeLimiter {
+		rule, err := rateLimiter.ParseRule(s)
+		if err != nil {
+			panic(route + ": " + err.Error())
+		}
+		return &routeLimiter{route: route, rule: rule, limiter: rateLimiter.NewLimiter(rule, client)}
+	}
+	var defaultLimiter *routeLimiter
+	if conf.Default != "" {
+		defaultLimiter = newRouteLimiter(defaultRoute, conf.Default)
+	}
+	routeLimiters := make(map[string]*routeLimiter, len(conf.Routes))
+	for route, s := range conf.Routes {
+		routeLimiters[route] = newRouteLimiter(route, s)
+	}
+
+	return func(ctx context.Context, c *app.RequestContext) {
+		for _, l := range []*routeLimiter{defaultLimiter, routeLimiters[string(c.Path())]} {
+			if l == nil {
+				continue
+			}
+			wait, err := l.limiter.Allow(ctx, limitKey(ctx, c, l))
+			if err != nil {
+				logger.GlobalLogger.Error().Err(err).Msgf("fail to check rate limit of %v", l.route)
+				continue
+			}
+			if wait > 0 {
+				c.Header("Retry-After", rateLimiter.RetryAfter(wait))
+				c.AbortWithStatusJSON(consts.StatusOK, api.TooManyRequestsResponse{
+					Response: api.Response{
+						StatusCode: int32(api.TooManyRequestsErr),
+						StatusMsg:  api.ErrorCodeToMsg[api.TooManyRequestsErr],
+					},
+					RetryAfterMs: wait.Milliseconds(),
+				})
+				return
+			}
+		}
+	}
+}
+
+// limitKey 计数使用的key，不同接口的规则分别计数
+// 按IP计数时使用clientIP解析的客户端IP，只有来自可信代理的请求才使用请求头中的IP，客户端无法伪造IP绕过限流
+func limitKey(ctx context.Context, c *app.RequestContext, l *routeLimiter) string {
+	switch l.rule.Key {
+	case rateLimiter.KeyRoute:
+		return l.route
+	case rateLimiter.KeyUser:
+		if userId, ok := jwt.UserIdFromToken(ctx, c); ok {
+			return l.route + "_user_" + strconv.FormatInt(userId, 10)
+		}
+	}
+	return l.route + "_ip_" + c.ClientIP()
+}
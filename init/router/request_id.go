@@ -0,0 +1,62 @@
+package router
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/cloudwego/hertz/pkg/app"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	requestIdHeader    = "X-Request-Id"
+	maxRequestIdLength = 64
+)
+
+// validRequestId 只接受较短的、由字母、数字与-_.组成的请求ID，避免伪造的请求头污染日志
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for _, r := range requestId {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIdMiddleware 沿用请求头X-Request-Id中的请求ID，没有时生成一个，并在响应头中返回
+// 请求ID与路由模板写入ctx，之后的handler通过logger.Ctx(ctx)输出带有这些字段的日志，并经gRPC传递给下层
+func requestIdMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		start := time.Now()
+		requestId := string(c.GetHeader(requestIdHeader))
+		if !validRequestId(requestId) {
+			requestId = logger.NewRequestId()
+		}
+		c.Header(requestIdHeader, requestId)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestId))
+		ctx = logger.WithRequestId(ctx, requestId)
+		if route := c.FullPath(); route != "" {
+			ctx = logger.WithRoute(ctx, route)
+		}
+
+		c.Next(ctx)
+
+		logger.Ctx(ctx).Debug().Str("method", string(c.Method())).Int("status", c.Response.StatusCode()).
+			Dur("latency", time.Since(start)).Msg("request finished")
+	}
+}
+
+// userIdMiddleware 在JwtMiddleware之后将登录用户的ID写入ctx
+func userIdMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		if userId, err := jwt.GetUserId(ctx, c); err == nil {
+			ctx = logger.WithUserId(ctx, userId)
+		}
+		c.Next(ctx)
+	}
+}
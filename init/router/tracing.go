@@ -0,0 +1,70 @@
+package router
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestHeaderCarrier 将hertz的请求头适配为propagation.TextMapCarrier
+type requestHeaderCarrier struct {
+	header *protocol.RequestHeader
+}
+
+var _ propagation.TextMapCarrier = requestHeaderCarrier{}
+
+func (c requestHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c requestHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c requestHeaderCarrier) Keys() []string {
+	keys := make([]string, 0)
+	c.header.VisitAll(func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	return keys
+}
+
+// tracingMiddleware 为每个请求开始一个span，请求头中带有traceparent时作为上游链路的子span
+// 之后的handler通过ctx把链路传递给gRPC调用，响应头X-Trace-Id返回链路的ID便于排查
+func tracingMiddleware() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, requestHeaderCarrier{header: &c.Request.Header})
+		route := c.FullPath()
+		if route == "" {
+			route = string(c.Path())
+		}
+		ctx, span := tracing.Tracer().Start(ctx, string(c.Method())+" "+route, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(string(c.Method())),
+				semconv.HTTPRoute(route),
+				semconv.HTTPTarget(string(c.Request.URI().RequestURI())),
+				semconv.NetSockPeerAddr(c.ClientIP()),
+			))
+		defer span.End()
+		if span.SpanContext().HasTraceID() {
+			c.Header("X-Trace-Id", span.SpanContext().TraceID().String())
+		}
+
+		c.Next(ctx)
+
+		statusCode := c.Response.StatusCode()
+		span.SetAttributes(semconv.HTTPStatusCode(statusCode))
+		if statusCode >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"strconv"
+)
+
+type JobListResponse struct {
+	api.Response
+	Leader string              `json:"leader"`
+	Jobs   []cronUtils.JobInfo `json:"jobs"`
+}
+
+type JobHistoryResponse struct {
+	api.Response
+	History []cronUtils.JobRun `json:"history"`
+}
+
+// 默认返回最近20次运行记录
+const defaultJobHistoryLimit = 20
+
+// JobList 管理接口，列出所有定时任务、当前的leader以及每个任务最近一次的运行记录
+func JobList(c context.Context, ctx *app.RequestContext) {
+	if !checkAdmin(c, ctx) {
+		return
+	}
+	jobs, leader, err := service.GetJobServiceInstance().ListJobs()
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.RedisDBErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+		})
+		return
+	}
+	ctx.JSON(consts.StatusOK, JobListResponse{
+		Response: api.Response{StatusCode: 0},
+		Leader:   leader,
+		Jobs:     jobs,
+	})
+}
+
+// JobHistory 管理接口，获取定时任务最近的运行记录，包括耗时与错误
+func JobHistory(c context.Context, ctx *app.RequestContext) {
+	if !checkAdmin(c, ctx) {
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultJobHistoryLimit)))
+	if err != nil || limit <= 0 || limit > initialization.JobConf.HistoryLength {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	history, err := service.GetJobServiceInstance().JobHistory(ctx.Query("name"), limit)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.RedisDBErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+		})
+		return
+	}
+	ctx.JSON(consts.StatusOK, JobHistoryResponse{
+		Response: api.Response{StatusCode: 0},
+		History:  history,
+	})
+}
+
+// JobTrigger 管理接口，请求立即运行一次定时任务，任务在某一个实例上异步运行，结果见运行记录
+func JobTrigger(c context.Context, ctx *app.RequestContext) {
+	if !checkAdmin(c, ctx) {
+		return
+	}
+	err := service.GetJobServiceInstance().TriggerJob(ctx.Query("name"))
+	if err != nil {
+		if errors.Is(constants.RecordNotExistErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.RecordNotExistErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.RecordNotExistErr],
+			})
+		} else {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.RedisDBErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+			})
+		}
+		return
+	}
+	ctx.JSON(consts.StatusOK, api.Response{StatusCode: 0})
+}
+
+// checkAdmin 检查登录用户是否在配置的管理员列表中，不是时写入错误响应
+func checkAdmin(c context.Context, ctx *app.RequestContext) bool {
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return false
+	}
+	for _, userId := range initialization.AdminConf.UserIds {
+		if userId == loginUserId {
+			return true
+		}
+	}
+	ctx.JSON(consts.StatusOK, api.Response{
+		StatusCode: int32(api.PermissionDeniedErr),
+		StatusMsg:  api.ErrorCodeToMsg[api.PermissionDeniedErr],
+	})
+	return false
+}
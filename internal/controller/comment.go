@@ -2,14 +2,22 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
 	"github.com/cloudwego/hertz/pkg/app"
-	"net/http"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"strconv"
 )
 
 type CommentListResponse struct {
 	api.Response
 	CommentList []api.Comment `json:"comment_list,omitempty"`
+	NextCursor  string        `json:"next_cursor,omitempty"`
+	HasMore     bool          `json:"has_more"`
 }
 
 type CommentActionResponse struct {
@@ -17,33 +25,107 @@ type CommentActionResponse struct {
 	Comment api.Comment `json:"comment,omitempty"`
 }
 
-// CommentAction no practical effect, just check if token is valid
+// CommentAction 发表或删除评论
 func CommentAction(c context.Context, ctx *app.RequestContext) {
-	token := ctx.Query("token")
-	actionType := ctx.Query("action_type")
-
-	if user, exist := usersLoginInfo[token]; exist {
-		if actionType == "1" {
-			text := ctx.Query("comment_text")
-			ctx.JSON(http.StatusOK, CommentActionResponse{Response: api.Response{StatusCode: 0},
-				Comment: api.Comment{
-					Id:         1,
-					User:       user,
-					Content:    text,
-					CreateDate: "05-01",
-				}})
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	videoId, err := strconv.ParseInt(ctx.Query("video_id"), 10, 64)
+	if err != nil {
+		writeInputFormatErr(ctx)
+		return
+	}
+	actionType, err := strconv.ParseInt(ctx.Query("action_type"), 10, 32)
+	if err != nil {
+		writeInputFormatErr(ctx)
+		return
+	}
+	var commentId int64
+	if actionType == api.CommentDeleteAction {
+		if commentId, err = strconv.ParseInt(ctx.Query("comment_id"), 10, 64); err != nil {
+			writeInputFormatErr(ctx)
 			return
 		}
-		ctx.JSON(http.StatusOK, api.Response{StatusCode: 0})
-	} else {
-		ctx.JSON(http.StatusOK, api.Response{StatusCode: 1, StatusMsg: "User doesn't exist"})
 	}
+	comment, err := service.GetCommentServiceInstance().CommentActionInfo(loginUserId, videoId, int32(actionType),
+		ctx.Query("comment_text"), commentId)
+	if err != nil {
+		writeInteractErr(ctx, err)
+		return
+	}
+	if comment == nil {
+		ctx.JSON(consts.StatusOK, api.Response{StatusCode: 0})
+		return
+	}
+	ctx.JSON(consts.StatusOK, CommentActionResponse{
+		Response: api.Response{StatusCode: 0},
+		Comment:  *comment,
+	})
 }
 
-// CommentList all videos have same demo comment list
+// CommentList 按评论时间倒序分页获取视频的评论
 func CommentList(c context.Context, ctx *app.RequestContext) {
-	ctx.JSON(http.StatusOK, CommentListResponse{
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	videoId, err := strconv.ParseInt(ctx.Query("video_id"), 10, 64)
+	if err != nil {
+		writeInputFormatErr(ctx)
+		return
+	}
+	limit, err := pageUtils.ParseLimit(ctx.Query("limit"))
+	if err != nil {
+		writeInteractErr(ctx, err)
+		return
+	}
+	commentList, next, hasMore, err := service.GetCommentServiceInstance().CommentListInfo(loginUserId, videoId, ctx.Query("cursor"), limit)
+	if err != nil {
+		writeInteractErr(ctx, err)
+		return
+	}
+	ctx.JSON(consts.StatusOK, CommentListResponse{
 		Response:    api.Response{StatusCode: 0},
-		CommentList: DemoComments,
+		CommentList: commentList,
+		NextCursor:  next,
+		HasMore:     hasMore,
+	})
+}
+
+func writeInputFormatErr(ctx *app.RequestContext) {
+	ctx.JSON(consts.StatusOK, api.Response{
+		StatusCode: int32(api.InputFormatCheckErr),
+		StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+	})
+}
+
+// writeInteractErr 将评论与关注相关的service层返回的错误转换为对应的错误码后返回
+func writeInteractErr(ctx *app.RequestContext, err error) {
+	code := api.InnerDataBaseErr
+	if errors.Is(constants.InvalidCursorErr, err) {
+		code = api.InvalidCursorErr
+	} else if errors.Is(constants.InputFormatCheckErr, err) {
+		code = api.InputFormatCheckErr
+	} else if errors.Is(constants.UnKnownActionTypeErr, err) {
+		code = api.UnKnownActionType
+	} else if errors.Is(constants.UserNotExistErr, err) {
+		code = api.UserNotExistErr
+	} else if errors.Is(constants.RecordNotExistErr, err) {
+		code = api.RecordNotExistErr
+	} else if errors.Is(constants.RecordNotMatchErr, err) {
+		code = api.RecordNotMatchErr
+	}
+	ctx.JSON(consts.StatusOK, api.Response{
+		StatusCode: int32(code),
+		StatusMsg:  api.ErrorCodeToMsg[code],
 	})
 }
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"strconv"
+	"time"
+)
+
+type CreatorStatsResponse struct {
+	api.Response
+	StartDate     string                `json:"start_date"`
+	EndDate       string                `json:"end_date"`
+	CreatorSeries []api.DailyStat       `json:"creator_series"`
+	VideoSeries   []api.VideoStatSeries `json:"video_series"`
+}
+
+// 默认返回最近7天的数据
+const defaultStatDays = 7
+
+// CreatorStats 创作者数据接口，返回创作者及其视频在日期范围内的每日统计，format=csv时导出为CSV文件
+func CreatorStats(c context.Context, ctx *app.RequestContext) {
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	start, end, ok := parseStatDateRange(ctx.Query("start_date"), ctx.Query("end_date"))
+	if !ok {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	videoId, err := strconv.ParseInt(ctx.DefaultQuery("video_id", "0"), 10, 64)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	creatorSeries, videoSeries, err := service.GetCreatorServiceInstance().CreatorStats(loginUserId, videoId, start, end)
+	if err != nil {
+		if errors.Is(constants.RecordNotExistErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.RecordNotExistErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.RecordNotExistErr],
+			})
+		} else if errors.Is(constants.RecordNotMatchErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.RecordNotMatchErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.RecordNotMatchErr],
+			})
+		} else {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InnerDataBaseErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
+			})
+		}
+		return
+	}
+	if ctx.Query("format") == "csv" {
+		data, err := creatorStatsCSV(creatorSeries, videoSeries)
+		if err != nil {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.GetDataErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.GetDataErr],
+			})
+			return
+		}
+		ctx.Header("Content-Disposition", "attachment; filename=creator_stats_"+
+			strconv.FormatInt(loginUserId, 10)+"_"+start.Format(api.StatDateLayout)+"_"+end.Format(api.StatDateLayout)+".csv")
+		ctx.Data(consts.StatusOK, "text/csv; charset=utf-8", data)
+		return
+	}
+	ctx.JSON(consts.StatusOK, CreatorStatsResponse{
+		Response:      api.Response{StatusCode: 0},
+		StartDate:     start.Format(api.StatDateLayout),
+		EndDate:       end.Format(api.StatDateLayout),
+		CreatorSeries: creatorSeries,
+		VideoSeries:   videoSeries,
+	})
+}
+
+// parseStatDateRange 解析日期范围，缺省时以今天为结束日期、向前取defaultStatDays天
+func parseStatDateRange(startStr, endStr string) (time.Time, time.Time, bool) {
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var err error
+	if endStr != "" {
+		if end, err = time.ParseInLocation(api.StatDateLayout, endStr, time.Local); err != nil {
+			return end, end, false
+		}
+	}
+	start := end.AddDate(0, 0, 1-defaultStatDays)
+	if startStr != "" {
+		if start, err = time.ParseInLocation(api.StatDateLayout, startStr, time.Local); err != nil {
+			return start, end, false
+		}
+	}
+	if start.After(end) || !start.AddDate(0, 0, initialization.CreatorConf.MaxRangeDays).After(end) {
+		return start, end, false
+	}
+	return start, end, true
+}
+
+// creatorStatsCSV 将统计导出为CSV，scope为creator的行为创作者汇总，video的行为单个视频
+func creatorStatsCSV(creatorSeries []api.DailyStat, videoSeries []api.VideoStatSeries) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	rows := [][]string{{"scope", "video_id", "date", "views", "favorites", "comments", "new_followers", "avg_watch_time_ms"}}
+	for _, stat := range creatorSeries {
+		rows = append(rows, dailyStatRow("creator", "", stat))
+	}
+	for _, series := range videoSeries {
+		videoIdStr := strconv.FormatInt(series.VideoId, 10)
+		for _, stat := range series.Series {
+			rows = append(rows, dailyStatRow("video", videoIdStr, stat))
+		}
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func dailyStatRow(scope, videoId string, stat api.DailyStat) []string {
+	return []string{
+		scope,
+		videoId,
+		stat.Date,
+		strconv.FormatInt(stat.Views, 10),
+		strconv.FormatInt(stat.Favorites, 10),
+		strconv.FormatInt(stat.Comments, 10),
+		strconv.FormatInt(stat.NewFollowers, 10),
+		strconv.FormatInt(stat.AvgWatchTime, 10),
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"strconv"
@@ -28,7 +29,7 @@ func FavoriteAction(c context.Context, ctx *app.RequestContext) {
 	videoId, err := strconv.ParseInt(videoIdStr, 10, 64)
 	actionTypeStr := ctx.Query("action_type")
 	actionType, err := strconv.ParseInt(actionTypeStr, 10, 32)
-	logger.GlobalLogger.Printf("videoId = %v, actionType = %v", videoId, actionType)
+	logger.Ctx(c).Debug().Msgf("videoId = %v, actionType = %v", videoId, actionType)
 	if err != nil {
 		ctx.JSON(consts.StatusOK, api.Response{
 			StatusCode: int32(api.InputFormatCheckErr),
@@ -36,7 +37,7 @@ func FavoriteAction(c context.Context, ctx *app.RequestContext) {
 		})
 		return
 	}
-	err = service.GetFavoriteServiceInstance().FavoriteInfo(loginUserId, videoId, int32(actionType))
+	err = service.GetFavoriteServiceInstance().FavoriteInfo(c, loginUserId, videoId, int32(actionType))
 	if err != nil {
 		if errors.Is(constants.RecordNotExistErr, err) {
 			ctx.JSON(consts.StatusOK, api.Response{
@@ -86,16 +87,38 @@ func FavoriteList(c context.Context, ctx *app.RequestContext) {
 		})
 		return
 	}
-	videoList, err := service.GetFavoriteServiceInstance().FavoriteListInfo(loginUserId, userId)
+	limit, err := pageUtils.ParseLimit(ctx.Query("limit"))
 	if err != nil {
 		ctx.JSON(consts.StatusOK, api.Response{
-			StatusCode: int32(api.InnerDataBaseErr),
-			StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
+			StatusCode: int32(api.InvalidCursorErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InvalidCursorErr],
 		})
 		return
 	}
+	videoList, next, hasMore, err := service.GetFavoriteServiceInstance().FavoriteListInfo(loginUserId, userId, ctx.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(constants.InvalidCursorErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InvalidCursorErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InvalidCursorErr],
+			})
+		} else if errors.Is(constants.UserNotExistErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.UserNotExistErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.UserNotExistErr],
+			})
+		} else {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InnerDataBaseErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
+			})
+		}
+		return
+	}
 	ctx.JSON(consts.StatusOK, VideoListResponse{
-		Response:  api.Response{StatusCode: 0},
-		VideoList: *videoList,
+		Response:   api.Response{StatusCode: 0},
+		VideoList:  *videoList,
+		NextCursor: next,
+		HasMore:    hasMore,
 	})
 }
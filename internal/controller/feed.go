@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"net/http"
@@ -23,30 +25,17 @@ type FeedResponse struct {
 
 // Feed 推送视频流
 func Feed(c context.Context, ctx *app.RequestContext) {
-	token := ctx.Query("token")
-	var userId int64
-	var err error
-	if token != "" {
-		jwt.JwtMiddleware.MiddlewareFunc()(c, ctx)
-		userId, err = jwt.GetUserId(c, ctx)
-		if err != nil {
-			logger.GlobalLogger.Printf("Time = %v ,can't get user From token", time.Now())
-			if errors.Is(constants.InvalidTokenErr, err) {
-				ctx.JSON(consts.StatusOK, api.Response{
-					StatusCode: int32(api.TokenInvalidErr),
-					StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
-				})
-			} else {
-				ctx.JSON(consts.StatusOK, api.Response{
-					StatusCode: int32(api.InnerDataBaseErr),
-					StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
-				})
-			}
-			return
-		}
+	userId, err := jwt.OptionalUserId(c, ctx)
+	if err != nil {
+		logger.Ctx(c).Warn().Msgf("Time = %v ,can't get user From token", time.Now())
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
 	}
 	latestTimeStr := ctx.Query("latest_time")
-	logger.GlobalLogger.Printf("Time = %v, latestTime = %v", time.Now(), latestTimeStr)
+	logger.Ctx(c).Debug().Msgf("Time = %v, latestTime = %v", time.Now(), latestTimeStr)
 	var latestTime time.Time
 	if latestTimeStr == "" {
 		latestTime = time.Now()
@@ -74,6 +63,11 @@ func Feed(c context.Context, ctx *app.RequestContext) {
 				StatusCode: int32(api.NoVideoErr),
 				StatusMsg:  api.ErrorCodeToMsg[api.NoVideoErr],
 			})
+		} else if errors.Is(rpcUtils.ErrBreakerOpen, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.ServiceUnavailableErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.ServiceUnavailableErr],
+			})
 		} else {
 			ctx.JSON(consts.StatusOK, api.Response{
 				StatusCode: int32(api.InnerDataBaseErr),
@@ -89,3 +83,67 @@ func Feed(c context.Context, ctx *app.RequestContext) {
 		NextTime:  nextTime,
 	})
 }
+
+type HotFeedResponse struct {
+	api.Response
+	VideoList  []api.Video `json:"video_list,omitempty"`
+	NextOffset int64       `json:"next_offset"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// HotFeed 分页推送热门视频榜单
+func HotFeed(c context.Context, ctx *app.RequestContext) {
+	userId, err := jwt.OptionalUserId(c, ctx)
+	if err != nil {
+		logger.Ctx(c).Warn().Msgf("Time = %v ,can't get user From token", time.Now())
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(ctx.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	count, err := strconv.ParseInt(ctx.DefaultQuery("count", strconv.Itoa(initialization.HotConf.ListLength)), 10, 64)
+	if err != nil || count <= 0 || count > int64(initialization.HotConf.ListLength) {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+
+	videoList, nextOffset, hasMore, err := service.GetHotServiceInstance().HotFeed(userId, offset, count)
+	if err != nil {
+		if errors.Is(constants.NoVideoErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.NoVideoErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.NoVideoErr],
+			})
+		} else if errors.Is(constants.RedisDBErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.RedisDBErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+			})
+		} else {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InnerDataBaseErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, HotFeedResponse{
+		Response:   api.Response{StatusCode: 0},
+		VideoList:  videoList,
+		NextOffset: nextOffset,
+		HasMore:    hasMore,
+	})
+}
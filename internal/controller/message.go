@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
 	"github.com/cloudwego/hertz/pkg/app"
 	"net/http"
 	"strconv"
@@ -42,6 +43,7 @@ func MessageAction(c context.Context, ctx *app.RequestContext) {
 		} else {
 			tempChat[chatKey] = []api.Message{curMessage}
 		}
+		metrics.Business(metrics.EventMessage)
 		ctx.JSON(http.StatusOK, api.Response{StatusCode: 0})
 	} else {
 		ctx.JSON(http.StatusOK, api.Response{StatusCode: 1, StatusMsg: "User doesn't exist"})
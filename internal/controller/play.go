@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"strconv"
+)
+
+// PlayAction 视频播放事件上报接口，包括开始播放、观看时长与完整播放
+func PlayAction(c context.Context, ctx *app.RequestContext) {
+	var err error
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	videoId, err := strconv.ParseInt(ctx.Query("video_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	eventType, err := strconv.ParseInt(ctx.Query("event_type"), 10, 32)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	watchDuration, err := strconv.ParseInt(ctx.DefaultQuery("watch_duration", "0"), 10, 64)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InputFormatCheckErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+		})
+		return
+	}
+	err = service.GetPlayServiceInstance().PlayInfo(c, loginUserId, videoId, int32(eventType), watchDuration)
+	if err != nil {
+		if errors.Is(constants.RecordNotExistErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.RecordNotExistErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.RecordNotExistErr],
+			})
+		} else if errors.Is(constants.UnKnownActionTypeErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.UnKnownActionType),
+				StatusMsg:  api.ErrorCodeToMsg[api.UnKnownActionType],
+			})
+		} else if errors.Is(constants.InputFormatCheckErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InputFormatCheckErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
+			})
+		} else {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InnerDataBaseErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
+			})
+		}
+		return
+	}
+	ctx.JSON(consts.StatusOK, api.Response{
+		StatusCode: 0,
+	})
+}
@@ -10,17 +10,20 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"strconv"
 	"time"
 )
 
 type VideoListResponse struct {
 	api.Response
-	VideoList []api.Video `json:"video_list"`
+	VideoList  []api.Video `json:"video_list"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
 }
 
 // Publish check token then save upload file to public directory
@@ -36,10 +39,10 @@ func Publish(ctx context.Context, requestContext *app.RequestContext) {
 		return
 	}
 
-	logger.GlobalLogger.Printf("Time = %v,get User From loginUser = %v", time.Now(), userId)
+	logger.Ctx(ctx).Debug().Msgf("Time = %v,get User From loginUser = %v", time.Now(), userId)
 	data, err := requestContext.FormFile("data")
 	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v,can't get Video Data from post", time.Now())
+		logger.Ctx(ctx).Warn().Msgf("Time = %v,can't get Video Data from post", time.Now())
 		requestContext.JSON(consts.StatusOK, api.Response{
 			StatusCode: int32(api.GetDataErr),
 			StatusMsg:  api.ErrorCodeToMsg[api.GetDataErr],
@@ -48,9 +51,9 @@ func Publish(ctx context.Context, requestContext *app.RequestContext) {
 	}
 	title := requestContext.Query("title")
 	address := initialization.RpcCSConf.VideoServiceHost + initialization.RpcCSConf.VideoServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(ctx).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	c := pbservice.NewVideoServiceInfoClient(conn)
@@ -68,7 +71,7 @@ func Publish(ctx context.Context, requestContext *app.RequestContext) {
 	}
 
 	// Contact the server and print out its response.
-	ctx1, cancel1 := context.WithTimeout(context.Background(), time.Second)
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Second)
 	defer cancel1()
 	_, err = c.PublishVideoInfo(ctx1, &pbservice.VideoServicePost{
 		UserId:   userId,
@@ -77,7 +80,13 @@ func Publish(ctx context.Context, requestContext *app.RequestContext) {
 		FileSize: data.Size,
 		Content:  content,
 	})
-	if err != nil {
+	if errors.Is(rpcUtils.ErrBreakerOpen, err) {
+		requestContext.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.ServiceUnavailableErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.ServiceUnavailableErr],
+		})
+		return
+	} else if err != nil {
 		requestContext.JSON(consts.StatusOK, api.Response{
 			StatusCode: int32(api.UploadFailErr),
 			StatusMsg:  api.ErrorCodeToMsg[api.UploadFailErr],
@@ -93,7 +102,7 @@ func Publish(ctx context.Context, requestContext *app.RequestContext) {
 func PublishList(c context.Context, ctx *app.RequestContext) {
 	loginUserId, err := jwt.GetUserId(c, ctx)
 	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v,can't get user From token", time.Now())
+		logger.Ctx(c).Warn().Msgf("Time = %v,can't get user From token", time.Now())
 		if errors.Is(constants.InvalidTokenErr, err) {
 			ctx.JSON(consts.StatusOK, api.Response{
 				StatusCode: int32(api.TokenInvalidErr),
@@ -110,7 +119,7 @@ func PublishList(c context.Context, ctx *app.RequestContext) {
 
 	userStr := ctx.Query("user_id")
 	userId, err := strconv.ParseInt(userStr, 10, 64)
-	logger.GlobalLogger.Printf("userId = %v", userId)
+	logger.Ctx(c).Debug().Msgf("userId = %v", userId)
 	if err != nil {
 		ctx.JSON(consts.StatusOK, api.Response{
 			StatusCode: int32(api.InputFormatCheckErr),
@@ -119,11 +128,41 @@ func PublishList(c context.Context, ctx *app.RequestContext) {
 		return
 	}
 
-	videoList, err := service.GetVideoServiceInstance().PublishListInfo(userId, loginUserId)
+	limit, err := pageUtils.ParseLimit(ctx.Query("limit"))
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.InvalidCursorErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.InvalidCursorErr],
+		})
+		return
+	}
+
+	videoList, next, hasMore, err := service.GetVideoServiceInstance().PublishListInfo(c, userId, loginUserId, ctx.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(constants.InvalidCursorErr, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InvalidCursorErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InvalidCursorErr],
+			})
+		} else if errors.Is(rpcUtils.ErrBreakerOpen, err) {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.ServiceUnavailableErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.ServiceUnavailableErr],
+			})
+		} else {
+			ctx.JSON(consts.StatusOK, api.Response{
+				StatusCode: int32(api.InnerDataBaseErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
+			})
+		}
+		return
+	}
 	ctx.JSON(consts.StatusOK, VideoListResponse{
 		Response: api.Response{
 			StatusCode: 0,
 		},
-		VideoList: videoList,
+		VideoList:  videoList,
+		NextCursor: next,
+		HasMore:    hasMore,
 	})
 }
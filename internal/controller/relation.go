@@ -3,52 +3,95 @@ package controller
 import (
 	"context"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/service"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
 	"github.com/cloudwego/hertz/pkg/app"
-	"net/http"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"strconv"
 )
 
 type UserListResponse struct {
 	api.Response
-	UserList []api.User `json:"user_list"`
+	UserList   []api.User `json:"user_list"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	HasMore    bool       `json:"has_more"`
 }
 
-// RelationAction no practical effect, just check if token is valid
+// RelationAction 关注或取消关注用户
 func RelationAction(c context.Context, ctx *app.RequestContext) {
-	token := ctx.Query("token")
-
-	if _, exist := usersLoginInfo[token]; exist {
-		ctx.JSON(http.StatusOK, api.Response{StatusCode: 0})
-	} else {
-		ctx.JSON(http.StatusOK, api.Response{StatusCode: 1, StatusMsg: "User doesn't exist"})
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	toUserId, err := strconv.ParseInt(ctx.Query("to_user_id"), 10, 64)
+	if err != nil {
+		writeInputFormatErr(ctx)
+		return
+	}
+	actionType, err := strconv.ParseInt(ctx.Query("action_type"), 10, 32)
+	if err != nil {
+		writeInputFormatErr(ctx)
+		return
 	}
+	if err = service.GetFollowServiceInstance().RelationActionInfo(loginUserId, toUserId, int32(actionType)); err != nil {
+		writeInteractErr(ctx, err)
+		return
+	}
+	ctx.JSON(consts.StatusOK, api.Response{StatusCode: 0})
 }
 
-// FollowList all users have same follow list
+// FollowList 按关注时间倒序分页获取用户关注的用户
 func FollowList(c context.Context, ctx *app.RequestContext) {
-	ctx.JSON(http.StatusOK, UserListResponse{
-		Response: api.Response{
-			StatusCode: 0,
-		},
-		UserList: []api.User{DemoUser},
-	})
+	userList(c, ctx, service.GetFollowServiceInstance().FollowListInfo)
 }
 
-// FollowerList all users have same follower list
+// FollowerList 按关注时间倒序分页获取关注了用户的用户
 func FollowerList(c context.Context, ctx *app.RequestContext) {
-	ctx.JSON(http.StatusOK, UserListResponse{
-		Response: api.Response{
-			StatusCode: 0,
-		},
-		UserList: []api.User{DemoUser},
-	})
+	userList(c, ctx, service.GetFollowServiceInstance().FollowerListInfo)
 }
 
-// FriendList all users have same friend list
+// FriendList 按关注时间倒序分页获取与用户互相关注的用户
 func FriendList(c context.Context, ctx *app.RequestContext) {
-	ctx.JSON(http.StatusOK, UserListResponse{
+	userList(c, ctx, service.GetFollowServiceInstance().FriendListInfo)
+}
+
+// userList 解析user_id、cursor与limit后调用list获取一页用户
+func userList(c context.Context, ctx *app.RequestContext,
+	list func(loginUserId, userId int64, cursor string, limit int) ([]api.User, string, bool, error)) {
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	userId, err := strconv.ParseInt(ctx.Query("user_id"), 10, 64)
+	if err != nil {
+		writeInputFormatErr(ctx)
+		return
+	}
+	limit, err := pageUtils.ParseLimit(ctx.Query("limit"))
+	if err != nil {
+		writeInteractErr(ctx, err)
+		return
+	}
+	users, next, hasMore, err := list(loginUserId, userId, ctx.Query("cursor"), limit)
+	if err != nil {
+		writeInteractErr(ctx, err)
+		return
+	}
+	ctx.JSON(consts.StatusOK, UserListResponse{
 		Response: api.Response{
 			StatusCode: 0,
 		},
-		UserList: []api.User{DemoUser},
+		UserList:   users,
+		NextCursor: next,
+		HasMore:    hasMore,
 	})
 }
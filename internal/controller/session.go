@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/sessionUtils"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// SessionList 列出当前用户在所有设备上的登录会话
+func SessionList(c context.Context, ctx *app.RequestContext) {
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	sessions, err := jwt.SessionStore.List(c, loginUserId)
+	if err != nil {
+		logger.Ctx(c).Error().Err(err).Msgf("fail to list sessions of user %v", loginUserId)
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.RedisDBErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+		})
+		return
+	}
+	current := jwt.GetSessionId(c, ctx)
+	sessionList := make([]api.Session, len(sessions))
+	for i, session := range sessions {
+		sessionList[i] = api.Session{
+			SessionId:  session.Id,
+			DeviceName: session.DeviceName,
+			Ip:         session.Ip,
+			CreateTime: session.CreateTime.UnixMilli(),
+			LastSeen:   session.LastSeen.UnixMilli(),
+			Current:    session.Id == current,
+		}
+	}
+	ctx.JSON(consts.StatusOK, api.SessionListResponse{
+		Response:    api.Response{StatusCode: 0},
+		SessionList: sessionList,
+	})
+}
+
+// SessionRevoke 使当前用户的一个登录会话失效，session_id为空时使除当前会话外的所有会话失效
+func SessionRevoke(c context.Context, ctx *app.RequestContext) {
+	loginUserId, err := jwt.GetUserId(c, ctx)
+	if err != nil {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.TokenInvalidErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+		})
+		return
+	}
+	sessionId := ctx.Query("session_id")
+	if sessionId == "" {
+		_, err = jwt.SessionStore.RevokeOthers(c, loginUserId, jwt.GetSessionId(c, ctx))
+	} else {
+		err = jwt.SessionStore.Revoke(c, loginUserId, sessionId)
+	}
+	if err == sessionUtils.ErrSessionNotExist {
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.RecordNotExistErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.RecordNotExistErr],
+		})
+		return
+	} else if err != nil {
+		logger.Ctx(c).Error().Err(err).Msgf("fail to revoke sessions of user %v", loginUserId)
+		ctx.JSON(consts.StatusOK, api.Response{
+			StatusCode: int32(api.RedisDBErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+		})
+		return
+	}
+	ctx.JSON(consts.StatusOK, api.Response{StatusCode: 0})
+}
@@ -9,11 +9,12 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/jwt"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rateLimiter"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 	"strconv"
 	"time"
@@ -43,23 +44,37 @@ func Register(content context.Context, requestContext *app.RequestContext) {
 				StatusMsg:  api.ErrorCodeToMsg[api.InputFormatCheckErr],
 			},
 		})
+		return
+	}
+	// 限制同一IP注册的频率，防止批量注册账号，redis不可用时不拦截
+	if wait, err := jwt.Guard.AllowRegister(content, requestContext.ClientIP()); err != nil {
+		logger.Ctx(content).Error().Err(err).Msg("fail to check register limit")
+	} else if wait > 0 {
+		requestContext.Header("Retry-After", rateLimiter.RetryAfter(wait))
+		requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
+			Response: api.Response{
+				StatusCode: int32(api.TooManyRequestsErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.TooManyRequestsErr],
+			},
+		})
+		return
 	}
 	address := initialization.RpcCSConf.UserServiceHost + initialization.RpcCSConf.UserServicePort
-	logger.GlobalLogger.Printf("address = %v", address)
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	logger.Ctx(content).Debug().Msgf("address = %v", address)
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(content).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	grpcClient := pbuser.NewUserServiceInfoClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(content, time.Second)
 	defer cancel()
 	_, err = grpcClient.UserRegister(ctx, &pbuser.UserServicePost{
 		Username: user.Username,
 		Password: user.Password,
 	})
 	if err != nil {
-		logger.GlobalLogger.Printf("Got error from RPC ,error = %v", err)
+		logger.Ctx(content).Error().Err(err).Msg("Got error from RPC")
 		if errors.Is(status.Errorf(codes.AlreadyExists, constants.UserAlreadyExistErr.Error()), err) {
 			requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
 				Response: api.Response{
@@ -74,6 +89,13 @@ func Register(content context.Context, requestContext *app.RequestContext) {
 					StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
 				},
 			})
+		} else if errors.Is(rpcUtils.ErrBreakerOpen, err) {
+			requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
+				Response: api.Response{
+					StatusCode: int32(api.ServiceUnavailableErr),
+					StatusMsg:  api.ErrorCodeToMsg[api.ServiceUnavailableErr],
+				},
+			})
 		} else {
 			requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
 				Response: api.Response{
@@ -84,7 +106,7 @@ func Register(content context.Context, requestContext *app.RequestContext) {
 		}
 		return
 	}
-	jwt.JwtMiddleware.LoginHandler(content, requestContext)
+	jwt.LoginHandler(content, requestContext)
 }
 
 func UserInfo(content context.Context, requestContext *app.RequestContext) {
@@ -102,21 +124,29 @@ func UserInfo(content context.Context, requestContext *app.RequestContext) {
 	userIdStr := requestContext.Query("user_id")
 	userId, err := strconv.ParseInt(userIdStr, 10, 64)
 	address := initialization.RpcCSConf.UserServiceHost + initialization.RpcCSConf.UserServicePort
-	logger.GlobalLogger.Printf("address = %v", address)
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	logger.Ctx(content).Debug().Msgf("address = %v", address)
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(content).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	grpcClient := pbuser.NewUserServiceInfoClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(content, time.Second)
 	defer cancel()
 	result, err := grpcClient.GetUserInfo(ctx, &pbuser.UserServicePost{
 		QueryUserId: userId,
 		LoginUserId: loginUserId,
 	})
-	if err != nil {
-		logger.GlobalLogger.Printf("Failed to remotely access UserService ,error = %v", err)
+	if errors.Is(rpcUtils.ErrBreakerOpen, err) {
+		requestContext.JSON(consts.StatusOK, api.UserResponse{
+			Response: api.Response{
+				StatusCode: int32(api.ServiceUnavailableErr),
+				StatusMsg:  api.ErrorCodeToMsg[api.ServiceUnavailableErr],
+			},
+		})
+		return
+	} else if err != nil {
+		logger.Ctx(content).Error().Err(err).Msg("Failed to remotely access UserService")
 		requestContext.JSON(consts.StatusOK, api.UserResponse{
 			Response: api.Response{
 				StatusCode: int32(api.InnerConnectionErr),
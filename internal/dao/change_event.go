@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+var errEntityChangedFormat = errors.New("unknown entity changed message")
+
+// addChangeEvent 在事务tx中写入一条实体变更事件，与数据的修改一同提交，由发件箱的relay发送
+// 每个实例都广播消费该事件，删除各自的进程内缓存与redis中的缓存
+func addChangeEvent(tx *gorm.DB, event *api.EntityChangedEvent) error {
+	event.Version = api.EntityChangedEventVersion
+	event.EventId = uuid.New().String()
+	event.Timestamp = time.Now().UnixMilli()
+	return addOutbox(tx, event.EventId, constants.KafkaTopicPrefix+"entity_changed",
+		event.Entity+"_"+strconv.FormatInt(event.EntityId, 10), event)
+}
+
+// DecodeEntityChangedEvent 解析一条实体变更事件
+func DecodeEntityChangedEvent(msg *mqUtils.Message) (*api.EntityChangedEvent, error) {
+	var event api.EntityChangedEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return nil, errEntityChangedFormat
+	}
+	if event.Version > api.EntityChangedEventVersion || event.Entity == "" {
+		return nil, errEntityChangedFormat
+	}
+	return &event, nil
+}
@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// commentDao 与comment相关的数据库操作
+type commentDao struct{}
+
+var (
+	commentDaoInstance *commentDao
+	commentOnce        sync.Once
+
+	errCommentEventFormat = errors.New("unknown comment event")
+)
+
+// GetCommentDaoInstance 获取一个Dao层与Comment操作有关的Instance
+func GetCommentDaoInstance() *commentDao {
+	commentOnce.Do(func() {
+		commentDaoInstance = &commentDao{}
+	})
+	return commentDaoInstance
+}
+
+// AddComment 在一个事务中写入评论、增加视频的评论数并发送评论事件
+func (c *commentDao) AddComment(comment *model.Comment) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if err := updateCommentCount(tx, comment.VideoID, 1); err != nil {
+			return err
+		}
+		return addCommentEvent(tx, comment, api.CommentAddAction, comment.CreatedAt)
+	})
+}
+
+// DeleteComment 在一个事务中删除用户userId在视频videoId下的评论commentId、减少视频的评论数并发送评论事件
+// 可能返回的错误类型：RecordNotExistErr, RecordNotMatchErr, InnerDataBaseErr
+func (c *commentDao) DeleteComment(commentId, userId, videoId int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var comment model.Comment
+		err := tx.Where("id = ?", commentId).First(&comment).Error
+		if errors.Is(gorm.ErrRecordNotFound, err) {
+			return constants.RecordNotExistErr
+		} else if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if comment.UserID != userId || comment.VideoID != videoId {
+			return constants.RecordNotMatchErr
+		}
+		if err = tx.Delete(&comment).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if err = updateCommentCount(tx, videoId, -1); err != nil {
+			return err
+		}
+		return addCommentEvent(tx, &comment, api.CommentDeleteAction, time.Now())
+	})
+}
+
+// addCommentEvent 在事务tx中通过发件箱发送评论事件，以videoId作为Key使同一视频的评论事件按顺序发送
+func addCommentEvent(tx *gorm.DB, comment *model.Comment, action int32, at time.Time) error {
+	event := &api.CommentEvent{
+		Version:   api.CommentEventVersion,
+		EventId:   uuid.New().String(),
+		CommentId: int64(comment.ID),
+		UserId:    comment.UserID,
+		VideoId:   comment.VideoID,
+		Action:    action,
+		Timestamp: at.UnixMilli(),
+	}
+	if err := addOutbox(tx, event.EventId, constants.KafkaTopicPrefix+"comment",
+		strconv.FormatInt(comment.VideoID, 10), event); err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
+}
+
+// DecodeCommentEvent 解析一条评论事件
+func DecodeCommentEvent(msg *mqUtils.Message) (*api.CommentEvent, error) {
+	var event api.CommentEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return nil, errCommentEventFormat
+	}
+	if event.Version > api.CommentEventVersion || event.EventId == "" || event.Timestamp <= 0 {
+		return nil, errCommentEventFormat
+	}
+	if event.Action != api.CommentAddAction && event.Action != api.CommentDeleteAction {
+		return nil, errCommentEventFormat
+	}
+	return &event, nil
+}
+
+// GetCommentList 从数据库中按主键倒序获得视频的评论，seq为上一页最后一条评论的主键，为0时从头开始
+func (c *commentDao) GetCommentList(videoId, seq int64, limit int) ([]*model.Comment, error) {
+	comments := make([]*model.Comment, 0)
+	query := db.Where("video_id = ?", videoId)
+	if seq > 0 {
+		query = query.Where("id < ?", seq)
+	}
+	if err := query.Order("id desc").Limit(limit).Find(&comments).Error; err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return comments, nil
+}
+
+// updateCommentCount 在事务tx中修改视频的评论数，并通知各实例删除视频的缓存
+func updateCommentCount(tx *gorm.DB, videoId int64, delta int) error {
+	query := tx.Model(&model.Video{}).Where("video_id = ?", videoId)
+	if delta < 0 {
+		query = query.Where("comment_count >= ?", -delta)
+	}
+	if err := query.UpdateColumn("comment_count", gorm.Expr("comment_count + ?", delta)).Error; err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return addChangeEvent(tx, &api.EntityChangedEvent{
+		Entity:   api.EntityVideo,
+		EntityId: videoId,
+		Fields:   []string{"comment_count"},
+	})
+}
@@ -11,10 +11,16 @@ var (
 	dbOnce sync.Once
 )
 
-// DaoInitialization 初始化Dao层的服务，包括获取DB以及Kafka
+// DaoInitialization 初始化Dao层的服务，包括获取DB、Kafka以及发件箱的relay
 func DaoInitialization() {
+	DaoDataBaseInitialization()
+	initKafkaClient()
+	startOutboxRelay()
+}
+
+// DaoDataBaseInitialization 只获取DB，不启动消息队列的消费者，用于命令行工具
+func DaoDataBaseInitialization() {
 	dbOnce.Do(func() {
 		db = initialization.GetDB()
-		initKafkaClient()
 	})
 }
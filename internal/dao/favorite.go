@@ -1,15 +1,16 @@
 package dao
 
 import (
+	"context"
 	"errors"
-	"github.com/Shopify/sarama"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
-	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
 //userDao 与favorite相关的数据库操作
@@ -48,76 +49,173 @@ func (f favoriteDao) SetFavoriteCount(videoId int64, favoriteCount int32) error
 			Where("video_id = ?", videoId).Update("favorite_count", favoriteCount).Error; err != nil {
 			return constants.InnerDataBaseErr
 		}
-		return nil
+		return addChangeEvent(tx, &api.EntityChangedEvent{
+			Entity:   api.EntityVideo,
+			EntityId: videoId,
+			Fields:   []string{"favorite_count"},
+		})
 	})
 }
 
-//FavoriteAction 向数据库中插入一条点赞记录，若已有点赞记录，将该点赞记录设置为1
+//FavoriteAction 向数据库中插入一条点赞记录，若已有被软删除的点赞记录，删除后重新插入
 func (f *favoriteDao) FavoriteAction(userId, videoId int64) error {
 	return db.Transaction(func(tx *gorm.DB) error {
-		var err error
-		var favor model.Favourite
-		err = tx.Where("video_id = ? And user_id = ?", videoId, userId).First(&favor).Error
-		if errors.Is(gorm.ErrRecordNotFound, err) {
-			favor.UserID = userId
-			favor.VideoID = videoId
-			favor.IsFavor = 1
-			if err = tx.Create(&favor).Error; err != nil {
-				return constants.InnerDataBaseErr
-			}
-			return nil
-		} else if err != nil {
-			return constants.InnerDataBaseErr
-		}
-		if favor.IsFavor == 1 {
-			return constants.RecordNotMatchErr
-		}
-		err = tx.Model(&favor).Update("is_favor", 1).Error
-		if err != nil {
-			return constants.InnerDataBaseErr
-		}
-		return nil
+		return favoriteAction(tx, userId, videoId)
 	})
 }
 
 //UnfavoriteAction 从数据库中软删除一条点赞记录，也即将点赞的记录设置为0
 func (f *favoriteDao) UnfavoriteAction(userId, videoId int64) error {
 	return db.Transaction(func(tx *gorm.DB) error {
-		var err error
-		var favor model.Favourite
-		err = tx.Where("video_id = ? And user_id = ?", videoId, userId).First(&favor).Error
-		if errors.Is(gorm.ErrRecordNotFound, err) {
-			return constants.RecordNotExistErr
-		} else if err != nil {
+		return unfavoriteAction(tx, userId, videoId)
+	})
+}
+
+// AddFavoriteRequest 在事务中将用户的点赞请求写入发件箱，由relay发送到点赞消息的topic
+// 以videoId作为Key使同一视频的点赞事件按顺序发送，ctx中的链路随消息保存
+func (f *favoriteDao) AddFavoriteRequest(ctx context.Context, event *api.FavoriteEvent) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := addOutbox(tx, event.EventId, constants.KafkaTopicPrefix+"favorite",
+			strconv.FormatInt(event.VideoId, 10), event); err != nil {
 			return constants.InnerDataBaseErr
 		}
-		if favor.IsFavor == 0 {
-			return constants.RecordNotMatchErr
+		return nil
+	})
+}
+
+// ApplyFavoriteEvent 在一个事务中记录点赞事件并写入点赞记录，返回事件是否被应用
+// 事件ID重复的事件视为重放，早于同一用户对同一视频已应用事件的事件视为乱序，二者都不会改变点赞记录
+func (f *favoriteDao) ApplyFavoriteEvent(ctx context.Context, event *model.FavouriteEvent) (bool, error) {
+	applied := false
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int64
+		err := tx.Model(&model.FavouriteEvent{}).Select("COALESCE(MAX(event_time), 0)").
+			Where("user_id = ? And video_id = ? And applied = ?", event.UserID, event.VideoID, true).
+			Scan(&latest).Error
+		if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		event.Applied = event.EventTime >= latest
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+		if result.Error != nil {
+			return constants.InnerDataBaseErr
+		}
+		if result.RowsAffected == 0 || !event.Applied {
+			return nil
+		}
+		switch event.Action {
+		case api.FavoriteAction:
+			err = favoriteAction(tx, event.UserID, event.VideoID)
+		case api.UnFavoriteAction:
+			err = unfavoriteAction(tx, event.UserID, event.VideoID)
+		default:
+			return constants.UnKnownActionTypeErr
+		}
+		// 点赞记录已经是事件对应的状态，不需要通知下游
+		if errors.Is(constants.RecordNotMatchErr, err) || errors.Is(constants.RecordNotExistErr, err) {
+			applied = true
+			return nil
 		}
-		err = tx.Model(&favor).Update("is_favor", 0).Error
 		if err != nil {
+			return err
+		}
+		// 点赞状态发生了变化，在同一事务中写入发件箱，下游的计数只会据此更新一次
+		applied = true
+		return addOutbox(tx, event.EventID, constants.KafkaTopicPrefix+"favorite_applied",
+			strconv.FormatInt(event.VideoID, 10), &api.FavoriteEvent{
+				Version:   api.FavoriteEventVersion,
+				EventId:   event.EventID,
+				UserId:    event.UserID,
+				VideoId:   event.VideoID,
+				Action:    event.Action,
+				Timestamp: event.EventTime,
+			})
+	})
+	return applied, err
+}
+
+// DeleteFavoriteEventsBefore 删除早于before的已处理点赞事件
+func (f *favoriteDao) DeleteFavoriteEventsBefore(before time.Time) error {
+	if err := db.Where("created_at < ?", before).Delete(&model.FavouriteEvent{}).Error; err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
+}
+
+func favoriteAction(tx *gorm.DB, userId, videoId int64) error {
+	var favor model.Favourite
+	err := tx.Where("video_id = ? And user_id = ?", videoId, userId).First(&favor).Error
+	if errors.Is(gorm.ErrRecordNotFound, err) {
+		favor.UserID = userId
+		favor.VideoID = videoId
+		favor.IsFavor = 1
+		if err = tx.Create(&favor).Error; err != nil {
 			return constants.InnerDataBaseErr
 		}
 		return nil
-	})
+	} else if err != nil {
+		return constants.InnerDataBaseErr
+	}
+	if favor.IsFavor == 1 {
+		return constants.RecordNotMatchErr
+	}
+	//重新插入一条记录，使主键顺序与点赞顺序一致，分页时按主键倒序
+	if err = tx.Delete(&favor).Error; err != nil {
+		return constants.InnerDataBaseErr
+	}
+	if err = tx.Create(&model.Favourite{UserID: userId, VideoID: videoId, IsFavor: 1}).Error; err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
 }
 
-// GetFavoriteList 从数据库中获得userId点赞过的所有video
-func (f *favoriteDao) GetFavoriteList(userId int64) ([]*model.Video, error) {
+func unfavoriteAction(tx *gorm.DB, userId, videoId int64) error {
+	var favor model.Favourite
+	err := tx.Where("video_id = ? And user_id = ?", videoId, userId).First(&favor).Error
+	if errors.Is(gorm.ErrRecordNotFound, err) {
+		return constants.RecordNotExistErr
+	} else if err != nil {
+		return constants.InnerDataBaseErr
+	}
+	if favor.IsFavor == 0 {
+		return constants.RecordNotMatchErr
+	}
+	if err = tx.Model(&favor).Update("is_favor", 0).Error; err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
+}
+
+// GetFavoriteList 从数据库中按主键倒序获得userId的点赞记录，seq为上一页最后一条记录的主键，为0时从头开始
+func (f *favoriteDao) GetFavoriteList(userId, seq int64, limit int) ([]*model.Favourite, error) {
 	favors := make([]*model.Favourite, 0)
-	err := db.Where("user_id = ? And is_favor = ?", userId, 1).Find(&favors).Error
+	query := db.Where("user_id = ? And is_favor = ?", userId, 1)
+	if seq > 0 {
+		query = query.Where("id < ?", seq)
+	}
+	if err := query.Order("id desc").Limit(limit).Find(&favors).Error; err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return favors, nil
+}
+
+// CountFavorites 根据点赞记录统计一组视频的点赞数，没有点赞记录的视频不在结果中
+func (f *favoriteDao) CountFavorites(videoIds []int64) (map[int64]int32, error) {
+	type favoriteCount struct {
+		VideoID int64
+		Cnt     int32
+	}
+	counts := make([]favoriteCount, 0)
+	err := db.Model(&model.Favourite{}).Select("video_id, COUNT(*) AS cnt").
+		Where("video_id IN ? And is_favor = ?", videoIds, 1).Group("video_id").Scan(&counts).Error
 	if err != nil {
 		return nil, constants.InnerDataBaseErr
 	}
-	n := len(favors)
-	videos := make([]*model.Video, n)
-	for i, fav := range favors {
-		videos[i], err = GetVideoDaoInstance().GetVideoByVideoIdInfo(fav.VideoID)
-		if err != nil {
-			return nil, err
-		}
+	result := make(map[int64]int32, len(counts))
+	for _, c := range counts {
+		result[c.VideoID] = c.Cnt
 	}
-	return videos, nil
+	return result, nil
 }
 
 // CheckFavorite 查看一个用户是否点赞过一个视频
@@ -140,53 +238,3 @@ func (f *favoriteDao) HardDeleteUnFavorite() error {
 	}
 	return nil
 }
-
-// getFromMessageQueue 从消息队列中异步获取点赞信息，然后将信息写入数据库
-func (f *favoriteDao) getFromMessageQueue() error {
-	partitionList, err := kafkaClient.Partitions(constants.KafkaTopicPrefix + "favorite") // 根据topic取到所有的分区
-	if err != nil {
-		logger.GlobalLogger.Printf("fail to get list of partition:err%v\n", err)
-		return constants.KafkaClientErr
-	}
-	logger.GlobalLogger.Printf("partitionList = %v", partitionList)
-	for _, partition := range partitionList { // 遍历所有的分区
-		logger.GlobalLogger.Printf("partition = %v", partition)
-		// 针对每个分区创建一个对应的分区消费者
-		pc, err2 := kafkaClient.ConsumePartition(constants.KafkaTopicPrefix+"favorite", partition, sarama.OffsetNewest)
-		if err2 != nil {
-			logger.GlobalLogger.Printf("failed to start consumer for partition %d,err:%v\n", partition, err)
-			return constants.KafkaClientErr
-		}
-		defer pc.AsyncClose()
-		for msg := range pc.Messages() {
-			// 异步从每个分区消费信息
-			msg1 := msg
-			go func() {
-				logger.GlobalLogger.Print("Got messageFrom 消息队列")
-				key := string(msg1.Key)
-				value := string(msg1.Value)
-				logger.GlobalLogger.Printf("Partition:%d Offset:%d Key:%v Value:%v\n", msg1.Partition, msg1.Offset, key, value)
-				idx := strings.Index(value, ":")
-				userId, _ := strconv.ParseInt(value[0:idx], 10, 64)
-				videoId, _ := strconv.ParseInt(value[idx+1:], 10, 64)
-				logger.GlobalLogger.Printf("userId:%d videoId:%d", userId, videoId)
-				if key == "Favorite" {
-					for {
-						err1 := f.FavoriteAction(userId, videoId)
-						if err1 == nil {
-							break
-						}
-					}
-				} else {
-					for {
-						err1 := f.UnfavoriteAction(userId, videoId)
-						if err1 == nil {
-							break
-						}
-					}
-				}
-			}()
-		}
-	}
-	return nil
-}
@@ -0,0 +1,281 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errFavoriteMessageFormat = errors.New("unknown favorite message")
+	errNoPublisher           = errors.New("no publisher for dead letter")
+)
+
+// favoriteConsumer 基于消费组的点赞消息消费者
+// 同一分区内按消息的Key把消息分配到固定的worker，保证同一视频的点赞事件按顺序写入数据库；
+// 只有一条消息之前的消息全部处理完成后才提交它的offset，因此重启或再平衡后不会丢失消息
+type favoriteConsumer struct {
+	publisher mqUtils.Publisher
+}
+
+// consumeFromMessageQueue 加入点赞消息的消费组并持续消费
+func (f *favoriteDao) consumeFromMessageQueue() {
+	subscriber := mqUtils.GetSubscriber()
+	if subscriber == nil {
+		logger.GlobalLogger.Warn().Msg("message queue is not initialized, favorite consumer not started")
+		return
+	}
+	topic := constants.KafkaTopicPrefix + "favorite"
+	handler := &favoriteConsumer{publisher: mqUtils.GetPublisher()}
+	err := subscriber.SubscribeGroup(context.Background(), initialization.FavoriteConsumerConf.GroupId, []string{topic}, handler)
+	if err != nil {
+		logger.GlobalLogger.Error().Err(err).Msg("favorite consumer stopped")
+	}
+}
+
+// ConsumeClaim 消费一个分区，再平衡时停止分发新的消息，等待已分发的消息处理完成后退出
+func (c *favoriteConsumer) ConsumeClaim(session mqUtils.GroupSession, claim mqUtils.GroupClaim) error {
+	conf := initialization.FavoriteConsumerConf
+	tracker := &offsetTracker{finished: make(map[int64]struct{})}
+	workers := make([]chan *mqUtils.Message, conf.Workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = make(chan *mqUtils.Message, conf.QueueSize)
+		wg.Add(1)
+		go func(ch chan *mqUtils.Message) {
+			defer wg.Done()
+			for msg := range ch {
+				if !c.process(session.Context(), msg) {
+					// 未处理完成的消息不提交offset，由下一次分配到该分区的消费者重新处理
+					continue
+				}
+				if offset, ok := tracker.done(msg.Offset); ok {
+					session.MarkOffset(msg.Topic, msg.Partition, offset+1)
+				}
+			}
+		}(workers[i])
+	}
+	defer func() {
+		for _, ch := range workers {
+			close(ch)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			tracker.add(msg.Offset)
+			// worker的队列满时阻塞，限制同时处理的消息数
+			select {
+			case workers[workerIndex(msg, len(workers))] <- msg:
+			case <-session.Context().Done():
+				return nil
+			}
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// workerIndex 同一Key的消息总是分配给同一个worker，旧格式的消息Key为行为，按Value分配
+func workerIndex(msg *mqUtils.Message, n int) int {
+	h := fnv.New32a()
+	if len(msg.Value) > 0 && msg.Value[0] == '{' {
+		h.Write(msg.Key)
+	} else {
+		h.Write(msg.Value)
+	}
+	return int(h.Sum32() % uint32(n))
+}
+
+// process 处理一条点赞消息，失败时按指数退避重试，超过最大重试次数或消息格式错误时写入死信topic
+// 返回消息是否已经处理完成，可以提交offset
+func (c *favoriteConsumer) process(ctx context.Context, msg *mqUtils.Message) bool {
+	ctx, span := tracing.StartConsumerSpan(ctx, msg.Topic, msg.Headers)
+	defer span.End()
+	conf := initialization.FavoriteConsumerConf
+	backoff := time.Duration(conf.RetryBackoff) * time.Millisecond
+	var err error
+	for i := 0; i <= conf.MaxRetries; i++ {
+		err = applyFavoriteMessage(ctx, msg)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, errFavoriteMessageFormat) || i == conf.MaxRetries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	logger.Ctx(ctx).Error().Err(err).Msgf("fail to apply favorite message at partition %v offset %v",
+		msg.Partition, msg.Offset)
+	span.RecordError(err)
+	return c.sendToDeadLetter(ctx, msg, err)
+}
+
+// applyFavoriteMessage 解析点赞事件并在消息的链路中写入数据库，重放与乱序的事件会被丢弃
+func applyFavoriteMessage(ctx context.Context, msg *mqUtils.Message) error {
+	event, err := DecodeFavoriteEvent(msg)
+	if err != nil {
+		return err
+	}
+	applied, err := GetFavoriteDaoInstance().ApplyFavoriteEvent(ctx, &model.FavouriteEvent{
+		EventID:   event.EventId,
+		UserID:    event.UserId,
+		VideoID:   event.VideoId,
+		Action:    event.Action,
+		EventTime: event.Timestamp,
+	})
+	if errors.Is(constants.UnKnownActionTypeErr, err) {
+		return errFavoriteMessageFormat
+	}
+	if err == nil && !applied {
+		logger.Ctx(ctx).Warn().Msgf("skip duplicated or out-of-order favorite event %v of user %v video %v",
+			event.EventId, event.UserId, event.VideoId)
+	}
+	return err
+}
+
+// DecodeFavoriteEvent 解析一条点赞消息
+// 旧格式的消息Key为"Favorite"或"Unfavorite"，Value为"userId:videoId"，以分区与offset作为事件ID，以消息时间作为事件时间
+func DecodeFavoriteEvent(msg *mqUtils.Message) (*api.FavoriteEvent, error) {
+	if len(msg.Value) > 0 && msg.Value[0] == '{' {
+		var event api.FavoriteEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return nil, errFavoriteMessageFormat
+		}
+		if event.Version > api.FavoriteEventVersion || event.EventId == "" || event.Timestamp <= 0 {
+			return nil, errFavoriteMessageFormat
+		}
+		return &event, nil
+	}
+	value := string(msg.Value)
+	idx := strings.Index(value, ":")
+	if idx < 0 {
+		return nil, errFavoriteMessageFormat
+	}
+	userId, err := strconv.ParseInt(value[:idx], 10, 64)
+	if err != nil {
+		return nil, errFavoriteMessageFormat
+	}
+	videoId, err := strconv.ParseInt(value[idx+1:], 10, 64)
+	if err != nil {
+		return nil, errFavoriteMessageFormat
+	}
+	event := &api.FavoriteEvent{
+		EventId:   fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset),
+		UserId:    userId,
+		VideoId:   videoId,
+		Timestamp: msg.Timestamp.UnixMilli(),
+	}
+	if msg.Timestamp.Unix() <= 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+	switch string(msg.Key) {
+	case "Favorite":
+		event.Action = api.FavoriteAction
+	case "Unfavorite":
+		event.Action = api.UnFavoriteAction
+	default:
+		return nil, errFavoriteMessageFormat
+	}
+	return event, nil
+}
+
+// sendToDeadLetter 将无法处理的消息连同来源与错误信息写入死信topic，失败时按指数退避重试
+// 超过重试次数后记录完整的消息并计入丢弃的消息数，仍然返回true提交offset，避免一条消息阻塞整个分区
+// 只有消费被中止时返回false，由下一次分配到该分区的消费者重新处理
+func (c *favoriteConsumer) sendToDeadLetter(ctx context.Context, msg *mqUtils.Message, cause error) bool {
+	conf := initialization.FavoriteConsumerConf
+	err := errNoPublisher
+	if c.publisher != nil {
+		deadLetter := &mqUtils.Message{
+			Topic: msg.Topic + conf.DeadLetterSuffix,
+			Key:   msg.Key,
+			Value: msg.Value,
+			Headers: map[string]string{
+				"origin_topic":     msg.Topic,
+				"origin_partition": strconv.FormatInt(int64(msg.Partition), 10),
+				"origin_offset":    strconv.FormatInt(msg.Offset, 10),
+				"error":            cause.Error(),
+			},
+		}
+		backoff := time.Duration(conf.RetryBackoff) * time.Millisecond
+		for i := 0; i <= conf.DeadLetterRetries; i++ {
+			if err = c.publisher.Publish(ctx, deadLetter); err == nil {
+				return true
+			}
+			if i == conf.DeadLetterRetries {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	logger.Ctx(ctx).Error().Err(err).Msgf("drop favorite message at partition %v offset %v, key = %v, value = %v, cause = %v",
+		msg.Partition, msg.Offset, string(msg.Key), string(msg.Value), cause)
+	metrics.KafkaDropped.WithLabelValues(msg.Topic).Inc()
+	return true
+}
+
+// offsetTracker 记录一个分区中已分发的offset，消息可能乱序完成，只有连续完成的offset才能提交
+type offsetTracker struct {
+	mu       sync.Mutex
+	pending  []int64
+	finished map[int64]struct{}
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, offset)
+}
+
+// done 标记offset处理完成，返回可以提交的最大offset
+func (t *offsetTracker) done(offset int64) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished[offset] = struct{}{}
+	var committable int64
+	advanced := false
+	for len(t.pending) > 0 {
+		head := t.pending[0]
+		if _, ok := t.finished[head]; !ok {
+			break
+		}
+		delete(t.finished, head)
+		t.pending = t.pending[1:]
+		committable = head
+		advanced = true
+	}
+	return committable, advanced
+}
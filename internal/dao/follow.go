@@ -0,0 +1,180 @@
+package dao
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// followDao 与关注相关的数据库操作
+type followDao struct{}
+
+var (
+	followDaoInstance *followDao
+	followOnce        sync.Once
+
+	errFollowEventFormat = errors.New("unknown follow event")
+)
+
+// GetFollowDaoInstance 获取一个Dao层与Follow操作有关的Instance
+func GetFollowDaoInstance() *followDao {
+	followOnce.Do(func() {
+		followDaoInstance = &followDao{}
+	})
+	return followDaoInstance
+}
+
+// FollowAction 在一个事务中写入userId关注toUserId的记录，增加双方的关注数与粉丝数，并发送关注事件
+// 若已有被软删除的关注记录，删除后重新插入，使主键顺序与关注顺序一致
+// 可能返回的错误类型：RecordNotMatchErr, InnerDataBaseErr
+func (f *followDao) FollowAction(userId, toUserId int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var follow model.Follow
+		err := tx.Where("from_user_id = ? And to_user_id = ?", userId, toUserId).First(&follow).Error
+		if err == nil {
+			if follow.IsFollow == 1 {
+				return constants.RecordNotMatchErr
+			}
+			if err = tx.Delete(&follow).Error; err != nil {
+				return constants.InnerDataBaseErr
+			}
+		} else if !errors.Is(gorm.ErrRecordNotFound, err) {
+			return constants.InnerDataBaseErr
+		}
+		if err = tx.Create(&model.Follow{FromUserID: userId, ToUserID: toUserId, IsFollow: 1}).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if err = updateFollowCount(tx, userId, toUserId, 1); err != nil {
+			return err
+		}
+		return addFollowEvent(tx, userId, toUserId, api.FollowAction)
+	})
+}
+
+// UnfollowAction 在一个事务中软删除userId关注toUserId的记录，减少双方的关注数与粉丝数，并发送关注事件
+// 可能返回的错误类型：RecordNotExistErr, RecordNotMatchErr, InnerDataBaseErr
+func (f *followDao) UnfollowAction(userId, toUserId int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var follow model.Follow
+		err := tx.Where("from_user_id = ? And to_user_id = ?", userId, toUserId).First(&follow).Error
+		if errors.Is(gorm.ErrRecordNotFound, err) {
+			return constants.RecordNotExistErr
+		} else if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if follow.IsFollow == 0 {
+			return constants.RecordNotMatchErr
+		}
+		if err = tx.Model(&follow).Update("is_follow", 0).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if err = updateFollowCount(tx, userId, toUserId, -1); err != nil {
+			return err
+		}
+		return addFollowEvent(tx, userId, toUserId, api.UnFollowAction)
+	})
+}
+
+// addFollowEvent 在事务tx中通过发件箱发送关注事件，以toUserId作为Key使同一用户的新增粉丝事件按顺序发送
+func addFollowEvent(tx *gorm.DB, userId, toUserId int64, action int32) error {
+	event := &api.FollowEvent{
+		Version:   api.FollowEventVersion,
+		EventId:   uuid.New().String(),
+		UserId:    userId,
+		ToUserId:  toUserId,
+		Action:    action,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := addOutbox(tx, event.EventId, constants.KafkaTopicPrefix+"follow",
+		strconv.FormatInt(toUserId, 10), event); err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
+}
+
+// DecodeFollowEvent 解析一条关注事件
+func DecodeFollowEvent(msg *mqUtils.Message) (*api.FollowEvent, error) {
+	var event api.FollowEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return nil, errFollowEventFormat
+	}
+	if event.Version > api.FollowEventVersion || event.EventId == "" || event.Timestamp <= 0 {
+		return nil, errFollowEventFormat
+	}
+	if event.Action != api.FollowAction && event.Action != api.UnFollowAction {
+		return nil, errFollowEventFormat
+	}
+	return &event, nil
+}
+
+// GetFollowList 从数据库中按主键倒序获得userId的关注记录，seq为上一页最后一条记录的主键，为0时从头开始
+func (f *followDao) GetFollowList(userId, seq int64, limit int) ([]*model.Follow, error) {
+	return getFollows(db.Where("from_user_id = ? And is_follow = ?", userId, 1), seq, limit)
+}
+
+// GetFollowerList 从数据库中按主键倒序获得关注userId的记录，seq为上一页最后一条记录的主键，为0时从头开始
+func (f *followDao) GetFollowerList(userId, seq int64, limit int) ([]*model.Follow, error) {
+	return getFollows(db.Where("to_user_id = ? And is_follow = ?", userId, 1), seq, limit)
+}
+
+// GetFriendList 从数据库中按主键倒序获得userId关注的、同时也关注了userId的用户的关注记录
+func (f *followDao) GetFriendList(userId, seq int64, limit int) ([]*model.Follow, error) {
+	followers := db.Model(&model.Follow{}).Select("from_user_id").Where("to_user_id = ? And is_follow = ?", userId, 1)
+	return getFollows(db.Where("from_user_id = ? And is_follow = ? And to_user_id IN (?)", userId, 1, followers), seq, limit)
+}
+
+// CheckFollow 查看userId是否关注了toUserId
+func (f *followDao) CheckFollow(userId, toUserId int64) (bool, error) {
+	var follow model.Follow
+	err := db.Where("from_user_id = ? And to_user_id = ? And is_follow = ?", userId, toUserId, 1).First(&follow).Error
+	if errors.Is(gorm.ErrRecordNotFound, err) {
+		return false, nil
+	} else if err != nil {
+		return false, constants.InnerDataBaseErr
+	}
+	return true, nil
+}
+
+func getFollows(query *gorm.DB, seq int64, limit int) ([]*model.Follow, error) {
+	follows := make([]*model.Follow, 0)
+	if seq > 0 {
+		query = query.Where("id < ?", seq)
+	}
+	if err := query.Order("id desc").Limit(limit).Find(&follows).Error; err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return follows, nil
+}
+
+// updateFollowCount 在事务tx中修改userId的关注数与toUserId的粉丝数，并通知各实例删除双方的缓存
+func updateFollowCount(tx *gorm.DB, userId, toUserId int64, delta int) error {
+	for _, update := range []struct {
+		userId int64
+		column string
+	}{{userId, "follow_count"}, {toUserId, "follower_count"}} {
+		query := tx.Model(&model.User{}).Where("user_id = ?", update.userId)
+		if delta < 0 {
+			query = query.Where(update.column+" >= ?", -delta)
+		}
+		if err := query.UpdateColumn(update.column, gorm.Expr(update.column+" + ?", delta)).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		err := addChangeEvent(tx, &api.EntityChangedEvent{
+			Entity:   api.EntityUser,
+			EntityId: update.userId,
+			Fields:   []string{update.column},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,26 +1,14 @@
 package dao
 
 import (
-	"github.com/Shopify/sarama"
-	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"sync"
 )
 
-var (
-	kafkaClient sarama.Consumer
-	kafkaOnce   sync.Once
-)
+var kafkaOnce sync.Once
 
+// initKafkaClient 启动点赞消息的消费组
 func initKafkaClient() {
 	kafkaOnce.Do(func() {
-		kafkaClient = initialization.GetKafkaClient()
-		go func() {
-			for {
-				err := GetFavoriteDaoInstance().getFromMessageQueue()
-				if err == nil {
-					break
-				}
-			}
-		}()
+		go GetFavoriteDaoInstance().consumeFromMessageQueue()
 	})
 }
@@ -0,0 +1,237 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// outboxDao 与发件箱相关的数据库操作
+// 业务数据与待发送的消息在同一事务中写入，由relay按主键顺序发送到消息队列，
+// relay在发送后、标记为已发送前崩溃时消息会被重新发送，下游需要按事件ID去重
+type outboxDao struct{}
+
+const (
+	outboxPending int8 = 0
+	outboxSent    int8 = 1
+
+	outboxPurgeInterval = time.Hour
+)
+
+var (
+	outboxDaoInstance *outboxDao
+	outboxOnce        sync.Once
+	outboxRelayOnce   sync.Once
+)
+
+// GetOutboxDaoInstance 获取一个Dao层与发件箱操作有关的Instance
+func GetOutboxDaoInstance() *outboxDao {
+	outboxOnce.Do(func() {
+		outboxDaoInstance = &outboxDao{}
+	})
+	return outboxDaoInstance
+}
+
+// addOutbox 在事务tx中写入一条待发送的消息，payload会被序列化为JSON
+// tx的context中的链路写入消息头一同保存，relay发送时带上，使消费者的span接在写入者的链路之后
+func addOutbox(tx *gorm.DB, eventId, topic, key string, payload interface{}) error {
+	value, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var headers []byte
+	if carrier := tracing.InjectHeaders(tx.Statement.Context, nil); len(carrier) > 0 {
+		if headers, err = json.Marshal(carrier); err != nil {
+			return err
+		}
+	}
+	return tx.Create(&model.Outbox{
+		EventID:       eventId,
+		Topic:         topic,
+		MsgKey:        key,
+		Payload:       value,
+		Headers:       string(headers),
+		Status:        outboxPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// startOutboxRelay 启动发件箱的relay，定时将待发送的消息发送到消息队列，并清理超过保留时间的已发送消息
+// 开始关闭时relay退出，尚未发送的消息留在发件箱中，下次启动后继续发送
+func startOutboxRelay() {
+	outboxRelayOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(time.Duration(initialization.OutboxConf.Interval) * time.Millisecond)
+			defer ticker.Stop()
+			lastPurge := time.Now()
+			for {
+				select {
+				case <-lifecycle.Context().Done():
+					return
+				case <-ticker.C:
+				}
+				if time.Since(lastPurge) > outboxPurgeInterval {
+					retention := time.Duration(initialization.OutboxConf.RetentionDays) * 24 * time.Hour
+					if err := GetOutboxDaoInstance().DeleteSentOutboxBefore(time.Now().Add(-retention)); err != nil {
+						logger.GlobalLogger.Error().Err(err).Msg("fail to purge outbox")
+					}
+					lastPurge = time.Now()
+				}
+				publisher := mqUtils.GetPublisher()
+				if publisher == nil {
+					continue
+				}
+				// 每批中同一Key只发送最早的一条，还有消息发送成功时继续发送直到清空或出错
+				for lifecycle.Context().Err() == nil {
+					sent, err := GetOutboxDaoInstance().RelayOutbox(publisher, initialization.OutboxConf.BatchSize)
+					if err != nil {
+						logger.GlobalLogger.Error().Err(err).Msg("fail to relay outbox")
+						break
+					}
+					if sent == 0 {
+						break
+					}
+				}
+			}
+		}()
+	})
+}
+
+// RelayOutbox 取出一批到期的待发送消息并按主键顺序发送，返回发送成功的条数
+// 同一topic与Key下只取出最早的一条待发送消息，它发送失败推迟重试时之后的消息也不会被发送，保证同一Key的消息按顺序发送
+func (o *outboxDao) RelayOutbox(publisher mqUtils.Publisher, limit int) (int, error) {
+	rows, err := o.claimOutbox(limit)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, row := range rows {
+		err = publisher.Publish(context.Background(), &mqUtils.Message{
+			Topic:   row.Topic,
+			Key:     []byte(row.MsgKey),
+			Value:   row.Payload,
+			Headers: outboxHeaders(row),
+		})
+		if err != nil {
+			if err = markOutboxFailed(row, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		now := time.Now()
+		err = db.Model(row).Updates(map[string]interface{}{"status": outboxSent, "sent_at": &now}).Error
+		if err != nil {
+			return sent, constants.InnerDataBaseErr
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// outboxHeaders 发送时的消息头：写入时保存的链路以及事件ID，保存的消息头无法解析时只发送事件ID
+func outboxHeaders(row *model.Outbox) map[string]string {
+	headers := make(map[string]string)
+	if row.Headers != "" {
+		if err := json.Unmarshal([]byte(row.Headers), &headers); err != nil {
+			logger.GlobalLogger.Warn().Err(err).Msgf("ignore invalid headers of outbox %v", row.EventID)
+			headers = make(map[string]string)
+		}
+	}
+	headers["event_id"] = row.EventID
+	return headers
+}
+
+// claimOutbox 在一个短事务中取出一批消息，并将它们的下一次尝试时间推迟ClaimTimeout，事务提交后再发送
+// 多个relay同时运行时跳过彼此锁住的消息；relay在发送完成前崩溃时，消息在ClaimTimeout之后被重新取出发送
+func (o *outboxDao) claimOutbox(limit int) ([]*model.Outbox, error) {
+	var rows []*model.Outbox
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 先不加锁地找出各Key最早的待发送消息，其他relay已取出、尚未提交的消息在这里仍然可见，会挡住同一Key之后的消息
+		// 同一Key更早的待发送消息通过idx_status_key查找，积压时不会逐条扫描
+		table := tx.NamingStrategy.TableName("Outbox")
+		var candidates []uint
+		err := tx.Model(&model.Outbox{}).Select("id").
+			Where("status = ? And next_attempt_at <= ?", outboxPending, now).
+			Where("NOT EXISTS (SELECT 1 FROM "+table+" AS earlier WHERE earlier.status = ? And earlier.topic = "+
+				table+".topic And earlier.msg_key = "+table+".msg_key And earlier.id < "+table+".id)", outboxPending).
+			Order("id").Limit(limit).Find(&candidates).Error
+		if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+		// 再对候选加锁，并按最新的状态排除已被其他relay取出的消息
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id IN ? And status = ? And next_attempt_at <= ?", candidates, outboxPending, now).
+			Order("id").Find(&rows).Error
+		if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+		claimTimeout := time.Duration(initialization.OutboxConf.ClaimTimeout) * time.Second
+		if err = tx.Model(&model.Outbox{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(claimTimeout)).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// markOutboxFailed 记录一次发送失败，重试的等待时间随失败次数翻倍，不超过配置的上限
+func markOutboxFailed(row *model.Outbox, cause error) error {
+	backoff := time.Second << uint(minInt(int(row.Attempts), 16))
+	if maxBackoff := time.Duration(initialization.OutboxConf.MaxBackoff) * time.Second; backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	lastError := cause.Error()
+	if len(lastError) > 255 {
+		lastError = lastError[:255]
+	}
+	err := db.Model(row).Updates(map[string]interface{}{
+		"attempts":        row.Attempts + 1,
+		"next_attempt_at": time.Now().Add(backoff),
+		"last_error":      lastError,
+	}).Error
+	if err != nil {
+		return constants.InnerDataBaseErr
+	}
+	logger.GlobalLogger.Error().Err(cause).Msgf("fail to send outbox %v to %v, attempts = %v", row.EventID, row.Topic, row.Attempts+1)
+	return nil
+}
+
+// DeleteSentOutboxBefore 删除早于before发送成功的消息
+func (o *outboxDao) DeleteSentOutboxBefore(before time.Time) error {
+	if err := db.Where("status = ? And sent_at < ?", outboxSent, before).Delete(&model.Outbox{}).Error; err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// statDao 与创作者数据统计相关的数据库操作集合
+type statDao struct{}
+
+var (
+	statDaoInstance *statDao
+	statOnce        sync.Once
+)
+
+// GetStatDaoInstance 获取一个statDao的实例
+func GetStatDaoInstance() *statDao {
+	statOnce.Do(func() {
+		statDaoInstance = &statDao{}
+	})
+	return statDaoInstance
+}
+
+// SaveVideoDailyStat 写入视频某一天的统计，已存在时覆盖
+func (s *statDao) SaveVideoDailyStat(stat *model.VideoDailyStat) error {
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "video_id"}, {Name: "stat_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "views", "favorites", "comments", "watch_time", "watch_count"}),
+	}).Create(stat).Error
+	if err != nil {
+		return constants.InnerDataBaseErr
+	}
+	return nil
+}
+
+// RollupCreatorDailyStat 将创作者所有视频某一天的统计汇总为创作者的每日统计
+// newFollowers为当天的新增粉丝数，为nil时保留已有的新增粉丝数
+func (s *statDao) RollupCreatorDailyStat(userId int64, date time.Time, newFollowers *int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		stat := &model.CreatorDailyStat{UserID: userId, StatDate: date}
+		err := tx.Model(&model.VideoDailyStat{}).
+			Select("COALESCE(SUM(views), 0) AS views, COALESCE(SUM(favorites), 0) AS favorites, "+
+				"COALESCE(SUM(comments), 0) AS comments, COALESCE(SUM(watch_time), 0) AS watch_time, "+
+				"COALESCE(SUM(watch_count), 0) AS watch_count").
+			Where("user_id = ? AND stat_date = ?", userId, date).
+			Scan(stat).Error
+		if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		columns := []string{"views", "favorites", "comments", "watch_time", "watch_count"}
+		if newFollowers != nil {
+			stat.NewFollowers = *newFollowers
+			columns = append(columns, "new_followers")
+		}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "stat_date"}},
+			DoUpdates: clause.AssignmentColumns(columns),
+		}).Create(stat).Error
+		if err != nil {
+			return constants.InnerDataBaseErr
+		}
+		return nil
+	})
+}
+
+// GetCreatorDailyStats 获取创作者在[start, end]内的每日统计，按日期升序
+func (s *statDao) GetCreatorDailyStats(userId int64, start, end time.Time) ([]*model.CreatorDailyStat, error) {
+	stats := make([]*model.CreatorDailyStat, 0)
+	err := db.Where("user_id = ? AND stat_date BETWEEN ? AND ?", userId, start, end).
+		Order("stat_date").Find(&stats).Error
+	if err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return stats, nil
+}
+
+// GetVideoDailyStats 获取创作者的视频在[start, end]内的每日统计，videoId为0时获取其所有视频
+func (s *statDao) GetVideoDailyStats(userId, videoId int64, start, end time.Time) ([]*model.VideoDailyStat, error) {
+	stats := make([]*model.VideoDailyStat, 0)
+	query := db.Where("user_id = ? AND stat_date BETWEEN ? AND ?", userId, start, end)
+	if videoId != 0 {
+		query = query.Where("video_id = ?", videoId)
+	}
+	if err := query.Order("video_id").Order("stat_date").Find(&stats).Error; err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return stats, nil
+}
@@ -2,14 +2,18 @@ package dao
 
 import (
 	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	pbdao "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_service_dao/user"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	"gorm.io/gorm"
+	"strconv"
 	"sync"
+	"time"
 )
 
 //userDao 与user相关的数据库操作
@@ -35,7 +39,7 @@ func (u *userDao) AddUser(ctx context.Context, in *pbdao.UserDaoPost) (*wrappers
 	user.UserID = in.GetUserId()
 	user.PassWord = in.GetPassword()
 	user.UserName = in.GetUsername()
-	err := u.CreateUser(user)
+	err := u.CreateUser(ctx, user)
 	if err != nil {
 		return &wrapperspb.BoolValue{Value: false},
 			status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error())
@@ -65,6 +69,17 @@ func (u *userDao) GetUserInfoByUserNameAndPassword(ctx context.Context, in *pbda
 	return returnUserDaoRPC(userInfo, err)
 }
 
+// UpdatePassword RPC远程调用将用户的密码从oldPassword替换为password，用于升级旧的密码哈希
+// 密码已被修改时不替换，返回false
+func (u *userDao) UpdatePassword(ctx context.Context, in *pbdao.UserDaoPost) (*wrapperspb.BoolValue, error) {
+	updated, err := u.ReplacePassword(in.GetUserId(), in.GetOldPassword(), in.GetPassword())
+	if err != nil {
+		return &wrapperspb.BoolValue{Value: false},
+			status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error())
+	}
+	return &wrapperspb.BoolValue{Value: updated}, nil
+}
+
 // GetUserByUsername 通过用户名查找在数据库中的User
 func (u *userDao) GetUserByUsername(username string) (*model.User, error) {
 	userInfos := make([]*model.User, 0)
@@ -101,7 +116,27 @@ func (u *userDao) GetUserByUserId(userId int64) (*model.User, error) {
 	return userInfos[0], nil
 }
 
+// ScanUsers 按主键顺序分批遍历所有用户，seq为上一批最后一个用户的主键
+func (u *userDao) ScanUsers(seq int64, limit int) ([]*model.User, error) {
+	userInfos := make([]*model.User, 0)
+	if err := db.Where("id > ?", seq).Order("id").Limit(limit).Find(&userInfos).Error; err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return userInfos, nil
+}
+
+// ReplacePassword 在数据库中将用户的密码从oldPassword替换为password，返回是否替换成功
+func (u *userDao) ReplacePassword(userId int64, oldPassword, password string) (bool, error) {
+	result := db.Model(&model.User{}).Where("user_id = ? AND pass_word = ?", userId, oldPassword).
+		Update("pass_word", password)
+	if result.Error != nil {
+		return false, constants.InnerDataBaseErr
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // CheckUserByNameAndPassword 通过username与password查找在数据库中的User
+// 密码以带盐的哈希保存后无法按密码查询，只能匹配尚未升级的明文密码，登录由service层校验哈希
 func (u *userDao) CheckUserByNameAndPassword(username string, password string) (*model.User, error) {
 	userInfos := make([]*model.User, 0)
 	if err := db.Where("user_name = ?", username).Where("pass_word = ?", password).Find(&userInfos).Error; err != nil {
@@ -120,13 +155,29 @@ func (u *userDao) CheckUserByNameAndPassword(username string, password string) (
 }
 
 // CreateUser 在数据库中通过事务创建一个新用户,所有的写操作都通过事务完成
-func (u *userDao) CreateUser(user *model.User) error {
-	return db.Transaction(func(tx *gorm.DB) error {
+func (u *userDao) CreateUser(ctx context.Context, user *model.User) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(user).Error; err != nil {
 			// 返回任何错误都会回滚事务
 			return err
 		}
-		return nil
+		// 与用户在同一事务中写入注册事件，由发件箱的relay通知下游写入登录缓存
+		event := &api.UserRegisteredEvent{
+			EventId:   uuid.New().String(),
+			UserId:    user.UserID,
+			UserName:  user.UserName,
+			Timestamp: time.Now().UnixMilli(),
+		}
+		if err := addOutbox(tx, event.EventId, constants.KafkaTopicPrefix+"user_registered",
+			strconv.FormatInt(user.UserID, 10), event); err != nil {
+			return err
+		}
+		// 删除其他实例中该用户名与用户ID的空缓存
+		return addChangeEvent(tx, &api.EntityChangedEvent{
+			Entity:   api.EntityUser,
+			EntityId: user.UserID,
+			UserName: user.UserName,
+		})
 	})
 }
 
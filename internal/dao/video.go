@@ -3,15 +3,18 @@ package dao
 import (
 	"context"
 	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	pbdao "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_service_dao/video"
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	"gorm.io/gorm"
 	"io"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -45,25 +48,21 @@ func (v *videoDao) AddVideo(ctx context.Context, post *pbdao.VideoDaoPost) (*wra
 		PlayURL:       post.PlayURL,
 		CoverURL:      post.CoverURL,
 	}
-	err := v.createVideo(video)
+	err := v.createVideo(ctx, video)
 	if err != nil {
 		return &wrapperspb.BoolValue{Value: false}, status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error())
 	}
 	return &wrapperspb.BoolValue{Value: true}, nil
 }
 
-// GetPublishIdList RPC远程调用获取publish的IdList
-func (v *videoDao) GetPublishIdList(in *wrapperspb.Int64Value, stream pbdao.VideoDaoInfo_GetPublishIdListServer) error {
-	userId := in.Value
-	videoInfos, err := v.GetPublishListInfo(userId)
+// GetPublishIdList RPC远程调用分页获取publish的IdList，按发布顺序倒序
+func (v *videoDao) GetPublishIdList(in *pbdao.PublishListPost, stream pbdao.VideoDaoInfo_GetPublishIdListServer) error {
+	videoInfos, err := v.GetPublishListInfo(in.UserId, in.Seq, int(in.Limit))
 	if err != nil {
 		return status.Errorf(codes.Internal, err.Error())
 	}
-	if len(videoInfos) == 0 {
-		return status.Errorf(codes.NotFound, constants.RecordNotExistErr.Error())
-	}
 	for _, video := range videoInfos {
-		if err := stream.Send(&wrapperspb.Int64Value{Value: video.VideoID}); err != nil {
+		if err := stream.Send(&pbdao.PublishIdMsg{VideoId: video.VideoID, Seq: int64(video.ID)}); err != nil {
 			return err
 		}
 	}
@@ -85,6 +84,9 @@ func (v *videoDao) GetVideoByVideoId(ctx context.Context, in *wrapperspb.Int64Va
 		CommentCount:  videoInfo.CommentCount,
 		PlayURL:       videoInfo.PlayURL,
 		CoverURL:      videoInfo.CoverURL,
+		PlayCount:     videoInfo.PlayCount,
+		WatchTime:     videoInfo.WatchTime,
+		WatchCount:    videoInfo.WatchCount,
 	}, status.New(codes.OK, "").Err()
 }
 
@@ -112,6 +114,9 @@ func (v *videoDao) GetVideoListByVideoIdList(stream pbdao.VideoDaoInfo_GetVideoL
 			CommentCount:  videoInfo.CommentCount,
 			PlayURL:       videoInfo.PlayURL,
 			CoverURL:      videoInfo.CoverURL,
+			PlayCount:     videoInfo.PlayCount,
+			WatchTime:     videoInfo.WatchTime,
+			WatchCount:    videoInfo.WatchCount,
 		}); err != nil {
 			return err
 		}
@@ -119,22 +124,50 @@ func (v *videoDao) GetVideoListByVideoIdList(stream pbdao.VideoDaoInfo_GetVideoL
 }
 
 // createVideo 在数据库中通过事务插入一条Video数据
-func (v *videoDao) createVideo(video *model.Video) error {
-	return db.Transaction(func(tx *gorm.DB) error {
+func (v *videoDao) createVideo(ctx context.Context, video *model.Video) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 在事务中执行一些 db 操作（从这里开始，您应该使用 'tx' 而不是 'db'）
 		if err := tx.Create(video).Error; err != nil {
 			// 返回任何错误都会回滚事务
 			return err
 		}
-		// 返回 nil 提交事务
-		return nil
+		// 与视频在同一事务中写入发布事件，提交后由发件箱的relay发送
+		event := &api.VideoPublishedEvent{
+			EventId:   uuid.New().String(),
+			VideoId:   video.VideoID,
+			UserId:    video.UserID,
+			Timestamp: time.Now().UnixMilli(),
+		}
+		if err := addOutbox(tx, event.EventId, constants.KafkaTopicPrefix+"video_published",
+			strconv.FormatInt(video.VideoID, 10), event); err != nil {
+			return err
+		}
+		// 删除视频的空缓存与作者的发布列表缓存
+		return addChangeEvent(tx, &api.EntityChangedEvent{
+			Entity:   api.EntityVideo,
+			EntityId: video.VideoID,
+			OwnerId:  video.UserID,
+		})
 	})
 }
 
-// GetPublishListInfo 在数据库中获得该user发表过的所有的视频
-func (v *videoDao) GetPublishListInfo(userId int64) ([]*model.Video, error) {
+// GetPublishListInfo 在数据库中按主键倒序获得该user发表过的视频，seq为上一页最后一个视频的主键，为0时从头开始
+func (v *videoDao) GetPublishListInfo(userId, seq int64, limit int) ([]*model.Video, error) {
+	videoInfos := make([]*model.Video, 0)
+	query := db.Where("user_id = ?", userId)
+	if seq > 0 {
+		query = query.Where("id < ?", seq)
+	}
+	if err := query.Order("id desc").Limit(limit).Find(&videoInfos).Error; err != nil {
+		return nil, constants.InnerDataBaseErr
+	}
+	return videoInfos, nil
+}
+
+// ScanVideos 按主键顺序分批获取视频，seq为上一批最后一个视频的主键，为0时从头开始
+func (v *videoDao) ScanVideos(seq int64, limit int) ([]*model.Video, error) {
 	videoInfos := make([]*model.Video, 0)
-	if err := db.Where("user_id = ?", userId).Find(&videoInfos).Error; err != nil {
+	if err := db.Where("id > ?", seq).Order("id").Limit(limit).Find(&videoInfos).Error; err != nil {
 		return nil, constants.InnerDataBaseErr
 	}
 	return videoInfos, nil
@@ -158,12 +191,29 @@ func (v *videoDao) GetFeedList(latestTime time.Time) ([]*model.Video, error) {
 // GetVideoByVideoIdInfo 通过VideoId查找Video
 func (v *videoDao) GetVideoByVideoIdInfo(videoId int64) (*model.Video, error) {
 	videoInfos := make([]*model.Video, 0)
-	if err := db.Where("video_id = ?", videoId).Find(&videoInfos).Error; err != nil {
-		if err != nil || 1 < len(videoInfos) {
-			return nil, constants.InnerDataBaseErr
-		} else if 0 == len(videoInfos) {
-			return nil, constants.RecordNotExistErr
-		}
+	if err := db.Where("video_id = ?", videoId).Find(&videoInfos).Error; err != nil || 1 < len(videoInfos) {
+		return nil, constants.InnerDataBaseErr
+	}
+	if 0 == len(videoInfos) {
+		return nil, constants.RecordNotExistErr
 	}
 	return videoInfos[0], nil
 }
+
+// SetPlayStat 通过videoId设置视频的播放量与观看时长统计
+func (v *videoDao) SetPlayStat(videoId, playCount, watchTime, watchCount int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Video{}).Where("video_id = ?", videoId).Updates(map[string]interface{}{
+			"play_count":  playCount,
+			"watch_time":  watchTime,
+			"watch_count": watchCount,
+		}).Error; err != nil {
+			return constants.InnerDataBaseErr
+		}
+		return addChangeEvent(tx, &api.EntityChangedEvent{
+			Entity:   api.EntityVideo,
+			EntityId: videoId,
+			Fields:   []string{"play_count", "watch_time", "watch_count"},
+		})
+	})
+}
@@ -13,6 +13,9 @@ type Video struct {
 	UserID        int64  `gorm:"type:BIGINT;not null;index:idx_author_id"`
 	FavoriteCount int32  `gorm:"type:INT;not null;default:0"`
 	CommentCount  int32  `gorm:"type:INT;not null;default:0"`
+	PlayCount     int64  `gorm:"type:BIGINT;not null;default:0;comment:去重后的播放量"`
+	WatchTime     int64  `gorm:"type:BIGINT;not null;default:0;comment:累计观看时长(毫秒)"`
+	WatchCount    int64  `gorm:"type:BIGINT;not null;default:0;comment:上报观看时长的次数"`
 	PlayURL       string `gorm:"type:varchar(200);not null"`
 	CoverURL      string `gorm:"type:varchar(200);not null"`
 }
@@ -22,7 +25,7 @@ type User struct {
 	gorm.Model
 	UserID        int64  `gorm:"type:bigint;unsigned;not null;unique;uniqueIndex:idx_user_id" json:"user_id"`
 	UserName      string `gorm:"type:varchar(50);not null;unique;uniqueIndex:idx_user_name" json:"name" validate:"min=6,max=32"`
-	PassWord      string `gorm:"type:varchar(50);not null" json:"password" validate:"min=6,max=32"`
+	PassWord      string `gorm:"type:varchar(128);not null" json:"-"` // 带盐的密码哈希，旧数据为明文或MD5，登录成功后升级
 	FollowCount   int64  `gorm:"type:bigint;unsigned;not null;default:0" json:"follow_count"`
 	FollowerCount int64  `gorm:"type:bigint;unsigned;not null;default:0" json:"follower_count"`
 }
@@ -44,12 +47,41 @@ type Favourite struct {
 	IsFavor int8  `gorm:"type:TINYINT;not null;comment:软删除的点赞记录" json:"is_favor"`
 }
 
+// FavouriteEvent 已处理的点赞事件：数据库实体，用于点赞事件的去重以及乱序检测
+type FavouriteEvent struct {
+	ID        uint      `gorm:"primarykey"`
+	EventID   string    `gorm:"type:varchar(64);not null;uniqueIndex;comment:事件ID"`
+	UserID    int64     `gorm:"type:BIGINT;not null;index:idx_member_time;comment:点赞用户ID"`
+	VideoID   int64     `gorm:"type:BIGINT;not null;index:idx_member_time;comment:被点赞视频ID"`
+	Action    int32     `gorm:"type:TINYINT;not null;comment:1为点赞，2为取消点赞"`
+	EventTime int64     `gorm:"type:BIGINT;not null;index:idx_member_time;comment:事件产生的时间(毫秒)"`
+	Applied   bool      `gorm:"not null;comment:是否写入了点赞记录，早于已应用的事件时为false"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// Outbox 发件箱：数据库实体，与业务数据在同一事务中写入，由relay发送到消息队列后标记为已发送
+type Outbox struct {
+	ID            uint      `gorm:"primarykey;index:idx_status_key,priority:4"`
+	EventID       string    `gorm:"type:varchar(64);not null;uniqueIndex;comment:事件ID，下游据此去重"`
+	Topic         string    `gorm:"type:varchar(128);not null;index:idx_status_key,priority:2;comment:消息的topic"`
+	MsgKey        string    `gorm:"type:varchar(128);not null;default:'';index:idx_status_key,priority:3;comment:消息的Key"`
+	Payload       []byte    `gorm:"type:BLOB;not null;comment:消息的内容"`
+	Headers       string    `gorm:"type:varchar(1024);not null;default:'';comment:写入时的链路等消息头，JSON格式"`
+	Status        int8      `gorm:"type:TINYINT;not null;default:0;index:idx_status_next;index:idx_status_key,priority:1;comment:0为待发送，1为已发送"`
+	Attempts      int32     `gorm:"type:INT;not null;default:0;comment:发送失败的次数"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_status_next;comment:下一次尝试发送的时间"`
+	LastError     string    `gorm:"type:varchar(255);not null;default:'';comment:最近一次发送失败的原因"`
+	CreatedAt     time.Time
+	SentAt        *time.Time `gorm:"index;comment:发送成功的时间"`
+}
+
 // Follow 关注：数据库实体
 type Follow struct {
-	ID         uint  `gorm:"primarykey"`
-	FromUserID int64 `gorm:"type:BIGINT;not null;uniqueIndex:idx_member_id;comment:粉丝用户ID"`
-	ToUserID   int64 `gorm:"type:BIGINT;not null;uniqueIndex:idx_member_id;comment:被关注用户ID"`
-	IsFollow   int8  `gorm:"type:TINYINT;not null;comment:软删除的关注记录"`
+	ID         uint      `gorm:"primarykey"`
+	FromUserID int64     `gorm:"type:BIGINT;not null;uniqueIndex:idx_member_id;comment:粉丝用户ID"`
+	ToUserID   int64     `gorm:"type:BIGINT;not null;uniqueIndex:idx_member_id;comment:被关注用户ID"`
+	IsFollow   int8      `gorm:"type:TINYINT;not null;comment:软删除的关注记录"`
+	CreatedAt  time.Time `gorm:"index;comment:最近一次关注的时间，取消关注后重新关注时记录被重新插入"`
 }
 
 // Message 消息：数据库实体
@@ -60,3 +92,29 @@ type Message struct {
 	ToUserId   int64  `gorm:"type:BIGINT;not null;index:idx_to_user_id;comment:接收用户ID"`
 	Content    string `gorm:"type:varchar(300);not null;comment:聊天内容" json:"content"`
 }
+
+// VideoDailyStat 视频每日统计：数据库实体，由定时聚合任务写入
+type VideoDailyStat struct {
+	ID         uint      `gorm:"primarykey"`
+	VideoID    int64     `gorm:"type:BIGINT;not null;uniqueIndex:idx_video_date;comment:视频ID"`
+	UserID     int64     `gorm:"type:BIGINT;not null;index:idx_user_date;comment:视频作者ID"`
+	StatDate   time.Time `gorm:"type:DATE;not null;uniqueIndex:idx_video_date;index:idx_user_date;comment:统计日期"`
+	Views      int64     `gorm:"type:BIGINT;not null;default:0;comment:去重后的播放量"`
+	Favorites  int64     `gorm:"type:BIGINT;not null;default:0;comment:新增点赞数"`
+	Comments   int64     `gorm:"type:BIGINT;not null;default:0;comment:新增评论数"`
+	WatchTime  int64     `gorm:"type:BIGINT;not null;default:0;comment:累计观看时长(毫秒)"`
+	WatchCount int64     `gorm:"type:BIGINT;not null;default:0;comment:上报观看时长的次数"`
+}
+
+// CreatorDailyStat 创作者每日统计：数据库实体，由该创作者所有视频的每日统计与新增粉丝数聚合而成
+type CreatorDailyStat struct {
+	ID           uint      `gorm:"primarykey"`
+	UserID       int64     `gorm:"type:BIGINT;not null;uniqueIndex:idx_user_date;comment:创作者ID"`
+	StatDate     time.Time `gorm:"type:DATE;not null;uniqueIndex:idx_user_date;comment:统计日期"`
+	Views        int64     `gorm:"type:BIGINT;not null;default:0;comment:去重后的播放量"`
+	Favorites    int64     `gorm:"type:BIGINT;not null;default:0;comment:新增点赞数"`
+	Comments     int64     `gorm:"type:BIGINT;not null;default:0;comment:新增评论数"`
+	NewFollowers int64     `gorm:"type:BIGINT;not null;default:0;comment:新增粉丝数"`
+	WatchTime    int64     `gorm:"type:BIGINT;not null;default:0;comment:累计观看时长(毫秒)"`
+	WatchCount   int64     `gorm:"type:BIGINT;not null;default:0;comment:上报观看时长的次数"`
+}
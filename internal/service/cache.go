@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cacheUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"strconv"
+	"time"
+)
+
+const (
+	userInfoPrefix      = "user_info_"
+	userInfoExpireTime  = 90 * time.Minute
+	videoInfoPrefix     = "video_info_"
+	videoInfoExpireTime = 10 * time.Minute // 视频信息中包含点赞数等统计，有效期较短
+	userIdBloomKey      = "bloom_user_id"
+	videoIdBloomKey     = "bloom_video_id"
+	bloomLoadBatch      = 1000
+)
+
+// userLogin 登录缓存，通过用户名查找用户ID，不包含密码
+type userLogin struct {
+	UserId int64
+}
+
+var (
+	userIdBloom    *cacheUtils.BloomFilter
+	videoIdBloom   *cacheUtils.BloomFilter
+	userInfoCache  *cacheUtils.Cache[model.User]
+	userLoginCache *cacheUtils.Cache[userLogin]
+	videoInfoCache *cacheUtils.Cache[model.Video]
+)
+
+// initCache 创建各实体的缓存，需在redisClient初始化之后调用
+func initCache() {
+	conf := initialization.CacheConf
+	localTTL := time.Duration(conf.LocalTTL) * time.Second
+	userIdBloom = cacheUtils.NewBloomFilter(redisClient, userIdBloomKey, conf.BloomCapacity, conf.BloomFalsePositive)
+	videoIdBloom = cacheUtils.NewBloomFilter(redisClient, videoIdBloomKey, conf.BloomCapacity, conf.BloomFalsePositive)
+	userInfoCache = cacheUtils.NewCache[model.User](redisClient, cacheUtils.Options{
+		Prefix:    userInfoPrefix,
+		TTL:       userInfoExpireTime,
+		EmptyTTL:  emptyCacheExpireTime,
+		Jitter:    30 * time.Minute,
+		LocalSize: conf.LocalSize,
+		LocalTTL:  localTTL,
+		Bloom:     userIdBloom,
+	})
+	userLoginCache = cacheUtils.NewCache[userLogin](redisClient, cacheUtils.Options{
+		Prefix:    userLoginPrefix,
+		TTL:       userLoginExpireTime,
+		EmptyTTL:  emptyCacheExpireTime,
+		Jitter:    30 * time.Minute,
+		LocalSize: conf.LocalSize,
+		LocalTTL:  localTTL,
+	})
+	videoInfoCache = cacheUtils.NewCache[model.Video](redisClient, cacheUtils.Options{
+		Prefix:    videoInfoPrefix,
+		TTL:       videoInfoExpireTime,
+		EmptyTTL:  emptyCacheExpireTime,
+		Jitter:    5 * time.Minute,
+		LocalSize: conf.LocalSize,
+		LocalTTL:  localTTL,
+		Bloom:     videoIdBloom,
+	})
+}
+
+// observeCache 统计不经过cacheUtils.Cache、直接读写redis的缓存的查询结果，与cacheUtils.Cache计入同一指标
+func observeCache(prefix string, hit bool) {
+	result := metrics.CacheMiss
+	if hit {
+		result = metrics.CacheHit
+	}
+	metrics.CacheRequests.WithLabelValues(prefix, result).Inc()
+}
+
+// bloomFiltersReady 用户ID与视频ID的布隆过滤器是否都已经加载完成
+func bloomFiltersReady() bool {
+	ctx := context.Background()
+	userReady, err := userIdBloom.Ready(ctx)
+	if err != nil || !userReady {
+		return false
+	}
+	videoReady, err := videoIdBloom.Ready(ctx)
+	return err == nil && videoReady
+}
+
+// LoadBloomFilters 从数据库中加载所有的用户ID与视频ID到布隆过滤器
+// 新的ID在创建时添加，定时重新加载用于修复添加失败的ID
+func LoadBloomFilters() error {
+	ctx := context.Background()
+	err := userIdBloom.Load(ctx, func(add func(items ...string) error) error {
+		var seq int64
+		for {
+			users, err := dao.GetUserDaoInstance().ScanUsers(seq, bloomLoadBatch)
+			if err != nil {
+				return err
+			}
+			userIds := make([]string, len(users))
+			for i, user := range users {
+				userIds[i] = strconv.FormatInt(user.UserID, 10)
+			}
+			if err = add(userIds...); err != nil {
+				return err
+			}
+			if len(users) < bloomLoadBatch {
+				return nil
+			}
+			seq = int64(users[len(users)-1].ID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return videoIdBloom.Load(ctx, func(add func(items ...string) error) error {
+		var seq int64
+		for {
+			videos, err := dao.GetVideoDaoInstance().ScanVideos(seq, bloomLoadBatch)
+			if err != nil {
+				return err
+			}
+			videoIds := make([]string, len(videos))
+			for i, video := range videos {
+				videoIds[i] = strconv.FormatInt(video.VideoID, 10)
+			}
+			if err = add(videoIds...); err != nil {
+				return err
+			}
+			if len(videos) < bloomLoadBatch {
+				return nil
+			}
+			seq = int64(videos[len(videos)-1].ID)
+		}
+	})
+}
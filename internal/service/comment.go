@@ -1 +1,105 @@
 package service
+
+import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// commentService 与评论相关的操作集合
+type commentService struct{}
+
+var (
+	commentServiceInstance *commentService
+	commentOnce            sync.Once
+)
+
+const commentMaxLength = 300 // 评论内容的最大字符数，与数据库中的字段长度一致
+
+// GetCommentServiceInstance 获取一个commentService的实例
+func GetCommentServiceInstance() *commentService {
+	initRedis()
+	commentOnce.Do(func() {
+		commentServiceInstance = &commentService{}
+	})
+	return commentServiceInstance
+}
+
+// CommentActionInfo service层处理用户userId在视频videoId下发表评论或删除评论commentId，发表时返回新的评论
+// 可能返回的错误类型：UnKnownActionTypeErr, InputFormatCheckErr, RecordNotExistErr, RecordNotMatchErr, InnerDataBaseErr
+func (c *commentService) CommentActionInfo(userId, videoId int64, actionType int32, text string, commentId int64) (*api.Comment, error) {
+	switch actionType {
+	case api.CommentAddAction:
+		if text == "" || utf8.RuneCountInString(text) > commentMaxLength {
+			return nil, constants.InputFormatCheckErr
+		}
+		if _, err := getVideoByVideoId(videoId); err != nil {
+			return nil, err
+		}
+		comment := &model.Comment{UserID: userId, VideoID: videoId, Content: text}
+		if err := dao.GetCommentDaoInstance().AddComment(comment); err != nil {
+			return nil, err
+		}
+		metrics.Business(metrics.EventComment)
+		result, err := getApiComment(userId, comment)
+		if err != nil {
+			return nil, err
+		}
+		return &result, nil
+	case api.CommentDeleteAction:
+		return nil, dao.GetCommentDaoInstance().DeleteComment(commentId, userId, videoId)
+	default:
+		return nil, constants.UnKnownActionTypeErr
+	}
+}
+
+// CommentListInfo service层按评论时间倒序分页获取视频的评论，返回评论列表、下一页的游标以及是否还有更多
+// 可能返回的错误类型：RecordNotExistErr, InvalidCursorErr, InnerDataBaseErr
+func (c *commentService) CommentListInfo(loginUserId, videoId int64, cursor string, limit int) ([]api.Comment, string, bool, error) {
+	page, err := pageUtils.Decode(cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if _, err = getVideoByVideoId(videoId); err != nil {
+		return nil, "", false, err
+	}
+	// 多取一个用于判断是否还有下一页
+	comments, err := dao.GetCommentDaoInstance().GetCommentList(videoId, page.Seq, limit+1)
+	if err != nil {
+		return nil, "", false, err
+	}
+	hasMore := len(comments) > limit
+	if hasMore {
+		comments = comments[:limit]
+	}
+	commentList := make([]api.Comment, len(comments))
+	for i, comment := range comments {
+		if commentList[i], err = getApiComment(loginUserId, comment); err != nil {
+			return nil, "", false, err
+		}
+	}
+	next := ""
+	if hasMore {
+		next = pageUtils.Cursor{Seq: int64(comments[len(comments)-1].ID)}.Encode()
+	}
+	return commentList, next, hasMore, nil
+}
+
+func getApiComment(loginUserId int64, comment *model.Comment) (api.Comment, error) {
+	user, err := getApiUser(loginUserId, comment.UserID)
+	if err != nil {
+		return api.Comment{}, err
+	}
+	return api.Comment{
+		Id:         int64(comment.ID),
+		User:       user,
+		Content:    comment.Content,
+		CreateDate: comment.CreatedAt.In(time.Local).Format(api.CommentDateLayout),
+	}, nil
+}
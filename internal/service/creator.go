@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// creatorService 创作者数据统计相关的操作集合
+// 与热榜、播放统计消费同样的互动消息，先在redis中按天累计，再由定时任务聚合写入数据库的每日统计表
+// 点赞、评论与关注事件由dao层在写入数据的事务中通过发件箱发送，每日的新增数为当天增加与撤销相抵后的净值
+type creatorService struct{}
+
+var (
+	creatorServiceInstance *creatorService
+	creatorOnce            sync.Once
+)
+
+const (
+	statVideoDailyPrefix   = "stat_video_daily_"   // 视频某一天的统计，hash，key为前缀+日期_videoId
+	statCreatorDailyPrefix = "stat_creator_daily_" // 创作者某一天的统计，hash，key为前缀+日期_userId
+	statDirtyKey           = "stat_daily_dirty"    // 有变化、尚未聚合的每日统计，set，member为"v:日期:videoId"或"c:日期:userId"
+	statFieldUserId        = "user_id"
+	statFieldViews         = "views"
+	statFieldFavorites     = "favorites"
+	statFieldComments      = "comments"
+	statFieldNewFollowers  = "new_followers"
+	statFieldWatchTime     = "watch_time"
+	statFieldWatchCount    = "watch_count"
+	statVideoMember        = "v"
+	statCreatorMember      = "c"
+)
+
+// creatorTopics 创作者数据需要消费的互动topic以及对应的计数字段，新增粉丝计入创作者的统计，其余计入视频的统计
+// 点赞只计入已写入数据库、状态发生了变化的点赞事件；播放与观看时长需要先按用户去重，因此由playService统计后再计入
+var creatorTopics = map[string]string{
+	constants.KafkaTopicPrefix + "favorite_applied": statFieldFavorites,
+	constants.KafkaTopicPrefix + "comment":          statFieldComments,
+	constants.KafkaTopicPrefix + "follow":           statFieldNewFollowers,
+}
+
+// GetCreatorServiceInstance 获取一个creatorService的实例
+func GetCreatorServiceInstance() *creatorService {
+	initRedis()
+	creatorOnce.Do(func() {
+		creatorServiceInstance = &creatorService{}
+	})
+	return creatorServiceInstance
+}
+
+func getStatDailyExpireTime() time.Duration {
+	return time.Duration(initialization.CreatorConf.DailyExpireDays) * 24 * time.Hour
+}
+
+// startConsumers 以creator消费组启动互动消息的消费者，多个实例分摊消息，每条消息只计入一次每日统计
+func (c *creatorService) startConsumers() {
+	topics := make([]string, 0, len(creatorTopics))
+	for topic := range creatorTopics {
+		topics = append(topics, topic)
+	}
+	go consumeGroup("creator", topics, func(ctx context.Context, msg *mqUtils.Message) {
+		c.handleEngagement(ctx, creatorTopics[msg.Topic], msg)
+	})
+}
+
+func (c *creatorService) handleEngagement(ctx context.Context, field string, msg *mqUtils.Message) {
+	action, ok := parseActionMessage(msg)
+	if !ok {
+		logger.Ctx(ctx).Warn().Msgf("unknown engagement message, value = %v", string(msg.Value))
+		return
+	}
+	if !firstDelivery(ctx, "creator", action.EventId) {
+		return
+	}
+	var err error
+	if field == statFieldNewFollowers {
+		err = c.addCreatorDaily(ctx, action.To, action.Time, field, action.Delta)
+	} else {
+		err = c.addVideoDaily(ctx, action.To, action.Time, map[string]int64{field: action.Delta})
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to add daily %v of %v", field, action.To)
+	}
+}
+
+// addCreatorDaily 在redis中累加创作者在某一天的统计
+func (c *creatorService) addCreatorDaily(ctx context.Context, userId int64, t time.Time, field string, delta int64) error {
+	date := t.In(time.Local).Format(api.StatDateLayout)
+	userIdStr := strconv.FormatInt(userId, 10)
+	key := statCreatorDailyPrefix + date + "_" + userIdStr
+	pipe := redisClient.TxPipeline()
+	pipe.HIncrBy(ctx, key, field, delta)
+	pipe.Expire(ctx, key, getStatDailyExpireTime())
+	pipe.SAdd(ctx, statDirtyKey, statCreatorMember+":"+date+":"+userIdStr)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return constants.RedisDBErr
+	}
+	return nil
+}
+
+// addVideoDaily 在redis中累加视频在某一天的统计
+func (c *creatorService) addVideoDaily(ctx context.Context, videoId int64, t time.Time, deltas map[string]int64) error {
+	date := t.In(time.Local).Format(api.StatDateLayout)
+	videoIdStr := strconv.FormatInt(videoId, 10)
+	key := statVideoDailyPrefix + date + "_" + videoIdStr
+	exists, err := redisClient.HExists(ctx, key, statFieldUserId).Result()
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	if !exists {
+		video, err := dao.GetVideoDaoInstance().GetVideoByVideoIdInfo(videoId)
+		if err != nil {
+			return err
+		}
+		redisClient.HSetNX(ctx, key, statFieldUserId, video.UserID)
+	}
+	pipe := redisClient.TxPipeline()
+	for field, delta := range deltas {
+		pipe.HIncrBy(ctx, key, field, delta)
+	}
+	pipe.Expire(ctx, key, getStatDailyExpireTime())
+	pipe.SAdd(ctx, statDirtyKey, statVideoMember+":"+date+":"+videoIdStr)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return constants.RedisDBErr
+	}
+	return nil
+}
+
+// AggregateDailyStatRegularly 定时将redis中有变化的每日统计写入视频每日统计表，并汇总为创作者每日统计
+// 需要聚合的统计记录在redis的集合中，因此多个实例之间共享且重启后不会丢失
+func (c *creatorService) AggregateDailyStatRegularly() error {
+	ctx := context.Background()
+	creators := make(map[string]struct{})
+	for {
+		member, err := redisClient.SPop(ctx, statDirtyKey).Result()
+		if err == redis.Nil {
+			break
+		} else if err != nil {
+			c.markDirty(ctx, creators)
+			return constants.RedisDBErr
+		}
+		parts := strings.SplitN(member, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == statCreatorMember {
+			creators[member] = struct{}{}
+			continue
+		}
+		userId, err := c.saveVideoDaily(ctx, parts[1], parts[2])
+		if err != nil {
+			redisClient.SAdd(ctx, statDirtyKey, member)
+			c.markDirty(ctx, creators)
+			return err
+		}
+		if userId != 0 {
+			creators[statCreatorMember+":"+parts[1]+":"+strconv.FormatInt(userId, 10)] = struct{}{}
+		}
+	}
+	for member := range creators {
+		parts := strings.SplitN(member, ":", 3)
+		if err := c.rollupCreatorDaily(ctx, parts[1], parts[2]); err != nil {
+			c.markDirty(ctx, creators)
+			return err
+		}
+		delete(creators, member)
+	}
+	return nil
+}
+
+// markDirty 将尚未汇总的创作者统计放回statDirtyKey，等待下次聚合
+func (c *creatorService) markDirty(ctx context.Context, creators map[string]struct{}) {
+	for member := range creators {
+		redisClient.SAdd(ctx, statDirtyKey, member)
+	}
+}
+
+// saveVideoDaily 将redis中视频某一天的统计写入数据库，返回视频作者的userId
+func (c *creatorService) saveVideoDaily(ctx context.Context, date, videoIdStr string) (int64, error) {
+	counts, err := redisClient.HGetAll(ctx, statVideoDailyPrefix+date+"_"+videoIdStr).Result()
+	if err != nil {
+		return 0, constants.RedisDBErr
+	}
+	statDate, err := time.ParseInLocation(api.StatDateLayout, date, time.Local)
+	if err != nil || len(counts) == 0 {
+		// 格式错误或已过期，无法再聚合
+		return 0, nil
+	}
+	values := make(map[string]int64, len(counts))
+	for field, value := range counts {
+		values[field], _ = strconv.ParseInt(value, 10, 64)
+	}
+	videoId, _ := strconv.ParseInt(videoIdStr, 10, 64)
+	err = dao.GetStatDaoInstance().SaveVideoDailyStat(&model.VideoDailyStat{
+		VideoID:    videoId,
+		UserID:     values[statFieldUserId],
+		StatDate:   statDate,
+		Views:      values[statFieldViews],
+		Favorites:  values[statFieldFavorites],
+		Comments:   values[statFieldComments],
+		WatchTime:  values[statFieldWatchTime],
+		WatchCount: values[statFieldWatchCount],
+	})
+	if err != nil {
+		return 0, err
+	}
+	return values[statFieldUserId], nil
+}
+
+// rollupCreatorDaily 汇总创作者某一天的统计，新增粉丝数读取redis中的累计值，已过期时保留数据库中的值
+func (c *creatorService) rollupCreatorDaily(ctx context.Context, date, userIdStr string) error {
+	statDate, err := time.ParseInLocation(api.StatDateLayout, date, time.Local)
+	if err != nil {
+		return nil
+	}
+	var newFollowers *int64
+	followers, err := redisClient.HGet(ctx, statCreatorDailyPrefix+date+"_"+userIdStr, statFieldNewFollowers).Int64()
+	if err == nil {
+		newFollowers = &followers
+	} else if err != redis.Nil {
+		return constants.RedisDBErr
+	}
+	userId, _ := strconv.ParseInt(userIdStr, 10, 64)
+	return dao.GetStatDaoInstance().RollupCreatorDailyStat(userId, statDate, newFollowers)
+}
+
+// CreatorStats service层获取创作者在[start, end]内的每日统计，以及其视频的每日统计
+// videoId不为0时只返回该视频的统计，没有数据的日期补0
+// 可能返回的错误类型：RecordNotExistErr, RecordNotMatchErr, InnerDataBaseErr
+func (c *creatorService) CreatorStats(userId, videoId int64, start, end time.Time) ([]api.DailyStat, []api.VideoStatSeries, error) {
+	if videoId != 0 {
+		video, err := dao.GetVideoDaoInstance().GetVideoByVideoIdInfo(videoId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if video.UserID != userId {
+			return nil, nil, constants.RecordNotMatchErr
+		}
+	}
+	dates := make([]string, 0)
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format(api.StatDateLayout))
+	}
+
+	creatorStats, err := dao.GetStatDaoInstance().GetCreatorDailyStats(userId, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	creatorByDate := make(map[string]api.DailyStat, len(creatorStats))
+	for _, stat := range creatorStats {
+		date := stat.StatDate.In(time.Local).Format(api.StatDateLayout)
+		creatorByDate[date] = newDailyStat(date, stat.Views, stat.Favorites, stat.Comments, stat.NewFollowers,
+			stat.WatchTime, stat.WatchCount)
+	}
+	creatorSeries := fillDailySeries(dates, creatorByDate)
+
+	videoStats, err := dao.GetStatDaoInstance().GetVideoDailyStats(userId, videoId, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	videoIds := make([]int64, 0)
+	videoByDate := make(map[int64]map[string]api.DailyStat)
+	for _, stat := range videoStats {
+		if _, ok := videoByDate[stat.VideoID]; !ok {
+			videoIds = append(videoIds, stat.VideoID)
+			videoByDate[stat.VideoID] = make(map[string]api.DailyStat)
+		}
+		date := stat.StatDate.In(time.Local).Format(api.StatDateLayout)
+		videoByDate[stat.VideoID][date] = newDailyStat(date, stat.Views, stat.Favorites, stat.Comments, 0,
+			stat.WatchTime, stat.WatchCount)
+	}
+	if videoId != 0 && len(videoIds) == 0 {
+		videoIds = append(videoIds, videoId)
+	}
+	videoSeries := make([]api.VideoStatSeries, 0, len(videoIds))
+	for _, id := range videoIds {
+		videoSeries = append(videoSeries, api.VideoStatSeries{
+			VideoId: id,
+			Series:  fillDailySeries(dates, videoByDate[id]),
+		})
+	}
+	return creatorSeries, videoSeries, nil
+}
+
+func newDailyStat(date string, views, favorites, comments, followers, watchTime, watchCount int64) api.DailyStat {
+	stat := api.DailyStat{
+		Date:         date,
+		Views:        views,
+		Favorites:    favorites,
+		Comments:     comments,
+		NewFollowers: followers,
+	}
+	if watchCount > 0 {
+		stat.AvgWatchTime = watchTime / watchCount
+	}
+	return stat
+}
+
+// fillDailySeries 按日期顺序生成统计序列，没有数据的日期补0
+func fillDailySeries(dates []string, byDate map[string]api.DailyStat) []api.DailyStat {
+	series := make([]api.DailyStat, len(dates))
+	for i, date := range dates {
+		if stat, ok := byDate[date]; ok {
+			series[i] = stat
+		} else {
+			series[i] = api.DailyStat{Date: date}
+		}
+	}
+	return series
+}
@@ -3,14 +3,19 @@ package service
 import (
 	"context"
 	"errors"
-	"github.com/Shopify/sarama"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	pb "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_controller_service/favorite/route"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cacheUtils"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
-	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
-	"math/rand"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
 	"strconv"
 	"sync"
 	"time"
@@ -18,14 +23,12 @@ import (
 
 type favoriteService struct {
 	pb.UnimplementedFavoriteInfoServer
-	videoKeySet map[string]interface{}
 }
 
 var (
 	favoriteServiceInstance *favoriteService
 	favoriteOnce            sync.Once
-	deleteOnce              sync.Once
-	writeOnce               sync.Once
+	favoriteCountGroup      cacheUtils.Group
 )
 
 const (
@@ -33,26 +36,25 @@ const (
 	userFavoriteExpireTime  = 90 * time.Minute
 	videoFavoritePrefix     = "video_favorite_"
 	userFavoritePrefix      = "user_favorite_"
+	videoFavoriteDirtyKey   = "video_favorite_dirty" // 点赞数有变化、尚未写入数据库的视频，set
+	favoriteLoadBatch       = 500
 )
 
 // 获取视频点赞的持续时间
 func getVideoFavoriteExpireTime() time.Duration {
-	return time.Duration(int64(videoFavoriteExpireTime) + rand.Int63n(int64(12*time.Hour)))
+	return cacheUtils.JitterTTL(videoFavoriteExpireTime, 12*time.Hour)
 }
 
 // 获取用户点赞的持续时间
 func getUserFavoriteExpireTime() time.Duration {
-	return time.Duration(int64(userFavoriteExpireTime) + rand.Int63n(int64(30*time.Minute)))
+	return cacheUtils.JitterTTL(userFavoriteExpireTime, 30*time.Minute)
 }
 
 // GetFavoriteServiceInstance 获取一个favoriteService的实例
 func GetFavoriteServiceInstance() *favoriteService {
 	initRedis()
-	initKafka()
 	favoriteOnce.Do(func() {
-		favoriteServiceInstance = &favoriteService{
-			videoKeySet: make(map[string]interface{}, 0),
-		}
+		favoriteServiceInstance = &favoriteService{}
 	})
 	return favoriteServiceInstance
 }
@@ -62,7 +64,7 @@ func (f *favoriteService) FavoriteAction(ctx context.Context, in *pb.FavoriteAct
 	userId := in.UserId
 	videoId := in.VideoId
 	actionType := in.ActionType
-	err := f.FavoriteInfo(userId, videoId, actionType)
+	err := f.FavoriteInfo(ctx, userId, videoId, actionType)
 	if err != nil {
 		return nil, err
 	}
@@ -72,13 +74,20 @@ func (f *favoriteService) FavoriteAction(ctx context.Context, in *pb.FavoriteAct
 	}, nil
 }
 
+// FavoriteList GRPC调用，分页获取用户的点赞列表
 func (f *favoriteService) FavoriteList(userFavorite *pb.UserFavorite, stream pb.FavoriteInfo_FavoriteListServer) error {
 	loginUserId := userFavorite.LoginUserId
 	queryUserId := userFavorite.QueryUserId
-	videoList, err := f.FavoriteListInfo(loginUserId, queryUserId)
+	limit := int(userFavorite.Limit)
+	if limit <= 0 || limit > initialization.PageConf.MaxLimit {
+		limit = initialization.PageConf.DefaultLimit
+	}
+	videoList, next, hasMore, err := f.FavoriteListInfo(loginUserId, queryUserId, userFavorite.Cursor, limit)
 	if err != nil {
 		return err
 	}
+	//分页信息通过trailer返回
+	stream.SetTrailer(metadata.Pairs("next-cursor", next, "has-more", strconv.FormatBool(hasMore)))
 
 	for _, video := range *videoList {
 		err := stream.Send(&pb.VideoResp{
@@ -94,6 +103,8 @@ func (f *favoriteService) FavoriteList(userFavorite *pb.UserFavorite, stream pb.
 			CoverURL:      video.CoverUrl,
 			FavoriteCount: video.FavoriteCount,
 			CommentCount:  video.CommentCount,
+			PlayCount:     video.PlayCount,
+			AvgWatchTime:  video.AvgWatchTime,
 			IsFavorite:    video.IsFavorite,
 		})
 		if err != nil {
@@ -104,193 +115,274 @@ func (f *favoriteService) FavoriteList(userFavorite *pb.UserFavorite, stream pb.
 }
 
 // FavoriteInfo service层处理用户点赞或者取消点赞
-// 当有点赞消息传入时，通过go协程启动分布式定时任务，删除Favorite点赞记录数据库中被软删除的部分
 // 可能返回的错误类型：InnerDataBaseError, RecordNotMatch, RecordNotExist,UnknownActionTypeErr
-func (f *favoriteService) FavoriteInfo(userId, videoId int64, actionType int32) error {
-	//定时删除点赞消息
-	go deleteOnce.Do(func() {
-		for {
-			_, err := cronUtils.CronLab.AddFunc("@every 30m", func() {
-				logger.GlobalLogger.Printf("In DeleteDatabaseRegularly")
-				err := f.DeleteDatabaseRegularly()
-				if err != nil {
-					logger.GlobalLogger.Printf("error occurs in DeleteDatabaseRegularly")
-				}
-			})
-			if err == nil {
-				break
-			}
-		}
-	})
-
-	//定时将redis中的点赞数写入数据库
-	go writeOnce.Do(func() {
-		for {
-			_, err := cronUtils.CronLab.AddFunc("@every 15m", func() {
-				logger.GlobalLogger.Printf("In WriteToDataBaseRegularly")
-				err := f.WriteToDataBaseRegularly()
-				if err != nil {
-					logger.GlobalLogger.Printf("error occurs in WriteToDataBaseRegularly")
-				}
-			})
-			if err == nil {
-				break
-			}
-		}
+func (f *favoriteService) FavoriteInfo(ctx context.Context, userId, videoId int64, actionType int32) error {
+	if actionType != api.FavoriteAction && actionType != api.UnFavoriteAction {
+		return constants.UnKnownActionTypeErr
+	}
+	//布隆过滤器判断视频一定不存在时直接返回
+	if ok, _ := videoIdBloom.MightContain(context.Background(), strconv.FormatInt(videoId, 10)); !ok {
+		return constants.RecordNotExistErr
+	}
+	f.touchVideoFavorite(videoId)
+	// 点赞请求写入发件箱后即返回，由relay发送到消息队列，发送失败时按退避时间重试
+	// 写入数据库后状态发生了变化的点赞事件再由startConsumers更新redis中的点赞数
+	err := dao.GetFavoriteDaoInstance().AddFavoriteRequest(ctx, &api.FavoriteEvent{
+		Version:   api.FavoriteEventVersion,
+		EventId:   uuid.New().String(),
+		UserId:    userId,
+		VideoId:   videoId,
+		Action:    actionType,
+		Timestamp: time.Now().UnixMilli(),
 	})
-
-	go f.writeToKafkaAsyn(userId, videoId, actionType)
-	go func() {
-		for {
-			err := f.writeToRedis(userId, videoId, actionType)
-			if err == nil {
-				break
-			}
-		}
-	}()
+	if err != nil {
+		return err
+	}
+	if actionType == api.FavoriteAction {
+		metrics.Business(metrics.EventFavorite)
+	} else {
+		metrics.Business(metrics.EventUnfavorite)
+	}
 	return nil
 }
 
-// 异步写入消息队列
-func (f *favoriteService) writeToKafkaAsyn(userId, videoId int64, actionType int32) {
-	//将点赞消息写入Kafka
-	for {
-		favoriteMsg := &sarama.ProducerMessage{}
-		favoriteMsg.Topic = constants.KafkaTopicPrefix + "favorite"
-		if actionType == api.FavoriteAction {
-			favoriteMsg.Key = sarama.StringEncoder("Favorite")
-		} else if actionType == api.UnFavoriteAction {
-			favoriteMsg.Key = sarama.StringEncoder("Unfavorite")
+// startConsumers 以favorite_count消费组消费写入数据库后状态发生了变化的点赞事件，据此更新redis中的点赞数与用户的点赞列表
+// 重放与乱序的点赞请求在写入数据库时已被丢弃，不会改变redis中的计数
+func (f *favoriteService) startConsumers() {
+	go consumeGroup("favorite_count", []string{constants.KafkaTopicPrefix + "favorite_applied"}, f.handleApplied)
+}
+
+// handleApplied 处理一条已应用的点赞事件，写入redis失败时按指数退避重试，超过最大重试次数后丢弃，点赞数由对账任务修复
+func (f *favoriteService) handleApplied(ctx context.Context, msg *mqUtils.Message) {
+	event, err := dao.DecodeFavoriteEvent(msg)
+	if err != nil {
+		logger.Ctx(ctx).Warn().Msgf("unknown favorite applied message, value = %v", string(msg.Value))
+		return
+	}
+	if !firstDelivery(ctx, "favorite_count", event.EventId) {
+		return
+	}
+	conf := initialization.FavoriteConsumerConf
+	backoff := time.Duration(conf.RetryBackoff) * time.Millisecond
+	for i := 0; ; i++ {
+		if err = f.writeToRedis(ctx, event.UserId, event.VideoId, event.Action); err == nil {
+			return
 		}
-		favoriteMsg.Value = sarama.StringEncoder(strconv.FormatInt(userId, 10) + ":" + strconv.FormatInt(videoId, 10))
-		pid, offset, err := kafkaServer.SendMessage(favoriteMsg)
-		if err == nil {
-			logger.GlobalLogger.Printf("pid:%v offset:%v\n", pid, offset)
+		if i == conf.MaxRetries {
 			break
 		}
+		select {
+		case <-ctx.Done():
+			logger.Ctx(ctx).Error().Err(ctx.Err()).Msgf("drop favorite count of event %v, user %v video %v",
+				event.EventId, event.UserId, event.VideoId)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
+	logger.Ctx(ctx).Error().Err(err).Msgf("drop favorite count of event %v, user %v video %v after %v retries",
+		event.EventId, event.UserId, event.VideoId, conf.MaxRetries)
 }
 
-//将点赞信息写入redis
-func (f *favoriteService) writeToRedis(userId, videoId int64, actionType int32) error {
+// 将点赞信息写入redis，先更新用户的点赞列表，最后增减点赞数，更新列表失败重试时不会重复计数
+func (f *favoriteService) writeToRedis(ctx context.Context, userId, videoId int64, actionType int32) error {
+	userKey := userFavoritePrefix + strconv.FormatInt(userId, 10)
+	if err := f.loadUserFavoriteList(ctx, userId); err != nil {
+		return err
+	}
+	//列表按点赞时间倒序，重复点赞或取消点赞时先移除原有的记录
+	if err := redisClient.LRem(ctx, userKey, 0, videoId).Err(); err != nil {
+		return constants.RedisDBErr
+	}
+	if actionType == api.FavoriteAction {
+		if err := redisClient.LPush(ctx, userKey, videoId).Err(); err != nil {
+			return constants.RedisDBErr
+		}
+	}
+	redisClient.Expire(ctx, userKey, getUserFavoriteExpireTime())
+
 	//判断redis中是否存在
 	videoKey := videoFavoritePrefix + strconv.FormatInt(videoId, 10)
-	exists, err := redisClient.Exists(context.Background(), videoKey).Result()
+	exists, err := redisClient.Exists(ctx, videoKey).Result()
 	if err != nil {
 		return constants.RedisDBErr
 	}
-	//写入videoKeySet
-	f.videoKeySet[videoKey] = nil
+	observeCache(videoFavoritePrefix, exists == 1)
 	if exists == 0 {
-		favoriteCount, err := dao.GetFavoriteDaoInstance().GetFavoriteCount(videoId)
-		if err != nil && errors.Is(constants.RecordNotExistErr, err) {
-			//放入空缓存
-			err = redisClient.Set(context.Background(), videoKey, emptyCache, getEmptyCacheExpireTime()).Err()
+		//同一视频的并发请求只从数据库加载一次点赞数，且只有第一次写入生效，之后统一在redis中增减
+		_, err, _ = favoriteCountGroup.Do(videoKey, func() (interface{}, error) {
+			favoriteCount, err := dao.GetFavoriteDaoInstance().GetFavoriteCount(videoId)
+			if errors.Is(constants.RecordNotExistErr, err) {
+				//放入空缓存
+				err = redisClient.SetNX(ctx, videoKey, emptyCache, getEmptyCacheExpireTime()).Err()
+			} else if err != nil {
+				return nil, err
+			} else {
+				err = redisClient.SetNX(ctx, videoKey, favoriteCount, getVideoFavoriteExpireTime()).Err()
+			}
 			if err != nil {
-				return constants.RedisDBErr
+				return nil, constants.RedisDBErr
 			}
-		} else {
+			return nil, nil
+		})
+		if err != nil {
 			return err
 		}
-		if actionType == api.FavoriteAction {
-			favoriteCount++
-		} else if actionType == api.UnFavoriteAction {
-			favoriteCount--
-		}
-		err = redisClient.Set(context.Background(), videoKey, strconv.Itoa(int(favoriteCount)), getVideoFavoriteExpireTime()).Err()
+	} else {
+		redisClient.Expire(ctx, videoKey, videoFavoriteExpireTime)
+	}
+	if emptyCache == redisClient.Get(ctx, videoKey).Val() {
+		//直接返回
+		return nil
+	}
+	//记录点赞数有变化的视频，由定时任务写入数据库，多个实例之间共享
+	redisClient.SAdd(ctx, videoFavoriteDirtyKey, videoKey)
+	if actionType == api.FavoriteAction {
+		err = redisClient.Incr(ctx, videoKey).Err()
+	} else if actionType == api.UnFavoriteAction {
+		err = redisClient.Decr(ctx, videoKey).Err()
+	}
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	return nil
+}
+
+// loadUserFavoriteList 若redis中不存在用户的点赞列表，则从数据库中按点赞时间倒序分批加载
+// 先写入一个临时的key，加载完成后RENAMENX为用户的点赞列表，并发加载时只有最先完成的生效，列表中不会有重复的视频
+func (f *favoriteService) loadUserFavoriteList(ctx context.Context, userId int64) error {
+	userKey := userFavoritePrefix + strconv.FormatInt(userId, 10)
+	exists, err := redisClient.Exists(ctx, userKey).Result()
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	observeCache(userFavoritePrefix, exists == 1)
+	if exists == 1 {
+		return nil
+	}
+	loadingKey := userKey + "_loading_" + uuid.New().String()
+	var seq int64
+	loaded := false
+	for {
+		favors, err := dao.GetFavoriteDaoInstance().GetFavoriteList(userId, seq, favoriteLoadBatch)
 		if err != nil {
-			return constants.RedisDBErr
+			redisClient.Del(ctx, loadingKey)
+			return err
 		}
-	} else {
-		redisClient.Expire(context.Background(), videoKey, videoFavoriteExpireTime)
-		if emptyCache == redisClient.Get(context.Background(), videoKey).Val() {
-			//直接返回
-			return nil
+		if len(favors) == 0 {
+			break
 		}
-		if actionType == api.FavoriteAction {
-			err = redisClient.Incr(context.Background(), videoKey).Err()
-		} else if actionType == api.UnFavoriteAction {
-			err = redisClient.Decr(context.Background(), videoKey).Err()
+		videoIds := make([]interface{}, len(favors))
+		for i, favor := range favors {
+			videoIds[i] = favor.VideoID
 		}
-		if err != nil {
+		if err = redisClient.RPush(ctx, loadingKey, videoIds...).Err(); err != nil {
+			redisClient.Del(ctx, loadingKey)
 			return constants.RedisDBErr
 		}
+		loaded = true
+		// 加载中途退出时临时的key也会过期
+		redisClient.Expire(ctx, loadingKey, getUserFavoriteExpireTime())
+		if len(favors) < favoriteLoadBatch {
+			break
+		}
+		seq = int64(favors[len(favors)-1].ID)
 	}
-	userKey := userFavoritePrefix + strconv.FormatInt(userId, 10)
-	exists, err = redisClient.Exists(context.Background(), userKey).Result()
+	if !loaded {
+		// 没有点赞记录
+		return nil
+	}
+	renamed, err := redisClient.RenameNX(ctx, loadingKey, userKey).Result()
 	if err != nil {
+		redisClient.Del(ctx, loadingKey)
 		return constants.RedisDBErr
 	}
-	if exists == 0 {
-		//从数据库中获得点赞列表，放入redis中
-		videos, errdb := dao.GetFavoriteDaoInstance().GetFavoriteList(userId)
-		for errdb != nil {
-			videos, err = dao.GetFavoriteDaoInstance().GetFavoriteList(userId)
-		}
-		for _, video := range videos {
-			redisClient.LPush(context.Background(), userKey, video.VideoID)
-		}
+	if !renamed {
+		// 其他请求已经加载了点赞列表，丢弃本次加载的结果
+		redisClient.Del(ctx, loadingKey)
 	}
-	redisClient.LPush(context.Background(), userKey, videoId)
-	redisClient.Expire(context.Background(), userKey, getUserFavoriteExpireTime())
 	return nil
 }
 
-//FavoriteListInfo service层查找用户点赞过的所有视频
-func (f *favoriteService) FavoriteListInfo(loginUserId, userId int64) (*[]api.Video, error) {
-	_, err := GetUserServiceInstance().getUserByUserId(userId)
+// FavoriteListInfo service层按点赞时间倒序分页查找用户点赞过的视频，返回视频列表、下一页的游标以及是否还有更多
+// 点赞列表缓存在redis的list中，按下标窗口分页
+// 可能返回的错误类型：UserNotExistErr, InvalidCursorErr, RedisDBErr, InnerDataBaseErr
+func (f *favoriteService) FavoriteListInfo(loginUserId, userId int64, cursor string, limit int) (*[]api.Video, string, bool, error) {
+	page, err := pageUtils.Decode(cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
+	_, err = GetUserServiceInstance().getUserByUserId(userId)
 	if errors.Is(constants.UserNotExistErr, err) {
-		return nil, err
+		return nil, "", false, err
+	}
+	if err = f.loadUserFavoriteList(context.Background(), userId); err != nil {
+		return nil, "", false, err
 	}
 	userKey := userFavoritePrefix + strconv.FormatInt(userId, 10)
-	exists, err := redisClient.Exists(context.Background(), userKey).Result()
+	// 多取一个用于判断是否还有下一页
+	videoIds, err := redisClient.LRange(context.Background(), userKey, page.Offset, page.Offset+int64(limit)).Result()
 	if err != nil {
-		return nil, constants.RedisDBErr
+		return nil, "", false, constants.RedisDBErr
 	}
-	if exists == 0 {
-		//从数据库中获得点赞列表，放入redis中
-		videos, errdb := dao.GetFavoriteDaoInstance().GetFavoriteList(userId)
-		for errdb != nil {
-			videos, err = dao.GetFavoriteDaoInstance().GetFavoriteList(userId)
-		}
-		for _, video := range videos {
-			redisClient.LPush(context.Background(), userKey, video.VideoID)
-		}
+	hasMore := len(videoIds) > limit
+	if hasMore {
+		videoIds = videoIds[:limit]
 	}
-	videoIds, err := redisClient.LRange(context.Background(), userKey, 0, -1).Result()
+	videoList, err := getVideoListByID(loginUserId, videoIds)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
-	videoList, err := getVideoListByID(loginUserId, videoIds)
-	return &videoList, err
+	next := ""
+	if hasMore {
+		next = pageUtils.Cursor{Offset: page.Offset + int64(len(videoIds))}.Encode()
+	}
+	return &videoList, next, hasMore, nil
 }
 
 // DeleteDatabaseRegularly 定时删除数据库
 func (f *favoriteService) DeleteDatabaseRegularly() error {
 	err := dao.GetFavoriteDaoInstance().HardDeleteUnFavorite()
-	return err
+	if err != nil {
+		return err
+	}
+	//删除超过保留时间的点赞事件
+	retention := time.Duration(initialization.FavoriteConsumerConf.EventRetention) * 24 * time.Hour
+	return dao.GetFavoriteDaoInstance().DeleteFavoriteEventsBefore(time.Now().Add(-retention))
 }
 
-// WriteToDataBaseRegularly 定时从redis中获取点赞记录写入数据库
+// WriteToDataBaseRegularly 定时将redis中有变化的点赞数写入数据库
 func (f *favoriteService) WriteToDataBaseRegularly() error {
-	for key, _ := range f.videoKeySet {
-		logger.GlobalLogger.Printf("key = %v", key)
-		val, err := redisClient.Get(context.Background(), key).Result()
-		if err != nil {
+	ctx := context.Background()
+	for {
+		key, err := redisClient.SPop(ctx, videoFavoriteDirtyKey).Result()
+		if err == redis.Nil {
+			// 集合为空
+			return nil
+		} else if err != nil {
+			return constants.RedisDBErr
+		}
+		val, err := redisClient.Get(ctx, key).Result()
+		if err == redis.Nil {
+			// 缓存已经过期，数据库中的点赞数由对账任务以点赞记录为准修复
+			continue
+		} else if err != nil {
+			redisClient.SAdd(ctx, videoFavoriteDirtyKey, key)
 			return constants.RedisDBErr
 		}
 		videoId, err := strconv.ParseInt(key[len(videoFavoritePrefix):], 10, 64)
-		favoriteCnt, err := strconv.ParseInt(val, 10, 32)
-		logger.GlobalLogger.Printf("videoId = %v, favoriteCnt = %v", videoId, favoriteCnt)
 		if err != nil {
-			return constants.RedisDBErr
+			continue
 		}
-		err = dao.GetFavoriteDaoInstance().SetFavoriteCount(videoId, int32(favoriteCnt))
+		favoriteCnt, err := strconv.ParseInt(val, 10, 32)
 		if err != nil {
+			// 空缓存
+			continue
+		}
+		logger.GlobalLogger.Debug().Msgf("videoId = %v, favoriteCnt = %v", videoId, favoriteCnt)
+		if err = dao.GetFavoriteDaoInstance().SetFavoriteCount(videoId, int32(favoriteCnt)); err != nil {
+			redisClient.SAdd(ctx, videoFavoriteDirtyKey, key)
 			return constants.InnerDataBaseErr
 		}
 	}
-	//删除videoKeySet
-	f.videoKeySet = make(map[string]interface{}, 0)
-	return nil
 }
@@ -1,14 +1,27 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
+	"github.com/go-redis/redis/v8"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
 
+const (
+	feedSnapshotKey  = "feed_snapshot" // 最近发布的视频ID，zset，score为发布时间，video dao熔断时从中读取视频流
+	feedSnapshotSize = 1000
+)
+
 type feedService struct{}
 
 var (
@@ -18,28 +31,137 @@ var (
 
 func GetFeedServiceInstance() *feedService {
 	initRedis()
-	initKafka()
+	initPublisher()
 	feedOnce.Do(func() {
 		feedServiceInstance = &feedService{}
 	})
 	return feedServiceInstance
 }
 
-//Feed service层获取视频流
+// Feed service层获取视频流，video dao熔断时降级为从缓存中读取最近的视频
 func (f *feedService) Feed(userId int64, latestTime time.Time) (int64, []api.Video, error) {
-	videos, err := dao.GetVideoDaoInstance().GetFeedList(latestTime)
-	logger.GlobalLogger.Printf("get Videos From FeedList")
+	videos, err := f.getFeedList(latestTime)
+	logger.GlobalLogger.Debug().Msg("get Videos From FeedList")
+	degraded := errors.Is(rpcUtils.ErrBreakerOpen, err)
+	if degraded {
+		videos, err = f.getFeedListFromSnapshot(latestTime)
+	}
 	if err != nil {
-		logger.GlobalLogger.Printf("dao.NewVideoDaoInstance().GetLatest error: %s", err)
+		logger.GlobalLogger.Error().Err(err).Msg("dao.NewVideoDaoInstance().GetLatest")
 		return -1, nil, err
 	}
 	if len(videos) == 0 {
-		logger.GlobalLogger.Printf("没有早于当前时间的视频")
+		logger.GlobalLogger.Debug().Msg("没有早于当前时间的视频")
 		return -1, nil, constants.NoVideoErr
 	}
+	nextTime := videos[len(videos)-1].CreatedAt.UnixMilli()
+	if degraded {
+		return nextTime, getDegradedVideoList(videos), nil
+	}
+	if initialization.HotConf.FeedRerank {
+		f.rerankByHotScore(videos)
+	}
 	videoList, err := getVideoListByModel(userId, videos)
 	if err != nil {
 		return -1, nil, err
 	}
-	return videos[len(videos)-1].CreatedAt.UnixMilli(), videoList, nil
+	return nextTime, videoList, nil
+}
+
+// getFeedList 从数据库读取视频流，与gRPC调用video dao共用同一个熔断器，熔断期间返回ErrBreakerOpen
+func (f *feedService) getFeedList(latestTime time.Time) ([]*model.Video, error) {
+	done, err := rpcUtils.Breaker(initialization.RpcSDConf.VideoServiceHost + initialization.RpcSDConf.VideoServicePort).Allow()
+	if err != nil {
+		return nil, rpcUtils.ErrBreakerOpen
+	}
+	videos, err := dao.GetVideoDaoInstance().GetFeedList(latestTime)
+	done(err == nil || errors.Is(constants.RecordNotExistErr, err))
+	if err == nil {
+		go f.saveFeedSnapshot(videos)
+	}
+	return videos, err
+}
+
+// saveFeedSnapshot 记录最近读取到的视频，并写入视频缓存，用于熔断时降级
+func (f *feedService) saveFeedSnapshot(videos []*model.Video) {
+	if len(videos) == 0 {
+		return
+	}
+	ctx := context.Background()
+	members := make([]*redis.Z, len(videos))
+	for i, video := range videos {
+		members[i] = &redis.Z{Score: float64(video.CreatedAt.UnixMilli()), Member: video.VideoID}
+		if err := videoInfoCache.Set(ctx, strconv.FormatInt(video.VideoID, 10), video); err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("fail to cache video %v", video.VideoID)
+		}
+	}
+	pipe := redisClient.Pipeline()
+	pipe.ZAdd(ctx, feedSnapshotKey, members...)
+	pipe.ZRemRangeByRank(ctx, feedSnapshotKey, 0, -feedSnapshotSize-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.GlobalLogger.Error().Err(err).Msg("fail to save feed snapshot")
+	}
+}
+
+// getFeedListFromSnapshot 熔断时从最近的视频中读取早于latestTime的一页，只返回仍在缓存中的视频
+func (f *feedService) getFeedListFromSnapshot(latestTime time.Time) ([]*model.Video, error) {
+	ctx := context.Background()
+	ids, err := redisClient.ZRevRangeByScore(ctx, feedSnapshotKey, &redis.ZRangeBy{
+		Max:   "(" + strconv.FormatInt(latestTime.UnixMilli(), 10),
+		Min:   "-inf",
+		Count: int64(initialization.FeedListLength),
+	}).Result()
+	if err != nil {
+		logger.GlobalLogger.Error().Err(err).Msg("fail to read feed snapshot")
+		return nil, rpcUtils.ErrBreakerOpen
+	}
+	videos := make([]*model.Video, 0, len(ids))
+	for _, id := range ids {
+		video, err := videoInfoCache.Get(ctx, id, func(ctx context.Context) (*model.Video, error) {
+			return nil, rpcUtils.ErrBreakerOpen
+		})
+		if err == nil {
+			videos = append(videos, video)
+		}
+	}
+	return videos, nil
+}
+
+// getDegradedVideoList 降级时组装视频流，不查询是否点赞，作者信息只在能读取到时填充
+func getDegradedVideoList(videos []*model.Video) []api.Video {
+	videoList := make([]api.Video, len(videos))
+	for i, v := range videos {
+		author := api.User{Id: v.UserID}
+		if userInfo, err := GetUserServiceInstance().getUserByUserId(v.UserID); err == nil {
+			author.Name = userInfo.UserName
+			author.FollowCount = userInfo.FollowCount
+			author.FollowerCount = userInfo.FollowerCount
+		}
+		videoList[i] = api.Video{
+			Id:            v.VideoID,
+			Author:        author,
+			PlayUrl:       v.PlayURL,
+			CoverUrl:      v.CoverURL,
+			FavoriteCount: int64(v.FavoriteCount),
+			CommentCount:  int64(v.CommentCount),
+			PlayCount:     v.PlayCount,
+		}
+	}
+	return videoList
+}
+
+// rerankByHotScore 将一页视频按照热度重新排序，热度相同时保持按时间倒序
+func (f *feedService) rerankByHotScore(videos []*model.Video) {
+	videoIds := make([]int64, len(videos))
+	for i, video := range videos {
+		videoIds[i] = video.VideoID
+	}
+	scores, err := GetHotServiceInstance().HotScores(videoIds)
+	if err != nil {
+		logger.GlobalLogger.Error().Err(err).Msg("fail to get hot scores")
+		return
+	}
+	sort.SliceStable(videos, func(i, j int) bool {
+		return scores[videos[i].VideoID] > scores[videos[j].VideoID]
+	})
 }
@@ -1 +1,119 @@
 package service
+
+import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
+	"sync"
+)
+
+// followService 与关注相关的操作集合
+type followService struct{}
+
+var (
+	followServiceInstance *followService
+	followOnce            sync.Once
+)
+
+// GetFollowServiceInstance 获取一个followService的实例
+func GetFollowServiceInstance() *followService {
+	initRedis()
+	followOnce.Do(func() {
+		followServiceInstance = &followService{}
+	})
+	return followServiceInstance
+}
+
+// RelationActionInfo service层处理用户userId关注或者取消关注toUserId
+// 可能返回的错误类型：UnKnownActionTypeErr, InputFormatCheckErr, UserNotExistErr, RecordNotExistErr, RecordNotMatchErr, InnerDataBaseErr
+func (f *followService) RelationActionInfo(userId, toUserId int64, actionType int32) error {
+	if actionType != api.FollowAction && actionType != api.UnFollowAction {
+		return constants.UnKnownActionTypeErr
+	}
+	if userId == toUserId {
+		return constants.InputFormatCheckErr
+	}
+	if _, err := GetUserServiceInstance().getUserByUserId(toUserId); err != nil {
+		return err
+	}
+	if actionType == api.FollowAction {
+		return dao.GetFollowDaoInstance().FollowAction(userId, toUserId)
+	}
+	return dao.GetFollowDaoInstance().UnfollowAction(userId, toUserId)
+}
+
+// FollowListInfo service层按关注时间倒序分页获取userId关注的用户，返回用户列表、下一页的游标以及是否还有更多
+// 可能返回的错误类型：UserNotExistErr, InvalidCursorErr, InnerDataBaseErr
+func (f *followService) FollowListInfo(loginUserId, userId int64, cursor string, limit int) ([]api.User, string, bool, error) {
+	return f.listUsers(loginUserId, userId, cursor, limit, dao.GetFollowDaoInstance().GetFollowList,
+		func(follow *model.Follow) int64 { return follow.ToUserID })
+}
+
+// FollowerListInfo service层按关注时间倒序分页获取关注了userId的用户
+// 可能返回的错误类型：UserNotExistErr, InvalidCursorErr, InnerDataBaseErr
+func (f *followService) FollowerListInfo(loginUserId, userId int64, cursor string, limit int) ([]api.User, string, bool, error) {
+	return f.listUsers(loginUserId, userId, cursor, limit, dao.GetFollowDaoInstance().GetFollowerList,
+		func(follow *model.Follow) int64 { return follow.FromUserID })
+}
+
+// FriendListInfo service层按关注时间倒序分页获取与userId互相关注的用户
+// 可能返回的错误类型：UserNotExistErr, InvalidCursorErr, InnerDataBaseErr
+func (f *followService) FriendListInfo(loginUserId, userId int64, cursor string, limit int) ([]api.User, string, bool, error) {
+	return f.listUsers(loginUserId, userId, cursor, limit, dao.GetFollowDaoInstance().GetFriendList,
+		func(follow *model.Follow) int64 { return follow.ToUserID })
+}
+
+// listUsers 按关注记录的主键倒序进行keyset分页，pick从关注记录中取出需要返回的用户
+func (f *followService) listUsers(loginUserId, userId int64, cursor string, limit int,
+	load func(userId, seq int64, limit int) ([]*model.Follow, error), pick func(*model.Follow) int64) ([]api.User, string, bool, error) {
+	page, err := pageUtils.Decode(cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if _, err = GetUserServiceInstance().getUserByUserId(userId); err != nil {
+		return nil, "", false, err
+	}
+	// 多取一个用于判断是否还有下一页
+	follows, err := load(userId, page.Seq, limit+1)
+	if err != nil {
+		return nil, "", false, err
+	}
+	hasMore := len(follows) > limit
+	if hasMore {
+		follows = follows[:limit]
+	}
+	userList := make([]api.User, len(follows))
+	for i, follow := range follows {
+		if userList[i], err = getApiUser(loginUserId, pick(follow)); err != nil {
+			return nil, "", false, err
+		}
+	}
+	next := ""
+	if hasMore {
+		next = pageUtils.Cursor{Seq: int64(follows[len(follows)-1].ID)}.Encode()
+	}
+	return userList, next, hasMore, nil
+}
+
+// getApiUser 通过缓存获取用户userId，构造返回给登录用户loginUserId的api.User
+func getApiUser(loginUserId, userId int64) (api.User, error) {
+	userInfo, err := GetUserServiceInstance().getUserByUserId(userId)
+	if err != nil {
+		return api.User{}, err
+	}
+	isFollow := false
+	if loginUserId != 0 && loginUserId != userId {
+		if isFollow, err = dao.GetFollowDaoInstance().CheckFollow(loginUserId, userId); err != nil {
+			return api.User{}, err
+		}
+	}
+	return api.User{
+		Id:            userInfo.UserID,
+		Name:          userInfo.UserName,
+		FollowCount:   userInfo.FollowCount,
+		FollowerCount: userInfo.FollowerCount,
+		IsFollow:      isFollow,
+	}, nil
+}
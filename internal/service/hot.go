@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/go-redis/redis/v8"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// hotService 热门视频榜单相关的操作集合
+// 视频的热度由点赞、评论与播放事件计算，并按照Hacker News的重力公式随时间衰减
+type hotService struct{}
+
+var (
+	hotServiceInstance *hotService
+	hotOnce            sync.Once
+)
+
+const (
+	hotRankKey       = "video_hot_rank" // 热榜，zset，member为videoId，score为热度
+	hotVideoPrefix   = "video_hot_"     // 视频的互动计数，hash
+	hotFieldFavorite = "favorite"
+	hotFieldComment  = "comment"
+	hotFieldPlay     = "play"
+	hotFieldCreated  = "created_at"
+	hotRecomputeStep = 200
+	hotRetryInterval = 10 * time.Second
+)
+
+// hotTopics 热榜需要消费的topic以及对应的计数字段
+// 点赞只计入已写入数据库、状态发生了变化的点赞事件，评论事件在写入评论的事务中发送，删除评论时减少计数；
+// 播放事件需要先按用户去重，因此由playService统计后再计入热度
+var hotTopics = map[string]string{
+	constants.KafkaTopicPrefix + "favorite_applied": hotFieldFavorite,
+	constants.KafkaTopicPrefix + "comment":          hotFieldComment,
+}
+
+// GetHotServiceInstance 获取一个hotService的实例
+func GetHotServiceInstance() *hotService {
+	initRedis()
+	hotOnce.Do(func() {
+		hotServiceInstance = &hotService{}
+	})
+	return hotServiceInstance
+}
+
+// startConsumers 以hot消费组启动互动消息的消费者，多个实例分摊消息，每条消息只计入一次热度
+func (h *hotService) startConsumers() {
+	topics := make([]string, 0, len(hotTopics))
+	for topic := range hotTopics {
+		topics = append(topics, topic)
+	}
+	go consumeGroup("hot", topics, func(ctx context.Context, msg *mqUtils.Message) {
+		h.handleEngagement(ctx, hotTopics[msg.Topic], msg)
+	})
+}
+
+// handleEngagement 处理一条互动消息，消息的格式见parseActionMessage
+func (h *hotService) handleEngagement(ctx context.Context, field string, msg *mqUtils.Message) {
+	action, ok := parseActionMessage(msg)
+	if !ok {
+		logger.Ctx(ctx).Warn().Msgf("unknown engagement message, value = %v", string(msg.Value))
+		return
+	}
+	if !firstDelivery(ctx, "hot", action.EventId) {
+		return
+	}
+	if err := h.addEngagement(ctx, action.To, field, action.Delta); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to add engagement of video %v", action.To)
+	}
+}
+
+// addEngagement 增加视频的互动计数并更新其热度
+func (h *hotService) addEngagement(ctx context.Context, videoId int64, field string, delta int64) error {
+	key := hotVideoPrefix + strconv.FormatInt(videoId, 10)
+	exists, err := redisClient.HExists(ctx, key, hotFieldCreated).Result()
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	if !exists {
+		video, err := getVideoByVideoId(videoId)
+		if err != nil {
+			return err
+		}
+		redisClient.HSetNX(ctx, key, hotFieldCreated, video.CreatedAt.Unix())
+	}
+	if err = redisClient.HIncrBy(ctx, key, field, delta).Err(); err != nil {
+		return constants.RedisDBErr
+	}
+	redisClient.Expire(ctx, key, h.maxAge())
+	return h.refreshScore(ctx, videoId, time.Now())
+}
+
+// refreshScore 根据视频的互动计数重新计算热度，超过最大时长的视频将被移出热榜
+func (h *hotService) refreshScore(ctx context.Context, videoId int64, now time.Time) error {
+	videoIdStr := strconv.FormatInt(videoId, 10)
+	counts, err := redisClient.HGetAll(ctx, hotVideoPrefix+videoIdStr).Result()
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	createdAt, err := strconv.ParseInt(counts[hotFieldCreated], 10, 64)
+	if err != nil || now.Sub(time.Unix(createdAt, 0)) > h.maxAge() {
+		redisClient.ZRem(ctx, hotRankKey, videoIdStr)
+		return nil
+	}
+	score := hotScore(counts, now.Sub(time.Unix(createdAt, 0)))
+	return redisClient.ZAdd(ctx, hotRankKey, &redis.Z{Score: score, Member: videoIdStr}).Err()
+}
+
+// hotScore 计算热度：score = 互动分 / (发布小时数 + 2)^Gravity
+func hotScore(counts map[string]string, age time.Duration) float64 {
+	conf := initialization.HotConf
+	weights := map[string]float64{
+		hotFieldFavorite: conf.FavoriteWeight,
+		hotFieldComment:  conf.CommentWeight,
+		hotFieldPlay:     conf.PlayWeight,
+	}
+	var points float64
+	for field, weight := range weights {
+		cnt, _ := strconv.ParseInt(counts[field], 10, 64)
+		points += weight * float64(cnt)
+	}
+	if points <= 0 {
+		return 0
+	}
+	return points / math.Pow(age.Hours()+2, conf.Gravity)
+}
+
+func (h *hotService) maxAge() time.Duration {
+	return time.Duration(initialization.HotConf.MaxAgeHours) * time.Hour
+}
+
+// RecomputeHotRankRegularly 定时重新计算热榜中所有视频的热度
+func (h *hotService) RecomputeHotRankRegularly() error {
+	ctx := context.Background()
+	now := time.Now()
+	var cursor uint64
+	for {
+		members, next, err := redisClient.ZScan(ctx, hotRankKey, cursor, "", hotRecomputeStep).Result()
+		if err != nil {
+			return constants.RedisDBErr
+		}
+		// ZScan返回的结果为member,score交替排列
+		for i := 0; i < len(members); i += 2 {
+			videoId, err := strconv.ParseInt(members[i], 10, 64)
+			if err != nil {
+				redisClient.ZRem(ctx, hotRankKey, members[i])
+				continue
+			}
+			if err = h.refreshScore(ctx, videoId, now); err != nil {
+				return err
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// HotScores 获取一组视频的热度，不在热榜中的视频热度为0
+func (h *hotService) HotScores(videoIds []int64) (map[int64]float64, error) {
+	scores := make(map[int64]float64, len(videoIds))
+	if len(videoIds) == 0 {
+		return scores, nil
+	}
+	members := make([]string, len(videoIds))
+	for i, videoId := range videoIds {
+		members[i] = strconv.FormatInt(videoId, 10)
+	}
+	results, err := redisClient.ZMScore(context.Background(), hotRankKey, members...).Result()
+	if err != nil {
+		return nil, constants.RedisDBErr
+	}
+	for i, videoId := range videoIds {
+		scores[videoId] = results[i]
+	}
+	return scores, nil
+}
+
+// HotFeed service层分页获取热门视频，返回视频列表、下一页的offset以及是否还有更多视频
+func (h *hotService) HotFeed(userId, offset, count int64) ([]api.Video, int64, bool, error) {
+	ctx := context.Background()
+	// 多取一个用于判断是否还有下一页
+	videoIdStrs, err := redisClient.ZRevRange(ctx, hotRankKey, offset, offset+count).Result()
+	if err != nil {
+		return nil, offset, false, constants.RedisDBErr
+	}
+	if len(videoIdStrs) == 0 {
+		return nil, offset, false, constants.NoVideoErr
+	}
+	hasMore := int64(len(videoIdStrs)) > count
+	if hasMore {
+		videoIdStrs = videoIdStrs[:count]
+	}
+	videos := make([]*model.Video, 0, len(videoIdStrs))
+	var removed int64
+	for _, videoIdStr := range videoIdStrs {
+		videoId, _ := strconv.ParseInt(videoIdStr, 10, 64)
+		// 通过视频缓存读取，热榜中的视频大多已在缓存中，不会每个视频都查询一次数据库
+		video, err := getVideoByVideoId(videoId)
+		if errors.Is(constants.RecordNotExistErr, err) {
+			// 视频已被删除，移出热榜，之后的视频排名前移，下一页的offset相应减小
+			if redisClient.ZRem(ctx, hotRankKey, videoIdStr).Val() > 0 {
+				removed++
+			}
+			continue
+		}
+		if err != nil {
+			return nil, offset, false, err
+		}
+		videos = append(videos, video)
+	}
+	videoList, err := getVideoListByModel(userId, videos)
+	if err != nil {
+		return nil, offset, false, err
+	}
+	return videoList, offset + int64(len(videoIdStrs)) - removed, hasMore, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"strconv"
+	"time"
+)
+
+// startInvalidationConsumer 广播消费实体变更事件，每个实例都会收到所有的事件
+// 每个实例的进程内缓存需要各自删除，因此不使用消费组；重启期间错过的事件由缓存的有效期兜底
+func startInvalidationConsumer() {
+	go func() {
+		for {
+			err := consumeTopic(constants.KafkaTopicPrefix+"entity_changed", handleEntityChanged)
+			if err == nil {
+				break
+			}
+			time.Sleep(hotRetryInterval)
+		}
+	}()
+}
+
+// handleEntityChanged 处理一条实体变更事件，延时双删受影响的缓存
+// 删除是幂等的，重复的事件不需要去重；丢失的事件由缓存的有效期兜底
+// video_favorite_的点赞计数是尚未写回数据库的计数本身而不是数据库的缓存，删除会丢失未写回的点赞，因此不在此删除，由对账任务修复
+func handleEntityChanged(ctx context.Context, msg *mqUtils.Message) {
+	event, err := dao.DecodeEntityChangedEvent(msg)
+	if err != nil {
+		logger.Ctx(ctx).Warn().Msgf("unknown entity changed message, value = %v", string(msg.Value))
+		return
+	}
+	delay := time.Duration(initialization.CacheConf.DoubleDeleteDelay) * time.Millisecond
+	entityId := strconv.FormatInt(event.EntityId, 10)
+	switch event.Entity {
+	case api.EntityUser:
+		err = userInfoCache.DeleteDelayed(ctx, entityId, delay)
+		if event.UserName != "" {
+			if loginErr := userLoginCache.DeleteDelayed(ctx, event.UserName, delay); loginErr != nil {
+				err = loginErr
+			}
+		}
+	case api.EntityVideo:
+		err = videoInfoCache.DeleteDelayed(ctx, entityId, delay)
+		if event.OwnerId != 0 {
+			deleteKeyDelayed(userPublishPrefix+strconv.FormatInt(event.OwnerId, 10), delay)
+		}
+	default:
+		return
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to invalidate cache of %v %v", event.Entity, event.EntityId)
+	}
+}
+
+// deleteKeyDelayed 延时双删redis中不经过cacheUtils.Cache的缓存
+func deleteKeyDelayed(key string, delay time.Duration) {
+	if err := redisClient.Del(context.Background(), key).Err(); err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to delete cache %v", key)
+	}
+	time.AfterFunc(delay, func() {
+		if err := redisClient.Del(context.Background(), key).Err(); err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("fail to delete cache %v again", key)
+		}
+	})
+}
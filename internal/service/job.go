@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cronUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"sync"
+)
+
+// jobService 分布式定时任务的注册与管理
+// 所有定时任务在启动时统一注册，每个任务同一时刻只会在一个实例上运行，运行记录保存在redis中
+type jobService struct {
+	registry *cronUtils.JobRegistry
+}
+
+var (
+	jobServiceInstance *jobService
+	jobOnce            sync.Once
+	registerJobsOnce   sync.Once
+	flushHooksOnce     sync.Once
+)
+
+// GetJobServiceInstance 获取一个jobService的实例
+func GetJobServiceInstance() *jobService {
+	initRedis()
+	jobOnce.Do(func() {
+		jobServiceInstance = &jobService{registry: cronUtils.NewJobRegistry(redisClient)}
+	})
+	return jobServiceInstance
+}
+
+// registerJobs 注册Service层的所有定时任务
+func (j *jobService) registerJobs() {
+	registerJobsOnce.Do(func() {
+		favorite := GetFavoriteServiceInstance()
+		j.register("favorite_clean", initialization.JobConf.FavoriteCleanSpec, favorite.DeleteDatabaseRegularly)
+		j.register("favorite_flush", initialization.JobConf.FavoriteFlushSpec, favorite.WriteToDataBaseRegularly)
+		j.register("favorite_reconcile", initialization.ReconcileConf.Spec, func() error {
+			report, err := favorite.ReconcileFavoriteCounts(initialization.ReconcileConf.Repair)
+			if err != nil {
+				return err
+			}
+			logger.GlobalLogger.Info().Msgf("favorite reconcile report = %+v", *report)
+			return nil
+		})
+		j.register("hot_recompute", initialization.HotConf.RecomputeSpec, GetHotServiceInstance().RecomputeHotRankRegularly)
+		j.register("play_flush", initialization.PlayConf.FlushSpec, GetPlayServiceInstance().WritePlayStatToDataBaseRegularly)
+		j.register("creator_aggregate", initialization.CreatorConf.AggregateSpec, GetCreatorServiceInstance().AggregateDailyStatRegularly)
+		j.register("bloom_load", initialization.CacheConf.BloomLoadSpec, LoadBloomFilters)
+		// 布隆过滤器尚未加载时立即加载一次，加载完成之前不拦截
+		if !bloomFiltersReady() {
+			if err := j.TriggerJob("bloom_load"); err != nil {
+				logger.GlobalLogger.Error().Err(err).Msg("fail to trigger bloom_load")
+			}
+		}
+	})
+}
+
+// registerFlushHooks 关闭时在停止接收请求之后，将redis中尚未写入的点赞数与播放统计写入数据库
+func (j *jobService) registerFlushHooks() {
+	flushHooksOnce.Do(func() {
+		lifecycle.OnShutdown(lifecycle.PhaseFlush, "favorite_flush", func(ctx context.Context) error {
+			return GetFavoriteServiceInstance().WriteToDataBaseRegularly()
+		})
+		lifecycle.OnShutdown(lifecycle.PhaseFlush, "play_flush", func(ctx context.Context) error {
+			return GetPlayServiceInstance().WritePlayStatToDataBaseRegularly()
+		})
+	})
+}
+
+func (j *jobService) register(name, spec string, run func() error) {
+	if err := j.registry.Register(name, spec, run); err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to register job %v", name)
+	}
+}
+
+// ListJobs 获取所有注册过的定时任务以及当前的leader
+func (j *jobService) ListJobs() ([]cronUtils.JobInfo, string, error) {
+	return j.registry.ListJobs()
+}
+
+// JobHistory 获取定时任务最近limit次的运行记录
+func (j *jobService) JobHistory(name string, limit int) ([]cronUtils.JobRun, error) {
+	return j.registry.JobHistory(name, limit)
+}
+
+// TriggerJob 请求立即运行一次定时任务
+// 可能返回的错误类型：RecordNotExistErr, RedisDBErr
+func (j *jobService) TriggerJob(name string) error {
+	return j.registry.TriggerJob(name)
+}
@@ -1,18 +1,228 @@
 package service
 
 import (
-	"github.com/Shopify/sarama"
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+)
+
+const (
+	eventSeenPrefix     = "event_seen_" // 消费者已处理过的发件箱事件
+	eventSeenExpireTime = 24 * time.Hour
 )
 
 var (
-	kafkaServer sarama.SyncProducer
-	kafkaOnce   sync.Once
+	publisher      mqUtils.Publisher
+	publisherOnce  sync.Once
+	subscriber     mqUtils.Subscriber
+	subscriberOnce sync.Once
 )
 
-func initKafka() {
-	kafkaOnce.Do(func() {
-		kafkaServer = initialization.GetKafkaServer()
+func initPublisher() {
+	publisherOnce.Do(func() {
+		publisher = mqUtils.GetPublisher()
+	})
+}
+
+func initSubscriber() {
+	subscriberOnce.Do(func() {
+		subscriber = mqUtils.GetSubscriber()
+	})
+}
+
+// ServiceInitialization 初始化Service层的后台任务，包括以消费组消费互动消息维护点赞数与热榜、聚合播放统计、创作者每日统计、写入注册用户的登录缓存、将发布的视频加入布隆过滤器、按实体变更事件删除缓存、并注册所有的定时任务以及关闭时写入计数的hook
+func ServiceInitialization() {
+	initRedis()
+	initPublisher()
+	initSubscriber()
+	GetFavoriteServiceInstance().startConsumers()
+	GetHotServiceInstance().startConsumers()
+	GetPlayServiceInstance().startConsumers()
+	GetCreatorServiceInstance().startConsumers()
+	GetUserServiceInstance().startConsumers()
+	GetVideoServiceInstance().startConsumers()
+	startInvalidationConsumer()
+	GetJobServiceInstance().registerJobs()
+	GetJobServiceInstance().registerFlushHooks()
+}
+
+// consumeGroup 加入feature对应的消费组消费topics，并交由handler处理，每条消息的处理记录为发送者链路中的一个span
+// 同一消费组的多个service实例分摊分区，每条消息只由一个实例处理；offset提交到消息队列，重启期间发送的消息在重新加入后继续处理
+// 阻塞直到消息队列关闭，消费组出错时由Subscriber重新加入
+func consumeGroup(feature string, topics []string, handler func(ctx context.Context, msg *mqUtils.Message)) {
+	groupId := initialization.MQConf.GroupPrefix + "_" + feature
+	err := subscriber.SubscribeGroup(context.Background(), groupId, topics, &groupHandler{handler: handler})
+	if err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("consumer group %v stopped", groupId)
+	}
+}
+
+// groupHandler 按顺序处理分配到的分区中的消息，每条消息处理完成后标记它的offset
+type groupHandler struct {
+	handler func(ctx context.Context, msg *mqUtils.Message)
+}
+
+// ConsumeClaim 在分区的消息关闭或者再平衡时返回，未处理的消息由下一次分配到该分区的消费者处理
+func (h *groupHandler) ConsumeClaim(session mqUtils.GroupSession, claim mqUtils.GroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			handleTraced(session.Context(), msg, h.handler)
+			if session.Context().Err() != nil {
+				// 处理时发生了再平衡，消息可能没有处理完成，不标记offset
+				return nil
+			}
+			session.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1)
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// consumeTopic 从最新的消息开始广播消费topic，每个实例都会收到全部消息，只用于删除本实例可见的缓存等必须由每个实例处理的消息
+// 已处理的位置不会保存，实例重启期间发送的消息不会被处理
+func consumeTopic(topic string, handler func(ctx context.Context, msg *mqUtils.Message)) error {
+	err := subscriber.Subscribe(topic, func(msg *mqUtils.Message) {
+		handleTraced(context.Background(), msg, handler)
 	})
+	if err != nil {
+		return constants.KafkaClientErr
+	}
+	return nil
+}
+
+// handleTraced 在发送者链路中的一个span中处理消息，handler收到的ctx携带该span，消费组再平衡时结束
+func handleTraced(ctx context.Context, msg *mqUtils.Message, handler func(ctx context.Context, msg *mqUtils.Message)) {
+	ctx, span := tracing.StartConsumerSpan(ctx, msg.Topic, msg.Headers)
+	defer span.End()
+	handler(ctx, msg)
+}
+
+// actionMessage 一条解析后的互动消息
+type actionMessage struct {
+	EventId string    // 发件箱消息的事件ID，旧版本的消息为空
+	From    int64     // 行为的发起者
+	To      int64     // 行为的对象
+	Delta   int64     // 计数的变化量
+	Time    time.Time // 行为发生的时间
+}
+
+// parseActionMessage 解析一条互动消息
+// 点赞、评论与关注消息分别使用dao.DecodeFavoriteEvent、dao.DecodeCommentEvent与dao.DecodeFollowEvent解析；
+// 其余消息的Key为行为，Value为"id:id"，Key以Un开头表示撤销
+func parseActionMessage(msg *mqUtils.Message) (*actionMessage, bool) {
+	if msg.Topic == constants.KafkaTopicPrefix+"follow" {
+		event, err := dao.DecodeFollowEvent(msg)
+		if err != nil {
+			return nil, false
+		}
+		var delta int64 = 1
+		if event.Action == api.UnFollowAction {
+			delta = -1
+		}
+		return &actionMessage{
+			EventId: event.EventId,
+			From:    event.UserId,
+			To:      event.ToUserId,
+			Delta:   delta,
+			Time:    time.UnixMilli(event.Timestamp),
+		}, true
+	}
+	if msg.Topic == constants.KafkaTopicPrefix+"comment" {
+		event, err := dao.DecodeCommentEvent(msg)
+		if err != nil {
+			return nil, false
+		}
+		var delta int64 = 1
+		if event.Action == api.CommentDeleteAction {
+			delta = -1
+		}
+		return &actionMessage{
+			EventId: event.EventId,
+			From:    event.UserId,
+			To:      event.VideoId,
+			Delta:   delta,
+			Time:    time.UnixMilli(event.Timestamp),
+		}, true
+	}
+	if msg.Topic == constants.KafkaTopicPrefix+"favorite_applied" {
+		event, err := dao.DecodeFavoriteEvent(msg)
+		if err != nil {
+			return nil, false
+		}
+		var delta int64 = 1
+		if event.Action == api.UnFavoriteAction {
+			delta = -1
+		}
+		return &actionMessage{
+			EventId: event.EventId,
+			From:    event.UserId,
+			To:      event.VideoId,
+			Delta:   delta,
+			Time:    time.UnixMilli(event.Timestamp),
+		}, true
+	}
+	value := string(msg.Value)
+	idx := strings.Index(value, ":")
+	if idx < 0 {
+		return nil, false
+	}
+	from, err := strconv.ParseInt(value[:idx], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	to, err := strconv.ParseInt(value[idx+1:], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	var delta int64 = 1
+	if strings.HasPrefix(string(msg.Key), "Un") {
+		delta = -1
+	}
+	return &actionMessage{
+		EventId: outboxEventId(msg),
+		From:    from,
+		To:      to,
+		Delta:   delta,
+		Time:    messageTime(msg),
+	}, true
+}
+
+// outboxEventId 获取发件箱消息在Header中携带的事件ID，不是由发件箱发送的消息返回空串
+func outboxEventId(msg *mqUtils.Message) string {
+	return msg.Header("event_id")
+}
+
+// firstDelivery 判断consumer是否第一次处理该事件
+// 发件箱的relay在发送后、标记为已发送前崩溃时会重复发送消息，非幂等的计数需要据此去重；没有事件ID的消息总是处理
+func firstDelivery(ctx context.Context, consumer, eventId string) bool {
+	if eventId == "" {
+		return true
+	}
+	first, err := redisClient.SetNX(ctx, eventSeenPrefix+consumer+"_"+eventId, 1, eventSeenExpireTime).Result()
+	if err != nil {
+		// redis不可用时宁可重复计数也不丢弃消息
+		return true
+	}
+	return first
+}
+
+// messageTime 获取消息产生的时间，旧版本协议的消息没有时间戳，使用当前时间
+func messageTime(msg *mqUtils.Message) time.Time {
+	if msg.Timestamp.IsZero() || msg.Timestamp.Unix() <= 0 {
+		return time.Now()
+	}
+	return msg.Timestamp
 }
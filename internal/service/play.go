@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cacheUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/tracing"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// playService 与视频播放统计相关的操作集合
+// 播放事件先写入消息队列削峰，消费时按用户去重后在redis中聚合，再定时写入数据库
+type playService struct{}
+
+var (
+	playServiceInstance *playService
+	playOnce            sync.Once
+)
+
+const (
+	playStatPrefix      = "video_play_stat_"   // 视频的播放统计，hash
+	playDedupPrefix     = "video_play_dedup_"  // 用户在去重窗口内播放过该视频
+	watchDedupPrefix    = "video_watch_dedup_" // 用户在去重窗口内上报过该视频该类型事件的观看时长
+	playDirtyKey        = "video_play_dirty"   // 播放统计有变化、尚未写入数据库的视频，set
+	playFieldCount      = "play_count"
+	playFieldWatchTime  = "watch_time"
+	playFieldWatchCount = "watch_count"
+	playStatExpireTime  = 24 * time.Hour
+	playSendRetryTimes  = 3
+
+	// countPlayScript 用户在去重窗口内第一次上报时设置去重标记并累加播放统计，返回1；重复上报时返回0
+	// 去重标记与计数在同一个脚本中完成，累加失败时不会留下去重标记而丢失这次播放
+	countPlayScript = `
+if not redis.call("set", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+    return 0
+end
+for i = 4, #ARGV, 2 do
+    redis.call("hincrby", KEYS[2], ARGV[i], ARGV[i + 1])
+end
+redis.call("sadd", KEYS[3], ARGV[3])
+return 1`
+)
+
+var countPlay = redis.NewScript(countPlayScript)
+
+// 获取播放统计的持续时间
+func getPlayStatExpireTime() time.Duration {
+	return cacheUtils.JitterTTL(playStatExpireTime, 12*time.Hour)
+}
+
+func getPlayDedupWindow() time.Duration {
+	return time.Duration(initialization.PlayConf.DedupWindow) * time.Minute
+}
+
+// GetPlayServiceInstance 获取一个playService的实例
+func GetPlayServiceInstance() *playService {
+	initRedis()
+	initPublisher()
+	playOnce.Do(func() {
+		playServiceInstance = &playService{}
+	})
+	return playServiceInstance
+}
+
+// PlayInfo service层接收一条播放事件，校验后异步写入消息队列
+// 可能返回的错误类型：UnKnownActionTypeErr, InputFormatCheckErr, RecordNotExistErr
+func (p *playService) PlayInfo(ctx context.Context, userId, videoId int64, eventType int32, watchDuration int64) error {
+	switch eventType {
+	case api.PlayStartEvent:
+		watchDuration = 0
+	case api.PlayWatchEvent, api.PlayCompleteEvent:
+		if watchDuration < 0 || watchDuration > initialization.PlayConf.MaxWatchMillis {
+			return constants.InputFormatCheckErr
+		}
+	default:
+		return constants.UnKnownActionTypeErr
+	}
+	if _, err := dao.GetVideoDaoInstance().GetVideoByVideoIdInfo(videoId); err != nil {
+		return err
+	}
+	go p.writeToKafkaAsyn(tracing.Detach(ctx), &api.PlayEvent{
+		UserId:        userId,
+		VideoId:       videoId,
+		EventType:     eventType,
+		WatchDuration: watchDuration,
+		Timestamp:     time.Now().UnixMilli(),
+	})
+	return nil
+}
+
+// 异步写入消息队列，以videoId作为Key使同一视频的事件落在同一分区，ctx中的链路写入消息的Header
+func (p *playService) writeToKafkaAsyn(ctx context.Context, event *api.PlayEvent) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	topic := constants.KafkaTopicPrefix + "play"
+	ctx, span := tracing.StartProducerSpan(ctx, topic)
+	defer span.End()
+	for i := 0; i < playSendRetryTimes; i++ {
+		err = publisher.Publish(ctx, &mqUtils.Message{
+			Topic:   topic,
+			Key:     []byte(strconv.FormatInt(event.VideoId, 10)),
+			Value:   value,
+			Headers: tracing.InjectHeaders(ctx, nil),
+		})
+		if err == nil {
+			return
+		}
+	}
+	span.RecordError(err)
+	logger.Ctx(ctx).Error().Err(err).Msgf("fail to send play event of video %v", event.VideoId)
+}
+
+// startConsumers 以play消费组启动播放事件的消费者，多个实例分摊消息，每条播放事件只统计一次
+func (p *playService) startConsumers() {
+	go consumeGroup("play", []string{constants.KafkaTopicPrefix + "play"}, p.handlePlayEvent)
+}
+
+func (p *playService) handlePlayEvent(ctx context.Context, msg *mqUtils.Message) {
+	var event api.PlayEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		logger.Ctx(ctx).Warn().Msgf("unknown play message, value = %v", string(msg.Value))
+		return
+	}
+	if err := p.aggregate(ctx, &event); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to aggregate play event of video %v", event.VideoId)
+	}
+}
+
+// aggregate 在redis中聚合播放事件，同一用户对同一视频在去重窗口内只计一次播放，观看时长按事件类型各计一次
+func (p *playService) aggregate(ctx context.Context, event *api.PlayEvent) error {
+	videoIdStr := strconv.FormatInt(event.VideoId, 10)
+	userIdStr := strconv.FormatInt(event.UserId, 10)
+	if err := p.loadPlayStat(ctx, event.VideoId); err != nil {
+		return err
+	}
+	statKey := playStatPrefix + videoIdStr
+	if event.EventType == api.PlayStartEvent {
+		first, err := p.countPlay(ctx, playDedupPrefix+videoIdStr+"_"+userIdStr, statKey, videoIdStr, event.Timestamp,
+			playFieldCount, 1)
+		if err != nil {
+			return err
+		}
+		if first {
+			if err = GetHotServiceInstance().addEngagement(ctx, event.VideoId, hotFieldPlay, 1); err != nil {
+				logger.Ctx(ctx).Error().Err(err).Msgf("fail to add play engagement of video %v", event.VideoId)
+			}
+			if err = GetCreatorServiceInstance().addVideoDaily(ctx, event.VideoId, time.UnixMilli(event.Timestamp),
+				map[string]int64{statFieldViews: 1}); err != nil {
+				logger.Ctx(ctx).Error().Err(err).Msgf("fail to add daily views of video %v", event.VideoId)
+			}
+		}
+	} else if event.WatchDuration > 0 {
+		first, err := p.countPlay(ctx, watchDedupPrefix+videoIdStr+"_"+userIdStr+"_"+strconv.Itoa(int(event.EventType)),
+			statKey, videoIdStr, event.Timestamp, playFieldWatchTime, event.WatchDuration, playFieldWatchCount, 1)
+		if err != nil {
+			return err
+		}
+		if first {
+			if err = GetCreatorServiceInstance().addVideoDaily(ctx, event.VideoId, time.UnixMilli(event.Timestamp),
+				map[string]int64{statFieldWatchTime: event.WatchDuration, statFieldWatchCount: 1}); err != nil {
+				logger.Ctx(ctx).Error().Err(err).Msgf("fail to add daily watch time of video %v", event.VideoId)
+			}
+		}
+	}
+	return nil
+}
+
+// countPlay 原子地完成去重与播放统计的累加，fields为字段名与增量交替排列，返回是否为去重窗口内的第一次上报
+func (p *playService) countPlay(ctx context.Context, dedupKey, statKey, videoIdStr string, timestamp int64, fields ...interface{}) (bool, error) {
+	args := append([]interface{}{timestamp, getPlayDedupWindow().Milliseconds(), videoIdStr}, fields...)
+	n, err := countPlay.Run(ctx, redisClient, []string{dedupKey, statKey, playDirtyKey}, args...).Int()
+	if err != nil {
+		return false, constants.RedisDBErr
+	}
+	return n == 1, nil
+}
+
+// loadPlayStat 若redis中不存在视频的播放统计，则从数据库中加载
+func (p *playService) loadPlayStat(ctx context.Context, videoId int64) error {
+	statKey := playStatPrefix + strconv.FormatInt(videoId, 10)
+	exists, err := redisClient.Exists(ctx, statKey).Result()
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	if exists == 1 {
+		redisClient.Expire(ctx, statKey, getPlayStatExpireTime())
+		return nil
+	}
+	video, err := dao.GetVideoDaoInstance().GetVideoByVideoIdInfo(videoId)
+	if err != nil {
+		return err
+	}
+	pipe := redisClient.TxPipeline()
+	pipe.HSetNX(ctx, statKey, playFieldCount, video.PlayCount)
+	pipe.HSetNX(ctx, statKey, playFieldWatchTime, video.WatchTime)
+	pipe.HSetNX(ctx, statKey, playFieldWatchCount, video.WatchCount)
+	pipe.Expire(ctx, statKey, getPlayStatExpireTime())
+	if _, err = pipe.Exec(ctx); err != nil {
+		return constants.RedisDBErr
+	}
+	return nil
+}
+
+// playStat 获取视频的播放量以及平均观看时长(毫秒)，redis中没有播放统计时使用数据库中的值
+func playStat(ctx context.Context, video *model.Video) (int64, int64) {
+	playCount, watchTime, watchCount := video.PlayCount, video.WatchTime, video.WatchCount
+	stat, err := redisClient.HMGet(ctx, playStatPrefix+strconv.FormatInt(video.VideoID, 10),
+		playFieldCount, playFieldWatchTime, playFieldWatchCount).Result()
+	if err == nil && stat[0] != nil {
+		values := parsePlayStat(stat)
+		playCount, watchTime, watchCount = values[0], values[1], values[2]
+	}
+	var avgWatchTime int64
+	if watchCount > 0 {
+		avgWatchTime = watchTime / watchCount
+	}
+	return playCount, avgWatchTime
+}
+
+// parsePlayStat 将HMGet得到的播放统计转换为整数，不存在的字段为0
+func parsePlayStat(stat []interface{}) []int64 {
+	values := make([]int64, len(stat))
+	for i, v := range stat {
+		if s, ok := v.(string); ok {
+			values[i], _ = strconv.ParseInt(s, 10, 64)
+		}
+	}
+	return values
+}
+
+// WritePlayStatToDataBaseRegularly 定时将redis中有变化的播放统计写入数据库
+// 需要写入的视频记录在redis的集合中，因此多个实例之间共享且重启后不会丢失
+func (p *playService) WritePlayStatToDataBaseRegularly() error {
+	ctx := context.Background()
+	for {
+		videoIdStr, err := redisClient.SPop(ctx, playDirtyKey).Result()
+		if err == redis.Nil {
+			// 集合为空
+			return nil
+		} else if err != nil {
+			return constants.RedisDBErr
+		}
+		stat, err := redisClient.HMGet(ctx, playStatPrefix+videoIdStr,
+			playFieldCount, playFieldWatchTime, playFieldWatchCount).Result()
+		if err != nil {
+			redisClient.SAdd(ctx, playDirtyKey, videoIdStr)
+			return constants.RedisDBErr
+		}
+		values := parsePlayStat(stat)
+		videoId, _ := strconv.ParseInt(videoIdStr, 10, 64)
+		if err = dao.GetVideoDaoInstance().SetPlayStat(videoId, values[0], values[1], values[2]); err != nil {
+			redisClient.SAdd(ctx, playDirtyKey, videoIdStr)
+			return err
+		}
+	}
+}
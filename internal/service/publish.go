@@ -3,6 +3,7 @@ package service
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	pbservice "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_controller_service/video"
 	pbdao "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_service_dao/video"
@@ -13,9 +14,13 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/files"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/idGenerator"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/pageUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 	"io"
@@ -52,7 +57,7 @@ const (
 // GetVideoServiceInstance 获取publishServiceInstance的实例
 func GetVideoServiceInstance() *videoService {
 	initRedis()
-	initKafka()
+	initPublisher()
 	publishOnce.Do(func() {
 		publishServiceInstance = &videoService{}
 	})
@@ -65,7 +70,7 @@ func (p *videoService) PublishVideoInfo(ctx context.Context, in *pbservice.Video
 	fileName := in.FileName
 	fileSize := in.FileSize
 	content := in.Content
-	err := p.PublishInfo(&content, userId, fileSize, title, fileName)
+	err := p.PublishInfo(ctx, &content, userId, fileSize, title, fileName)
 	if err != nil {
 		return &wrapperspb.BoolValue{Value: false}, err
 	} else {
@@ -73,13 +78,51 @@ func (p *videoService) PublishVideoInfo(ctx context.Context, in *pbservice.Video
 	}
 }
 
+// GetPublishListInfo GRPC调用，分页获取用户发布的视频列表
+func (p *videoService) GetPublishListInfo(in *pbservice.UserPost, stream pbservice.VideoServiceInfo_GetPublishListInfoServer) error {
+	limit := int(in.Limit)
+	if limit <= 0 || limit > initialization.PageConf.MaxLimit {
+		limit = initialization.PageConf.DefaultLimit
+	}
+	videoList, next, hasMore, err := p.PublishListInfo(stream.Context(), in.QueryUserId, in.LoginUserId, in.Cursor, limit)
+	if err != nil {
+		return err
+	}
+	//分页信息通过trailer返回
+	stream.SetTrailer(metadata.Pairs("next-cursor", next, "has-more", strconv.FormatBool(hasMore)))
+
+	for _, video := range videoList {
+		err := stream.Send(&pbservice.VideoServiceResp{
+			UserResp: &pbservice.UserServiceResp{
+				Id:          video.Author.Id,
+				Name:        video.Author.Name,
+				FollowCnt:   video.Author.FollowCount,
+				FollowerCnt: video.Author.FollowerCount,
+				IsFollow:    video.Author.IsFollow,
+			},
+			VideoId:       video.Id,
+			FavoriteCount: video.FavoriteCount,
+			CommentCount:  video.CommentCount,
+			PlayURL:       video.PlayUrl,
+			CoverURL:      video.CoverUrl,
+			IsFavorite:    video.IsFavorite,
+			PlayCount:     video.PlayCount,
+			AvgWatchTime:  video.AvgWatchTime,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *videoService) uploadVideoToOSS(data *[]byte, userId int64, filename string) error {
 	reader := bytes.NewReader(*data)
 
 	// 先将文件流上传至BucketDirectory目录下
 	err := oss.UploadFromReader(getUploadPath(userId, filename), reader)
 	if err != nil {
-		logger.GlobalLogger.Printf("Error in UploadFromReader: %v", err.Error())
+		logger.GlobalLogger.Error().Err(err).Msg("Error in UploadFromReader")
 		return err
 	}
 
@@ -88,7 +131,7 @@ func (p *videoService) uploadVideoToOSS(data *[]byte, userId int64, filename str
 
 func (p *videoService) uploadCoverToOSS(userId int64, filepath, filename string) error {
 	if err := oss.UploadFromFile(getUploadPath(userId, filename), filepath); err != nil {
-		logger.GlobalLogger.Printf("Error in UploadFromFile: %v", err.Error())
+		logger.GlobalLogger.Error().Err(err).Msg("Error in UploadFromFile")
 		return err
 	}
 
@@ -96,9 +139,9 @@ func (p *videoService) uploadCoverToOSS(userId int64, filepath, filename string)
 }
 
 // PublishInfo service层上传user的一个视频
-func (p *videoService) PublishInfo(data *[]byte, userId, fileSize int64, title, fileName string) error {
-	logger.GlobalLogger.Printf("title = %v", title)
-	logger.GlobalLogger.Printf("fileName = %v", fileName)
+func (p *videoService) PublishInfo(ctx context.Context, data *[]byte, userId, fileSize int64, title, fileName string) error {
+	logger.Ctx(ctx).Debug().Msgf("title = %v", title)
+	logger.Ctx(ctx).Debug().Msgf("fileName = %v", fileName)
 	//首先检查video的扩展名与大小
 	if !files.CheckFileExt(fileName) {
 		return constants.VideoFormatErr
@@ -107,12 +150,12 @@ func (p *videoService) PublishInfo(data *[]byte, userId, fileSize int64, title,
 		return constants.VideoSizeErr
 	}
 
-	logger.GlobalLogger.Print("Start Saving")
+	logger.Ctx(ctx).Debug().Msg("Start Saving")
 	//然后将文件保存至本地
 	saveDir := path.Join(initialization.VideoConf.SavePath, strconv.FormatInt(userId, 10))
 	videoName, err := files.SaveDataToLocal(saveDir, data, fileName)
 	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v, Saving Video Error = %v", time.Now(), err.Error())
+		logger.Ctx(ctx).Error().Err(err).Msgf("Time = %v, Saving Video Error", time.Now())
 		return constants.SavingFailErr
 	}
 
@@ -122,15 +165,15 @@ func (p *videoService) PublishInfo(data *[]byte, userId, fileSize int64, title,
 	saveCover := saveDir + "/" + coverName
 	err = files.ExtractCoverFromVideo(saveVideo, saveCover)
 	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v, Extracting Cover Error = %v", time.Now(), err.Error())
+		logger.Ctx(ctx).Error().Err(err).Msgf("Time = %v, Extracting Cover Error", time.Now())
 		return constants.SavingFailErr
 	}
 
 	//上传视频与封面
-	logger.GlobalLogger.Print("Saving Complete, Start Uploading")
+	logger.Ctx(ctx).Debug().Msg("Saving Complete, Start Uploading")
 	err = p.uploadVideoToOSS(data, userId, videoName)
 	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v, Extracting Cover Error = %v", time.Now(), err.Error())
+		logger.Ctx(ctx).Error().Err(err).Msgf("Time = %v, Extracting Cover Error", time.Now())
 		return constants.UploadFailErr
 	}
 	err = p.uploadCoverToOSS(userId, saveCover, coverName)
@@ -139,15 +182,15 @@ func (p *videoService) PublishInfo(data *[]byte, userId, fileSize int64, title,
 
 	//RPC写入数据库
 	address := initialization.RpcSDConf.VideoServiceHost + initialization.RpcSDConf.VideoServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(ctx).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	c := pbdao.NewVideoDaoInfoClient(conn)
 	// 更新用户的publish list
 	// Contact the server and print out its response.
-	ctx1, cancel1 := context.WithTimeout(context.Background(), time.Second)
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Second)
 	defer cancel1()
 	_, err = c.AddVideo(ctx1, &pbdao.VideoDaoPost{
 		VideoId:   videoId,
@@ -156,59 +199,92 @@ func (p *videoService) PublishInfo(data *[]byte, userId, fileSize int64, title,
 		PlayURL:   getUploadURL(userId, videoName),
 		CoverURL:  getUploadURL(userId, coverName),
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	// 发布事件的消费者会再次加入布隆过滤器，这里失败时只记录日志
+	if err = videoIdBloom.Add(ctx, strconv.FormatInt(videoId, 10)); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to add video %v to bloom filter", videoId)
+	}
+	metrics.Business(metrics.EventUpload)
+	return nil
 }
 
-// PublishListInfo service层获得用户userId所有发表过的视频
-func (p *videoService) PublishListInfo(userId, loginUserId int64) ([]api.Video, error) {
+// PublishListInfo service层按发布顺序倒序分页获得用户userId发表过的视频，返回视频列表、下一页的游标以及是否还有更多
+// 可能返回的错误类型：InvalidCursorErr
+func (p *videoService) PublishListInfo(ctx context.Context, userId, loginUserId int64, cursor string, limit int) ([]api.Video, string, bool, error) {
 	var err error
+	page, err := pageUtils.Decode(cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
 	//RPC从数据库中读取
 	address := initialization.RpcSDConf.VideoServiceHost + initialization.RpcSDConf.VideoServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(ctx).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	grpcClient := pbdao.NewVideoDaoInfoClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	stream, err := grpcClient.GetPublishIdList(ctx, &wrapperspb.Int64Value{Value: userId})
+	// 多取一个用于判断是否还有下一页
+	stream, err := grpcClient.GetPublishIdList(ctx, &pbdao.PublishListPost{
+		UserId: userId,
+		Seq:    page.Seq,
+		Limit:  int32(limit + 1),
+	})
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
-	videoIdList := make([]int64, 0)
+	idMsgs := make([]*pbdao.PublishIdMsg, 0, limit+1)
 	for {
 		videoResp, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			logger.GlobalLogger.Printf("get Videos From Dao Failed")
-			return nil, err
+			logger.Ctx(ctx).Error().Msg("get Videos From Dao Failed")
+			return nil, "", false, err
 		}
-		videoIdList = append(videoIdList, videoResp.Value)
+		idMsgs = append(idMsgs, videoResp)
+	}
+	hasMore := len(idMsgs) > limit
+	if hasMore {
+		idMsgs = idMsgs[:limit]
+	}
+	videoIdList := make([]int64, len(idMsgs))
+	for i, idMsg := range idMsgs {
+		videoIdList[i] = idMsg.VideoId
 	}
 	go p.putPublishListInRedis(userId, videoIdList)
 	videoList := make([]*model.Video, 0)
-	err = p.getVideoListThroughVideoIdList(videoIdList, &videoList)
+	err = p.getVideoListThroughVideoIdList(ctx, videoIdList, &videoList)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
 	apiVideos, err := getVideoListByModel(loginUserId, videoList)
-	return apiVideos, nil
+	if err != nil {
+		return nil, "", false, err
+	}
+	next := ""
+	if hasMore {
+		next = pageUtils.Cursor{Seq: idMsgs[len(idMsgs)-1].Seq}.Encode()
+	}
+	return apiVideos, next, hasMore, nil
 }
 
 // gRPC双向通信流
-func (p *videoService) getVideoListThroughVideoIdList(videoIdList []int64, videoList *[]*model.Video) error {
+func (p *videoService) getVideoListThroughVideoIdList(ctx context.Context, videoIdList []int64, videoList *[]*model.Video) error {
 	var err error
 	address := initialization.RpcSDConf.VideoServiceHost + initialization.RpcSDConf.VideoServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(ctx).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	grpcClient := pbdao.NewVideoDaoInfoClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	stream, err := grpcClient.GetVideoListByVideoIdList(ctx)
 	if err != nil {
@@ -224,7 +300,7 @@ func (p *videoService) getVideoListThroughVideoIdList(videoIdList []int64, video
 				return
 			}
 			if err != nil {
-				logger.GlobalLogger.Printf("error occurs In  getVideoListThroughVideoIdList, %v", err)
+				logger.Ctx(ctx).Error().Err(err).Msg("error occurs In  getVideoListThroughVideoIdList")
 			}
 			*videoList = append(*videoList, &model.Video{
 				VideoID:       videoResp.VideoId,
@@ -232,6 +308,9 @@ func (p *videoService) getVideoListThroughVideoIdList(videoIdList []int64, video
 				UserID:        videoResp.UserId,
 				FavoriteCount: videoResp.FavoriteCount,
 				CommentCount:  videoResp.CommentCount,
+				PlayCount:     videoResp.PlayCount,
+				WatchTime:     videoResp.WatchTime,
+				WatchCount:    videoResp.WatchCount,
 				PlayURL:       videoResp.PlayURL,
 				CoverURL:      videoResp.CoverURL,
 			})
@@ -248,6 +327,24 @@ func (p *videoService) getVideoListThroughVideoIdList(videoIdList []int64, video
 	return nil
 }
 
+// startConsumers 以video_published消费组启动视频发布事件的消费者，布隆过滤器保存在redis中，由一个实例写入即可
+func (p *videoService) startConsumers() {
+	go consumeGroup("video_published", []string{constants.KafkaTopicPrefix + "video_published"}, p.handleVideoPublished)
+}
+
+// handleVideoPublished 处理一条由发件箱发送的视频发布事件，将视频加入布隆过滤器
+// 发布时直接写入布隆过滤器失败时，由该事件保证视频不会被误判为不存在；重复的事件不影响结果
+func (p *videoService) handleVideoPublished(ctx context.Context, msg *mqUtils.Message) {
+	var event api.VideoPublishedEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		logger.Ctx(ctx).Warn().Msgf("unknown video published message, value = %v", string(msg.Value))
+		return
+	}
+	if err := videoIdBloom.Add(ctx, strconv.FormatInt(event.VideoId, 10)); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to add video %v to bloom filter", event.VideoId)
+	}
+}
+
 func (p *videoService) updatePublishListInRedis(userId, videoId int64) {
 }
 
@@ -2,8 +2,8 @@ package service
 
 import (
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/cacheUtils"
 	"github.com/go-redis/redis/v8"
-	"math/rand"
 	"sync"
 	"time"
 )
@@ -16,14 +16,15 @@ var (
 func initRedis() {
 	redisOnce.Do(func() {
 		redisClient = initialization.GetRDB()
+		initCache()
 	})
 }
 
 const (
-	emptyCache           = "{}"
+	emptyCache           = cacheUtils.EmptyCache
 	emptyCacheExpireTime = time.Hour
 )
 
 func getEmptyCacheExpireTime() time.Duration {
-	return time.Duration(int64(emptyCacheExpireTime) + rand.Int63n(int64(30*time.Minute)))
+	return cacheUtils.JitterTTL(emptyCacheExpireTime, 30*time.Minute)
 }
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+const (
+	videoFavoriteTouchedPrefix = "video_favorite_touched_" // 视频最近有点赞操作，对账时跳过
+	favoriteReconcileReportKey = "favorite_reconcile_report"
+)
+
+// FavoriteReconcileReport 一次点赞数对账的结果
+type FavoriteReconcileReport struct {
+	Scanned    int64         // 检查的视频数
+	Skipped    int64         // 最近有点赞操作而跳过的视频数
+	DBDrift    int64         // videos表中点赞数与点赞记录不一致的视频数
+	RedisDrift int64         // redis中点赞数与点赞记录不一致的视频数
+	TotalDrift int64         // 所有不一致的点赞数与点赞记录之差的绝对值之和
+	Repaired   int64         // 修复的点赞数个数
+	StartedAt  time.Time     // 开始时间
+	Duration   time.Duration // 耗时
+}
+
+// touchVideoFavorite 标记视频最近有点赞操作
+func (f *favoriteService) touchVideoFavorite(videoId int64) {
+	grace := time.Duration(initialization.ReconcileConf.GraceMinutes) * time.Minute
+	if grace <= 0 {
+		return
+	}
+	redisClient.Set(context.Background(), videoFavoriteTouchedPrefix+strconv.FormatInt(videoId, 10), 1, grace)
+}
+
+// ReconcileFavoriteCounts 以点赞记录为准，检查videos表与redis中每个视频的点赞数，repair为true时修复不一致
+// 最近有点赞操作的视频可能还有点赞消息未写入数据库，本次跳过；结果同时写入redis供运维查看，并导出为指标
+func (f *favoriteService) ReconcileFavoriteCounts(repair bool) (*FavoriteReconcileReport, error) {
+	ctx := context.Background()
+	report := &FavoriteReconcileReport{StartedAt: time.Now()}
+	batchSize := initialization.ReconcileConf.BatchSize
+	var seq int64
+	for {
+		videos, err := dao.GetVideoDaoInstance().ScanVideos(seq, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(videos) == 0 {
+			break
+		}
+		seq = int64(videos[len(videos)-1].ID)
+		videoIds := make([]int64, len(videos))
+		countKeys := make([]string, len(videos))
+		touchedKeys := make([]string, len(videos))
+		for i, video := range videos {
+			videoIdStr := strconv.FormatInt(video.VideoID, 10)
+			videoIds[i] = video.VideoID
+			countKeys[i] = videoFavoritePrefix + videoIdStr
+			touchedKeys[i] = videoFavoriteTouchedPrefix + videoIdStr
+		}
+		truth, err := dao.GetFavoriteDaoInstance().CountFavorites(videoIds)
+		if err != nil {
+			return report, err
+		}
+		cached, err := redisClient.MGet(ctx, countKeys...).Result()
+		if err != nil {
+			return report, constants.RedisDBErr
+		}
+		touched, err := redisClient.MGet(ctx, touchedKeys...).Result()
+		if err != nil {
+			return report, constants.RedisDBErr
+		}
+		for i, video := range videos {
+			report.Scanned++
+			if touched[i] != nil {
+				report.Skipped++
+				continue
+			}
+			expected := truth[video.VideoID]
+			if video.FavoriteCount != expected {
+				report.DBDrift++
+				report.TotalDrift += abs64(int64(video.FavoriteCount) - int64(expected))
+				if repair {
+					if err = dao.GetFavoriteDaoInstance().SetFavoriteCount(video.VideoID, expected); err != nil {
+						return report, err
+					}
+					report.Repaired++
+				}
+			}
+			value, ok := cached[i].(string)
+			if !ok || value == emptyCache {
+				continue
+			}
+			cachedCount, err := strconv.ParseInt(value, 10, 32)
+			if err != nil || int32(cachedCount) != expected {
+				report.RedisDrift++
+				report.TotalDrift += abs64(cachedCount - int64(expected))
+				if repair {
+					if err = redisClient.Set(ctx, countKeys[i], expected, redis.KeepTTL).Err(); err != nil {
+						return report, constants.RedisDBErr
+					}
+					report.Repaired++
+				}
+			}
+		}
+		if len(videos) < batchSize {
+			break
+		}
+	}
+	report.Duration = time.Since(report.StartedAt)
+	redisClient.HSet(ctx, favoriteReconcileReportKey, map[string]interface{}{
+		"started_at":  report.StartedAt.Unix(),
+		"duration_ms": report.Duration.Milliseconds(),
+		"scanned":     report.Scanned,
+		"skipped":     report.Skipped,
+		"db_drift":    report.DBDrift,
+		"redis_drift": report.RedisDrift,
+		"total_drift": report.TotalDrift,
+		"repaired":    report.Repaired,
+	})
+	metrics.FavoriteDrift.WithLabelValues("db").Set(float64(report.DBDrift))
+	metrics.FavoriteDrift.WithLabelValues("redis").Set(float64(report.RedisDrift))
+	metrics.FavoriteDrift.WithLabelValues("total").Set(float64(report.TotalDrift))
+	return report, nil
+}
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
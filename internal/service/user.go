@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	pbservice "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_controller_service/user"
 	pbdao "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_service_dao/user"
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
@@ -10,12 +12,13 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/idGenerator"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
-	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/md5"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/mqUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/passwordUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
-	"math/rand"
 	"strconv"
 	"sync"
 	"time"
@@ -24,6 +27,7 @@ import (
 // userService 与用户相关的操作使用的结构体
 type userService struct {
 	pbservice.UnimplementedUserServiceInfoServer
+	hasher *passwordUtils.Hasher
 }
 
 var (
@@ -32,20 +36,28 @@ var (
 )
 
 const (
-	userLoginPrefix     = "user_login_"
+	userLoginPrefix     = "user_login_v2_" // 旧版本的登录缓存中包含密码，更换前缀后由有效期清除
 	userLoginExpireTime = 90 * time.Minute
 )
 
-func getUserLoginExpireTime() time.Duration {
-	return time.Duration(int64(userLoginExpireTime) + rand.Int63n(int64(30*time.Minute)))
-}
-
 // GetUserServiceInstance 单例模式，获得一个userService的实例
 func GetUserServiceInstance() *userService {
 	initRedis()
-	initKafka()
+	initPublisher()
 	userOnce.Do(func() {
-		userServiceInstance = &userService{}
+		conf := initialization.UserConf
+		hasher, err := passwordUtils.NewHasher(passwordUtils.Options{
+			Algorithm:     conf.PasswordHash,
+			BcryptCost:    conf.BcryptCost,
+			Argon2Time:    conf.Argon2Time,
+			Argon2Memory:  conf.Argon2Memory,
+			Argon2Threads: conf.Argon2Threads,
+			LegacyMD5:     conf.PasswordEncrypted,
+		})
+		if err != nil {
+			panic(err)
+		}
+		userServiceInstance = &userService{hasher: hasher}
 	})
 	return userServiceInstance
 }
@@ -54,8 +66,8 @@ func GetUserServiceInstance() *userService {
 func (u *userService) UserRegister(ctx context.Context, in *pbservice.UserServicePost) (*pbservice.UserServiceResp, error) {
 	username := in.Username
 	password := in.Password
-	logger.GlobalLogger.Printf("username = %v, password = %v", username, password)
-	userInfo, err := u.userRegisterInfo(username, password)
+	logger.Ctx(ctx).Debug().Msgf("username = %v", username)
+	userInfo, err := u.userRegisterInfo(ctx, username, password)
 	//返回已经存在的错误
 	if err != nil {
 		return nil, err
@@ -73,7 +85,7 @@ func (u *userService) GetUserInfo(ctx context.Context, in *pbservice.UserService
 	_ = in.LoginUserId
 	queryUserId := in.QueryUserId
 	if "" != username && "" != password {
-		userInfo, err := u.checkUserInfo(username, password)
+		userInfo, err := u.checkUserInfo(ctx, username, password)
 		if err != nil {
 			return nil, err
 		}
@@ -112,51 +124,35 @@ func (u *userService) GetUserIdByUserName(ctx context.Context, in *pbservice.Use
 }
 
 // service层对用户注册请求的内部处理逻辑
-func (u *userService) userRegisterInfo(username, password string) (*model.User, error) {
+func (u *userService) userRegisterInfo(ctx context.Context, username, password string) (*model.User, error) {
 	var err error
-	// 先看redis中存不存在
-	key := userLoginPrefix + username
-	exist, err := redisClient.Exists(key).Result()
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, constants.RedisDBErr.Error())
-	}
-	if exist == 1 { //存在直接返回错误
+	// 先看缓存或数据库中存不存在，存在直接返回错误
+	_, err = userLoginCache.Get(ctx, username, u.loadUserLogin(username))
+	if err == nil {
 		return nil, status.Errorf(codes.AlreadyExists, constants.UserAlreadyExistErr.Error())
+	} else if !errors.Is(constants.RecordNotExistErr, err) {
+		return nil, err
 	}
 	address := initialization.RpcSDConf.UserServiceHost + initialization.RpcSDConf.UserServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(ctx).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	c := pbdao.NewUserDaoInfoClient(conn)
 
-	// Contact the server and print out its response.
-	ctx1, cancel1 := context.WithTimeout(context.Background(), time.Second)
-	defer cancel1()
-	userResp, err := c.GetUserInfoByUserName(ctx1, &pbdao.UserDaoPost{Username: username})
-
-	if userResp != nil {
-		return nil, status.Errorf(codes.AlreadyExists, constants.UserAlreadyExistErr.Error())
-	} else if err != nil {
-		if errors.Is(status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error()), err) {
-			return nil, err
-		}
-	}
-
 	userId := idGenerator.GenerateUserId()
-	logger.GlobalLogger.Printf("userId = %v", userId)
+	logger.Ctx(ctx).Debug().Msgf("userId = %v", userId)
 	user := &model.User{
 		UserID:   userId,
 		UserName: username,
 	}
-	if initialization.UserConf.PasswordEncrypted {
-		user.PassWord = md5.MD5(password)
-	} else {
-		user.PassWord = password
+	if user.PassWord, err = u.hasher.Hash(password); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("fail to hash password")
+		return nil, status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error())
 	}
-	go u.writeUsernameToUserInfoToRedis(username, user.PassWord, userId)
-	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	// 登录缓存由注册事件的消费者在用户写入数据库后写入，避免写入失败时缓存中残留不存在的用户
+	ctx2, cancel2 := context.WithTimeout(ctx, time.Second)
 	defer cancel2()
 	result, err := c.AddUser(ctx2, &pbdao.UserDaoPost{
 		Username: user.UserName,
@@ -164,176 +160,164 @@ func (u *userService) userRegisterInfo(username, password string) (*model.User,
 		UserId:   user.UserID,
 	})
 
-	if !result.Value {
+	if !result.GetValue() {
 		return nil, err
 	}
+	// 删除检查用户名时留下的空缓存，注册后可以立即登录
+	userLoginCache.Delete(ctx, username)
+	if err = userIdBloom.Add(ctx, strconv.FormatInt(userId, 10)); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to add user %v to bloom filter", userId)
+	}
+	metrics.Business(metrics.EventRegister)
 	return user, nil
 }
 
 // 从username,password获得User
-func (u *userService) checkUserInfo(username, password string) (*model.User, error) {
-	var err error
-	if initialization.UserConf.PasswordEncrypted {
-		password = md5.MD5(password)
-	}
-	key := userLoginPrefix + username
-	exist, err := redisClient.Exists(key).Result()
+// 密码哈希不写入缓存，每次都从数据库读取；旧的明文/MD5密码或代价已变更的哈希在校验成功后升级
+func (u *userService) checkUserInfo(ctx context.Context, username, password string) (*model.User, error) {
+	user, err := u.getUserCredential(ctx, username)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, constants.RedisDBErr.Error())
+		return nil, err
 	}
-	// 如果存在直接返回
-	if exist == 1 {
-		userInfo, _ := redisClient.HMGet(key, "UserId", "Password").Result()
-		userId := userInfo[0].(int64)
-		pwd := userInfo[1].(string)
-		if password != pwd {
-			return nil, status.Errorf(codes.NotFound, constants.UserNotExistErr.Error())
-		}
-		redisClient.Expire(key, getUserLoginExpireTime())
-		return &model.User{UserID: userId, UserName: username}, nil
+	ok, rehash := u.hasher.Verify(user.PassWord, password)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, constants.UserNotExistErr.Error())
 	}
-	address := initialization.RpcSDConf.UserServiceHost + initialization.RpcSDConf.UserServicePort
-	conn, err := grpc.Dial(address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+	if rehash {
+		go u.upgradePassword(user.UserID, user.PassWord, password)
 	}
-	defer conn.Close()
-	c := pbdao.NewUserDaoInfoClient(conn)
-
-	// Contact the server and print out its response.
-	ctx1, cancel1 := context.WithTimeout(context.Background(), time.Second)
-	defer cancel1()
-	userResp, err := c.GetUserInfoByUserNameAndPassword(
-		ctx1, &pbdao.UserDaoPost{Username: username, Password: password})
-
-	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v, 寻找数据失败, err = %s", err.Error())
-		return nil, err
-	}
-	go u.writeUsernameToUserInfoToRedis(username, password, userResp.Id)
-	return &model.User{
-		UserID:        userResp.Id,
-		UserName:      userResp.Name,
-		FollowCount:   userResp.FollowCnt,
-		FollowerCount: userResp.FollowerCnt,
-	}, nil
+	return &model.User{UserID: user.UserID, UserName: username}, nil
 }
 
-// 通过userid得到user
-func (u *userService) getUserByUserId(userId int64) (*model.User, error) {
-	var err error
-	//从redis中获取
-	key := userLoginPrefix + strconv.FormatInt(userId, 10)
-	exist, err := redisClient.Exists(key).Result()
+// upgradePassword 使用当前配置的算法重新计算密码的哈希并替换数据库中的oldPassword，失败时在下一次登录时重试
+func (u *userService) upgradePassword(userId int64, oldPassword, password string) {
+	hash, err := u.hasher.Hash(password)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, constants.RedisDBErr.Error())
-	}
-	// 如果存在直接返回
-	if exist == 1 {
-		userInfo, _ := redisClient.HMGet(key, "UserName", "FollowCnt", "FollowerCnt").Result()
-		redisClient.Expire(key, getUserLoginExpireTime())
-		userName := userInfo[0].(string)
-		followCnt := userInfo[1].(int64)
-		followerCnt := userInfo[2].(int64)
-		return &model.User{UserID: userId, UserName: userName, FollowCount: followCnt, FollowerCount: followerCnt}, nil
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to hash password of user %v", userId)
+		return
 	}
 	address := initialization.RpcSDConf.UserServiceHost + initialization.RpcSDConf.UserServicePort
-	conn, err := grpc.Dial(address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.GlobalLogger.Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	c := pbdao.NewUserDaoInfoClient(conn)
-
-	// Contact the server and print out its response.
-	ctx1, cancel1 := context.WithTimeout(context.Background(), time.Second)
-	defer cancel1()
-	userResp, err := c.GetUserInfoByUserId(
-		ctx1, &pbdao.UserDaoPost{UserId: userId})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = c.UpdatePassword(ctx, &pbdao.UserDaoPost{
+		UserId:      userId,
+		OldPassword: oldPassword,
+		Password:    hash,
+	})
 	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v, 寻找数据失败, err = %s", err.Error())
-		return nil, err
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to upgrade password of user %v", userId)
 	}
-	userInfo := &model.User{
-		UserID:        userResp.Id,
-		UserName:      userResp.Name,
-		FollowCount:   userResp.FollowCnt,
-		FollowerCount: userResp.FollowerCnt,
+}
+
+// 通过userid得到user
+func (u *userService) getUserByUserId(userId int64) (*model.User, error) {
+	userInfo, err := userInfoCache.Get(context.Background(), strconv.FormatInt(userId, 10), func(ctx context.Context) (*model.User, error) {
+		address := initialization.RpcSDConf.UserServiceHost + initialization.RpcSDConf.UserServicePort
+		conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
+		if err != nil {
+			logger.GlobalLogger.Error().Err(err).Msg("did not connect")
+		}
+		defer conn.Close()
+		c := pbdao.NewUserDaoInfoClient(conn)
+
+		// Contact the server and print out its response.
+		ctx1, cancel1 := context.WithTimeout(ctx, time.Second)
+		defer cancel1()
+		userResp, err := c.GetUserInfoByUserId(
+			ctx1, &pbdao.UserDaoPost{UserId: userId})
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		} else if err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("Time = %v, 寻找数据失败", time.Now())
+			return nil, err
+		}
+		return &model.User{
+			UserID:        userResp.Id,
+			UserName:      userResp.Name,
+			FollowCount:   userResp.FollowCnt,
+			FollowerCount: userResp.FollowerCnt,
+		}, nil
+	})
+	if errors.Is(constants.RecordNotExistErr, err) {
+		return nil, status.Errorf(codes.NotFound, constants.UserNotExistErr.Error())
 	}
-	u.writeUserIdToUserModelToRedis(userInfo)
-	return userInfo, nil
+	return userInfo, err
 }
 
 // 通过username得到user
 func (u *userService) getUserByUserName(username string) (*model.User, error) {
-	var err error
-	//先看redis中存不存在，存在的话直接返回
-	key := userLoginPrefix + username
-	exist, err := redisClient.Exists(key).Result()
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, constants.RedisDBErr.Error())
+	login, err := userLoginCache.Get(context.Background(), username, u.loadUserLogin(username))
+	if errors.Is(constants.RecordNotExistErr, err) {
+		return nil, status.Errorf(codes.NotFound, constants.UserNotExistErr.Error())
+	} else if err != nil {
+		return nil, err
 	}
-	if exist == 1 {
-		userInfos, _ := redisClient.HMGet(key, "UserId").Result()
-		redisClient.Expire(key, getUserLoginExpireTime())
-		userId, _ := userInfos[0].(int64)
-		return &model.User{UserID: userId}, nil
+	return &model.User{UserID: login.UserId}, nil
+}
+
+// loadUserLogin 登录缓存未命中时，通过username从数据库中加载用户ID
+func (u *userService) loadUserLogin(username string) func(ctx context.Context) (*userLogin, error) {
+	return func(ctx context.Context) (*userLogin, error) {
+		user, err := u.getUserCredential(ctx, username)
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return &userLogin{UserId: user.UserID}, nil
 	}
+}
+
+// getUserCredential 通过username从数据库中读取用户ID与密码哈希
+func (u *userService) getUserCredential(ctx context.Context, username string) (*model.User, error) {
 	address := initialization.RpcSDConf.UserServiceHost + initialization.RpcSDConf.UserServicePort
-	conn, err := grpc.Dial(address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(ctx).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	c := pbdao.NewUserDaoInfoClient(conn)
 
 	// Contact the server and print out its response.
-	ctx1, cancel1 := context.WithTimeout(context.Background(), time.Second)
+	ctx1, cancel1 := context.WithTimeout(ctx, time.Second)
 	defer cancel1()
-	userResp, err := c.GetUserInfoByUserName(
-		ctx1, &pbdao.UserDaoPost{Username: username})
-	if err != nil {
-		logger.GlobalLogger.Printf("Time = %v, 寻找数据失败, err = %s", err.Error())
+	userResp, err := c.GetUserInfoByUserName(ctx1, &pbdao.UserDaoPost{Username: username})
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, constants.UserNotExistErr.Error())
+	} else if errors.Is(rpcUtils.ErrBreakerOpen, err) {
 		return nil, err
+	} else if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("Time = %v, 寻找数据失败", time.Now())
+		return nil, status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error())
 	}
-	go u.writeUsernameToUserInfoToRedis(username, userResp.Password, userResp.Id)
-	return &model.User{
-		UserID: userResp.Id,
-	}, nil
+	return &model.User{UserID: userResp.Id, UserName: userResp.Name, PassWord: userResp.Password}, nil
 }
 
-func (u *userService) writeUsernameToUserInfoToRedis(username, password string, userId int64) {
-	for {
-		key := userLoginPrefix + username
-		userName2UserInfo := map[string]interface{}{
-			"UserId":   userId,
-			"Password": password,
-		}
-		err := redisClient.HMSet(key, userName2UserInfo).Err()
-		err = redisClient.Expire(key, getUserLoginExpireTime()).Err()
-		if err == nil {
-			break
-		}
+// startConsumers 以user_registered消费组启动注册事件的消费者，登录缓存保存在redis中，由一个实例写入即可
+func (u *userService) startConsumers() {
+	go consumeGroup("user_registered", []string{constants.KafkaTopicPrefix + "user_registered"}, u.handleUserRegistered)
+}
+
+// handleUserRegistered 处理一条由发件箱发送的注册事件，将用户名与用户ID写入登录缓存
+// 重复的事件只会重复写入相同的缓存，因此不需要去重；错过的事件在登录时缓存未命中，会从数据库读取
+func (u *userService) handleUserRegistered(ctx context.Context, msg *mqUtils.Message) {
+	var event api.UserRegisteredEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		logger.Ctx(ctx).Warn().Msgf("unknown user registered message, value = %v", string(msg.Value))
+		return
 	}
+	u.writeUsernameToUserInfoToRedis(ctx, event.UserName, event.UserId)
 }
 
-func (u *userService) writeUserIdToUserModelToRedis(user *model.User) {
-	for {
-		userId := user.UserID
-		userIdStr := strconv.FormatInt(userId, 10)
-		key := userLoginPrefix + userIdStr
-		userId2UserInfo := map[string]interface{}{
-			"UserName":    user.UserName,
-			"FollowCnt":   user.FollowCount,
-			"FollowerCnt": user.FollowerCount,
-		}
-		err := redisClient.HMSet(key, userId2UserInfo).Err()
-		err = redisClient.Expire(key, getUserLoginExpireTime()).Err()
-		if err == nil {
-			break
-		}
+func (u *userService) writeUsernameToUserInfoToRedis(ctx context.Context, username string, userId int64) {
+	err := userLoginCache.Set(ctx, username, &userLogin{UserId: userId})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to write login cache of %v", username)
 	}
 }
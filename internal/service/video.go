@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/dao"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
@@ -17,6 +19,7 @@ func getVideoListByModel(userId int64, videos []*model.Video) ([]api.Video, erro
 		if err != nil {
 			return nil, constants.InnerDataBaseErr
 		}
+		playCount, avgWatchTime := playStat(context.Background(), v)
 		videoList[i] = api.Video{
 			Id: v.VideoID,
 			Author: api.User{
@@ -30,6 +33,8 @@ func getVideoListByModel(userId int64, videos []*model.Video) ([]api.Video, erro
 			CoverUrl:      v.CoverURL,
 			FavoriteCount: int64(v.FavoriteCount),
 			CommentCount:  int64(v.CommentCount),
+			PlayCount:     playCount,
+			AvgWatchTime:  avgWatchTime,
 			IsFavorite:    isFavor,
 		}
 	}
@@ -40,12 +45,19 @@ func getVideoListByID(userId int64, videoIds []string) ([]api.Video, error) {
 	videoList := make([]api.Video, len(videoIds))
 	for i, videoIdstr := range videoIds {
 		videoId, _ := strconv.ParseInt(videoIdstr, 10, 64)
-		videoInfo, err := dao.GetVideoDaoInstance().GetVideoByVideoIdInfo(videoId)
-		userInfo, err := GetUserServiceInstance().getUserByUserId(userId)
+		videoInfo, err := getVideoByVideoId(videoId)
+		if err != nil {
+			return nil, err
+		}
+		userInfo, err := GetUserServiceInstance().getUserByUserId(videoInfo.UserID)
+		if err != nil {
+			return nil, err
+		}
 		isFavor, err := dao.GetFavoriteDaoInstance().CheckFavorite(userId, videoId)
 		if err != nil {
 			return nil, constants.InnerDataBaseErr
 		}
+		playCount, avgWatchTime := playStat(context.Background(), videoInfo)
 		videoList[i] = api.Video{
 			Id: videoId,
 			Author: api.User{
@@ -59,8 +71,22 @@ func getVideoListByID(userId int64, videoIds []string) ([]api.Video, error) {
 			CoverUrl:      videoInfo.CoverURL,
 			FavoriteCount: int64(videoInfo.FavoriteCount),
 			CommentCount:  int64(videoInfo.CommentCount),
+			PlayCount:     playCount,
+			AvgWatchTime:  avgWatchTime,
 			IsFavorite:    isFavor,
 		}
 	}
 	return videoList, nil
 }
+
+// getVideoByVideoId 通过缓存获取视频信息，视频不存在时返回RecordNotExistErr
+func getVideoByVideoId(videoId int64) (*model.Video, error) {
+	initRedis()
+	return videoInfoCache.Get(context.Background(), strconv.FormatInt(videoId, 10), func(ctx context.Context) (*model.Video, error) {
+		video, err := dao.GetVideoDaoInstance().GetVideoByVideoIdInfo(videoId)
+		if errors.Is(constants.RecordNotExistErr, err) {
+			return nil, nil
+		}
+		return video, err
+	})
+}
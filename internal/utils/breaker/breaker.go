@@ -0,0 +1,203 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// State 熔断器的状态
+type State int
+
+const (
+	Closed   State = iota // 正常放行请求，统计错误率
+	Open                  // 拒绝所有请求，经过OpenTimeout后进入HalfOpen
+	HalfOpen              // 放行少量探测请求，全部成功后恢复Closed，任一失败重新Open
+)
+
+const (
+	windowBuckets = 10                               // 统计窗口划分的桶数，窗口按桶滚动
+	minWindow     = windowBuckets * time.Millisecond // 统计窗口的最小值，每个桶至少1ms
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// Options 熔断器的配置
+type Options struct {
+	ErrorRate        float64       // 窗口内错误率达到该值时熔断
+	MinRequests      int           // 窗口内请求数达到该值时才判断错误率，避免少量请求失败就熔断
+	Window           time.Duration // 统计错误率的滑动窗口，小于10ms时按10ms处理
+	OpenTimeout      time.Duration // 熔断后经过该时长进入半开状态
+	HalfOpenRequests int           // 半开状态下放行的探测请求数，全部成功后恢复
+	// OnStateChange 状态变化时调用，不能在其中调用熔断器的方法
+	OnStateChange func(name string, from, to State)
+}
+
+type bucket struct {
+	start    time.Time
+	success  int
+	failures int
+}
+
+// Breaker 熔断器，依次处于关闭、打开、半开三种状态
+type Breaker struct {
+	name string
+	opts Options
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // 每次状态变化加1，忽略状态变化之前放行的请求的结果
+	buckets    [windowBuckets]bucket
+	openedAt   time.Time
+	probes     int // 半开状态下已经放行的探测请求数
+	successes  int // 半开状态下成功的探测请求数
+}
+
+func newBreaker(name string, opts Options) *Breaker {
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = 1
+	}
+	if opts.Window < minWindow {
+		opts.Window = minWindow
+	}
+	return &Breaker{name: name, opts: opts}
+}
+
+// Allow 判断是否放行一个请求，放行时返回的done必须在请求结束后调用一次，传入请求是否成功
+func (b *Breaker) Allow() (func(success bool), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.currentState(now) {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.opts.HalfOpenRequests {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+	generation := b.generation
+	return func(success bool) {
+		b.record(generation, success)
+	}, nil
+}
+
+// State 获取熔断器当前的状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState(time.Now())
+}
+
+// currentState 打开超过OpenTimeout后进入半开，半开的探测请求超过OpenTimeout仍未全部返回时重新放行探测请求
+func (b *Breaker) currentState(now time.Time) State {
+	if b.state != Closed && now.Sub(b.openedAt) >= b.opts.OpenTimeout {
+		b.openedAt = now
+		b.setState(HalfOpen)
+	}
+	return b.state
+}
+
+func (b *Breaker) record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	now := time.Now()
+	switch b.state {
+	case Closed:
+		bkt := b.bucket(now)
+		if success {
+			bkt.success++
+		} else {
+			bkt.failures++
+		}
+		total, failures := b.counts(now)
+		if total >= b.opts.MinRequests && float64(failures) >= b.opts.ErrorRate*float64(total) {
+			b.openedAt = now
+			b.setState(Open)
+		}
+	case HalfOpen:
+		if !success {
+			b.openedAt = now
+			b.setState(Open)
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenRequests {
+			b.setState(Closed)
+		}
+	}
+}
+
+// bucket 获取当前时间所在的桶，桶已经滚出窗口时清空
+func (b *Breaker) bucket(now time.Time) *bucket {
+	width := b.opts.Window / windowBuckets
+	start := now.Truncate(width)
+	bkt := &b.buckets[int(start.UnixNano()/int64(width))%windowBuckets]
+	if !bkt.start.Equal(start) {
+		*bkt = bucket{start: start}
+	}
+	return bkt
+}
+
+// counts 统计窗口内的请求数与失败数
+func (b *Breaker) counts(now time.Time) (int, int) {
+	total, failures := 0, 0
+	for _, bkt := range b.buckets {
+		if now.Sub(bkt.start) < b.opts.Window {
+			total += bkt.success + bkt.failures
+			failures += bkt.failures
+		}
+	}
+	return total, failures
+}
+
+func (b *Breaker) setState(state State) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.probes, b.successes = 0, 0
+	if state == Closed {
+		b.buckets = [windowBuckets]bucket{}
+	}
+	if b.opts.OnStateChange != nil && from != state {
+		b.opts.OnStateChange(b.name, from, state)
+	}
+}
+
+// Group 按名称(例如gRPC的目标地址)分别熔断，所有熔断器使用相同的配置
+type Group struct {
+	opts     Options
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+func NewGroup(opts Options) *Group {
+	return &Group{opts: opts, breakers: make(map[string]*Breaker)}
+}
+
+// Get 获取名称对应的熔断器，不存在时创建
+func (g *Group) Get(name string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, ok := g.breakers[name]
+	if !ok {
+		b = newBreaker(name, g.opts)
+		g.breakers[name] = b
+	}
+	return b
+}
@@ -0,0 +1,137 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+var testOptions = Options{
+	ErrorRate:        0.5,
+	MinRequests:      4,
+	Window:           time.Second,
+	OpenTimeout:      50 * time.Millisecond,
+	HalfOpenRequests: 2,
+}
+
+func call(t *testing.T, b *Breaker, success bool) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow in state %v = %v", b.State(), err)
+	}
+	done(success)
+}
+
+func TestBreakerOpensOnErrorRate(t *testing.T) {
+	b := NewGroup(testOptions).Get("video")
+	call(t, b, false)
+	call(t, b, false)
+	call(t, b, false)
+	// 请求数未达到MinRequests时不熔断
+	if b.State() != Closed {
+		t.Fatalf("state = %v, want closed", b.State())
+	}
+	call(t, b, true)
+	if b.State() != Open {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatalf("Allow when open = %v, want ErrOpen", err)
+	}
+}
+
+func TestBreakerStaysClosedBelowErrorRate(t *testing.T) {
+	b := NewGroup(testOptions).Get("video")
+	for i := 0; i < 10; i++ {
+		call(t, b, i%4 != 0)
+	}
+	if b.State() != Closed {
+		t.Fatalf("state = %v, want closed", b.State())
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	var transitions []State
+	opts := testOptions
+	opts.OnStateChange = func(name string, from, to State) {
+		transitions = append(transitions, to)
+	}
+	b := NewGroup(opts).Get("video")
+	for i := 0; i < 4; i++ {
+		call(t, b, false)
+	}
+	time.Sleep(opts.OpenTimeout)
+	if b.State() != HalfOpen {
+		t.Fatalf("state = %v, want half-open", b.State())
+	}
+	// 半开状态下只放行HalfOpenRequests个探测请求
+	first, _ := b.Allow()
+	second, _ := b.Allow()
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatalf("third probe = %v, want ErrOpen", err)
+	}
+	first(true)
+	second(false)
+	if b.State() != Open {
+		t.Fatalf("state after failed probe = %v, want open", b.State())
+	}
+
+	time.Sleep(opts.OpenTimeout)
+	call(t, b, true)
+	call(t, b, true)
+	if b.State() != Closed {
+		t.Fatalf("state after successful probes = %v, want closed", b.State())
+	}
+	want := []State{Open, HalfOpen, Open, HalfOpen, Closed}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions = %v, want %v", transitions, want)
+		}
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	b := NewGroup(testOptions).Get("video")
+	stale, _ := b.Allow()
+	for i := 0; i < 4; i++ {
+		call(t, b, false)
+	}
+	time.Sleep(testOptions.OpenTimeout)
+	b.State()
+	// 熔断之前放行的请求在半开状态下返回，不作为探测请求的结果
+	stale(true)
+	call(t, b, true)
+	if b.State() != HalfOpen {
+		t.Fatalf("state = %v, want half-open", b.State())
+	}
+}
+
+func TestBreakerWindowExpires(t *testing.T) {
+	opts := testOptions
+	opts.Window = 100 * time.Millisecond
+	b := NewGroup(opts).Get("video")
+	call(t, b, false)
+	call(t, b, false)
+	call(t, b, false)
+	time.Sleep(opts.Window)
+	call(t, b, false)
+	if b.State() != Closed {
+		t.Fatalf("failures outside window counted, state = %v", b.State())
+	}
+}
+
+func TestBreakerZeroWindow(t *testing.T) {
+	opts := testOptions
+	opts.Window = 0
+	b := NewGroup(opts).Get("video")
+	// 窗口为0时按最小窗口统计，不会因为桶宽为0而panic
+	for i := 0; i < 4; i++ {
+		call(t, b, false)
+	}
+	if b.State() != Open {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+}
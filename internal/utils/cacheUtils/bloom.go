@@ -0,0 +1,108 @@
+package cacheUtils
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"hash/fnv"
+	"math"
+)
+
+const (
+	bloomReadySuffix = "_ready"
+	// 过滤器未就绪时视为可能存在，避免在加载完成之前或redis数据丢失后误拦截存在的ID
+	mightContainScript = `
+if redis.call("exists",KEYS[2]) == 0 then
+    return 1
+end
+for i = 1, #ARGV do
+    if redis.call("getbit",KEYS[1],ARGV[i]) == 0 then
+        return 0
+    end
+end
+return 1`
+)
+
+// BloomFilter 保存在redis位图中的布隆过滤器，多个实例共享，用于拦截一定不存在的ID
+// 布隆过滤器只能添加不能删除，所有已存在的ID加载完成后才会开始拦截
+type BloomFilter struct {
+	client       *redis.Client
+	key          string
+	bits         uint64 // 位图的长度
+	hashes       int    // 哈希函数的个数
+	mightContain *redis.Script
+}
+
+// NewBloomFilter 按预计的元素个数与误判率计算位图长度与哈希函数个数
+func NewBloomFilter(client *redis.Client, key string, capacity int, falsePositiveRate float64) *BloomFilter {
+	bits := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Round(float64(bits) / float64(capacity) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &BloomFilter{
+		client:       client,
+		key:          key,
+		bits:         bits,
+		hashes:       hashes,
+		mightContain: redis.NewScript(mightContainScript),
+	}
+}
+
+// offsets 使用两个哈希值组合出hashes个位置
+func (b *BloomFilter) offsets(item string) []int64 {
+	h := fnv.New64a()
+	h.Write([]byte(item))
+	sum := h.Sum64()
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+	offsets := make([]int64, b.hashes)
+	for i := range offsets {
+		offsets[i] = int64((h1 + uint64(i)*h2) % b.bits)
+	}
+	return offsets
+}
+
+// Add 添加元素
+func (b *BloomFilter) Add(ctx context.Context, items ...string) error {
+	if len(items) == 0 {
+		return nil
+	}
+	pipe := b.client.Pipeline()
+	for _, item := range items {
+		for _, offset := range b.offsets(item) {
+			pipe.SetBit(ctx, b.key, offset, 1)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// MightContain 元素是否可能存在，返回false时元素一定不存在
+func (b *BloomFilter) MightContain(ctx context.Context, item string) (bool, error) {
+	offsets := b.offsets(item)
+	args := make([]interface{}, len(offsets))
+	for i, offset := range offsets {
+		args[i] = offset
+	}
+	n, err := b.mightContain.Run(ctx, b.client, []string{b.key, b.key + bloomReadySuffix}, args...).Int()
+	if err != nil {
+		return true, err
+	}
+	return n == 1, nil
+}
+
+// Ready 是否已经加载完成并开始拦截
+func (b *BloomFilter) Ready(ctx context.Context) (bool, error) {
+	n, err := b.client.Exists(ctx, b.key+bloomReadySuffix).Result()
+	return n == 1, err
+}
+
+// Load 通过scan加载所有已存在的元素，完成后开始拦截
+// scan每次调用add添加一批元素；重复加载是安全的，可以定时执行以修复添加失败的元素
+func (b *BloomFilter) Load(ctx context.Context, scan func(add func(items ...string) error) error) error {
+	if err := scan(func(items ...string) error {
+		return b.Add(ctx, items...)
+	}); err != nil {
+		return err
+	}
+	return b.client.Set(ctx, b.key+bloomReadySuffix, 1, 0).Err()
+}
@@ -0,0 +1,170 @@
+package cacheUtils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/go-redis/redis/v8"
+	"math/rand"
+	"time"
+)
+
+// EmptyCache 空缓存，表示记录不存在，防止缓存穿透
+const EmptyCache = "{}"
+
+var errPanicked = errors.New("cache loader panicked")
+
+// JitterTTL 在基础有效期上增加[0, jitter)的随机时长，避免大量缓存同时过期造成缓存雪崩
+func JitterTTL(base, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return base
+	}
+	return base + time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// Options 缓存的配置
+type Options struct {
+	Prefix    string        // redis中key的前缀
+	TTL       time.Duration // redis中缓存的有效期
+	EmptyTTL  time.Duration // 空缓存的有效期
+	Jitter    time.Duration // 有效期的随机增量
+	LocalSize int           // 进程内缓存的容量，为0时不使用进程内缓存
+	LocalTTL  time.Duration // 进程内缓存的有效期，进程内缓存不随redis中的缓存失效，因此应远小于TTL
+	Bloom     *BloomFilter  // 拦截一定不存在的key，为nil时不拦截
+}
+
+// Cache 旁路缓存，依次查询进程内的LRU缓存、redis，都未命中时调用loader从数据源加载并回写
+// 相同key的并发加载通过协程共享调用合并为一次；不存在的记录写入空缓存；布隆过滤器拦截一定不存在的key
+type Cache[T any] struct {
+	client *redis.Client
+	opts   Options
+	local  *lru
+	group  Group
+}
+
+// NewCache 创建一个缓存，值以json的形式保存在redis中
+func NewCache[T any](client *redis.Client, opts Options) *Cache[T] {
+	c := &Cache[T]{client: client, opts: opts}
+	if opts.LocalSize > 0 && opts.LocalTTL > 0 {
+		c.local = newLRU(opts.LocalSize)
+	}
+	return c
+}
+
+// Get 获取key对应的值，记录不存在时返回RecordNotExistErr
+// loader返回nil, nil表示记录不存在；loader返回错误时不写入缓存
+// 返回的是缓存中的值的浅拷贝，修改返回值不会影响缓存
+func (c *Cache[T]) Get(ctx context.Context, key string, loader func(ctx context.Context) (*T, error)) (*T, error) {
+	if c.local != nil {
+		if v, ok := c.local.get(key); ok {
+			c.observe(metrics.CacheLocalHit)
+			return c.result(v.(*T))
+		}
+	}
+	if c.opts.Bloom != nil {
+		// 布隆过滤器出错时不拦截
+		if ok, _ := c.opts.Bloom.MightContain(ctx, key); !ok {
+			c.observe(metrics.CacheBloomReject)
+			return nil, constants.RecordNotExistErr
+		}
+	}
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		return c.load(ctx, key, loader)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.result(v.(*T))
+}
+
+// observe 按key的前缀统计查询结果，合并的并发加载只统计一次
+func (c *Cache[T]) observe(result string) {
+	metrics.CacheRequests.WithLabelValues(c.opts.Prefix, result).Inc()
+}
+
+func (c *Cache[T]) result(value *T) (*T, error) {
+	if value == nil {
+		return nil, constants.RecordNotExistErr
+	}
+	copied := *value
+	return &copied, nil
+}
+
+// load 先查询redis，未命中时调用loader并回写；redis不可用时直接调用loader，不回写
+func (c *Cache[T]) load(ctx context.Context, key string, loader func(ctx context.Context) (*T, error)) (*T, error) {
+	data, err := c.client.Get(ctx, c.opts.Prefix+key).Result()
+	if err == nil {
+		if data == EmptyCache {
+			c.observe(metrics.CacheHit)
+			c.setLocal(key, nil)
+			return nil, nil
+		}
+		var value T
+		if err = json.Unmarshal([]byte(data), &value); err == nil {
+			c.observe(metrics.CacheHit)
+			c.setLocal(key, &value)
+			return &value, nil
+		}
+		// 无法解析的缓存视为未命中，由loader的结果覆盖
+	} else if err != redis.Nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to get cache %v", c.opts.Prefix+key)
+		c.observe(metrics.CacheMiss)
+		return loader(ctx)
+	}
+	c.observe(metrics.CacheMiss)
+	value, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Set(ctx, key, value); err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to set cache %v", c.opts.Prefix+key)
+	}
+	return value, nil
+}
+
+// Set 写入缓存，value为nil时写入空缓存
+func (c *Cache[T]) Set(ctx context.Context, key string, value *T) error {
+	c.setLocal(key, value)
+	if value == nil {
+		return c.client.Set(ctx, c.opts.Prefix+key, EmptyCache, JitterTTL(c.opts.EmptyTTL, c.opts.Jitter)).Err()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.opts.Prefix+key, data, JitterTTL(c.opts.TTL, c.opts.Jitter)).Err()
+}
+
+// Delete 删除缓存，下一次Get时重新加载
+func (c *Cache[T]) Delete(ctx context.Context, key string) error {
+	if c.local != nil {
+		c.local.delete(key)
+	}
+	return c.client.Del(ctx, c.opts.Prefix+key).Err()
+}
+
+// DeleteDelayed 延时双删：立即删除缓存，并在delay之后再删除一次
+// 用于删除与数据库提交并发的读请求回写的旧值，delay应大于一次读数据库并回写缓存的耗时
+func (c *Cache[T]) DeleteDelayed(ctx context.Context, key string, delay time.Duration) error {
+	err := c.Delete(ctx, key)
+	time.AfterFunc(delay, func() {
+		if err := c.Delete(context.Background(), key); err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("fail to delete cache %v again", c.opts.Prefix+key)
+		}
+	})
+	return err
+}
+
+func (c *Cache[T]) setLocal(key string, value *T) {
+	if c.local == nil {
+		return
+	}
+	if value != nil {
+		copied := *value
+		value = &copied
+	}
+	c.local.set(key, value, c.opts.LocalTTL)
+}
@@ -0,0 +1,266 @@
+package cacheUtils
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	Id   int64
+	Name string
+}
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return mr, client
+}
+
+// countingLoader 记录被调用的次数，id为0的用户不存在
+func countingLoader(calls *int32, delay time.Duration, id int64) func(ctx context.Context) (*testUser, error) {
+	return func(ctx context.Context) (*testUser, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		if id == 0 {
+			return nil, nil
+		}
+		return &testUser{Id: id, Name: "user" + strconv.FormatInt(id, 10)}, nil
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	_, client := newTestClient(t)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute})
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := cache.Get(context.Background(), "1", countingLoader(&calls, 50*time.Millisecond, 1))
+			if err != nil || user.Name != "user1" {
+				t.Errorf("Get = %+v, %v", user, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("loader called %d times, want 1", calls)
+	}
+	// 之后从redis中读取
+	if _, err := cache.Get(context.Background(), "1", countingLoader(&calls, 0, 1)); err != nil || calls != 1 {
+		t.Fatalf("Get from redis: err = %v, loader called %d times", err, calls)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	mr, client := newTestClient(t)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute})
+	var calls int32
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(context.Background(), "0", countingLoader(&calls, 0, 0)); !errors.Is(constants.RecordNotExistErr, err) {
+			t.Fatalf("Get = %v, want RecordNotExistErr", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("loader called %d times, want 1", calls)
+	}
+	if v, _ := mr.Get("u_0"); v != EmptyCache {
+		t.Fatalf("redis value = %q, want empty cache", v)
+	}
+	// 删除空缓存后重新加载
+	cache.Delete(context.Background(), "0")
+	cache.Get(context.Background(), "0", countingLoader(&calls, 0, 0))
+	if calls != 2 {
+		t.Fatalf("loader called %d times after Delete, want 2", calls)
+	}
+}
+
+func TestCacheLoaderError(t *testing.T) {
+	mr, client := newTestClient(t)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute})
+	loadErr := errors.New("db down")
+	_, err := cache.Get(context.Background(), "1", func(ctx context.Context) (*testUser, error) {
+		return nil, loadErr
+	})
+	if err != loadErr {
+		t.Fatalf("Get = %v, want loader error", err)
+	}
+	if mr.Exists("u_1") {
+		t.Fatalf("loader error should not be cached")
+	}
+}
+
+func TestCacheLocalTier(t *testing.T) {
+	mr, client := newTestClient(t)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute, LocalSize: 10, LocalTTL: time.Minute})
+	var calls int32
+	user, _ := cache.Get(context.Background(), "1", countingLoader(&calls, 0, 1))
+	// 修改返回值不影响缓存
+	user.Name = "changed"
+	// redis不可用时仍能从进程内缓存读取
+	mr.Close()
+	user, err := cache.Get(context.Background(), "1", countingLoader(&calls, 0, 1))
+	if err != nil || user.Name != "user1" || calls != 1 {
+		t.Fatalf("Get from local = %+v, %v, loader called %d times", user, err, calls)
+	}
+}
+
+func TestCacheRedisDownFallsBackToLoader(t *testing.T) {
+	mr, client := newTestClient(t)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute})
+	mr.Close()
+	var calls int32
+	user, err := cache.Get(context.Background(), "1", countingLoader(&calls, 0, 1))
+	if err != nil || user.Id != 1 {
+		t.Fatalf("Get = %+v, %v", user, err)
+	}
+}
+
+func TestCacheBloomGuard(t *testing.T) {
+	_, client := newTestClient(t)
+	bloom := NewBloomFilter(client, "bloom_test", 1000, 0.01)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute, Bloom: bloom})
+	ctx := context.Background()
+	var calls int32
+
+	// 加载完成之前不拦截
+	if _, err := cache.Get(ctx, "2", countingLoader(&calls, 0, 2)); err != nil {
+		t.Fatalf("Get before bloom ready: %v", err)
+	}
+	err := bloom.Load(ctx, func(add func(items ...string) error) error {
+		return add("1", "2")
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if ready, _ := bloom.Ready(ctx); !ready {
+		t.Fatalf("bloom filter not ready after Load")
+	}
+	calls = 0
+	if _, err = cache.Get(ctx, "1", countingLoader(&calls, 0, 1)); err != nil || calls != 1 {
+		t.Fatalf("Get existing: err = %v, loader called %d times", err, calls)
+	}
+	if _, err = cache.Get(ctx, "999999", countingLoader(&calls, 0, 999999)); !errors.Is(constants.RecordNotExistErr, err) {
+		t.Fatalf("Get filtered = %v, want RecordNotExistErr", err)
+	}
+	if calls != 1 {
+		t.Fatalf("loader called for filtered key")
+	}
+}
+
+func TestBloomFilterFalsePositiveRate(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+	bloom := NewBloomFilter(client, "bloom_test", 2000, 0.01)
+	items := make([]string, 2000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+	if err := bloom.Load(ctx, func(add func(items ...string) error) error {
+		return add(items...)
+	}); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, item := range items {
+		if ok, _ := bloom.MightContain(ctx, item); !ok {
+			t.Fatalf("added item %v not found", item)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 2000; i++ {
+		if ok, _ := bloom.MightContain(ctx, "absent_"+strconv.Itoa(i)); ok {
+			falsePositives++
+		}
+	}
+	if falsePositives > 100 {
+		t.Fatalf("%d false positives in 2000 lookups", falsePositives)
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := newLRU(2)
+	c.set("a", 1, time.Minute)
+	c.set("b", 2, time.Minute)
+	c.get("a")
+	c.set("c", 3, time.Minute)
+	if _, ok := c.get("b"); ok {
+		t.Fatalf("least recently used item not evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("recently used item evicted")
+	}
+	c.set("d", 4, -time.Second)
+	if _, ok := c.get("d"); ok {
+		t.Fatalf("expired item returned")
+	}
+}
+
+func TestCacheDeleteDelayed(t *testing.T) {
+	mr, client := newTestClient(t)
+	cache := NewCache[testUser](client, Options{Prefix: "u_", TTL: time.Minute, EmptyTTL: time.Minute, LocalSize: 10, LocalTTL: time.Minute})
+	ctx := context.Background()
+	var calls int32
+	cache.Get(ctx, "1", countingLoader(&calls, 0, 1))
+	if err := cache.DeleteDelayed(ctx, "1", 100*time.Millisecond); err != nil {
+		t.Fatalf("DeleteDelayed: %v", err)
+	}
+	// 删除后、数据库提交前的读请求回写了旧值
+	cache.Set(ctx, "1", &testUser{Id: 1, Name: "stale"})
+	time.Sleep(200 * time.Millisecond)
+	if mr.Exists("u_1") {
+		t.Fatalf("stale value not deleted by the second delete")
+	}
+	user, err := cache.Get(ctx, "1", countingLoader(&calls, 0, 1))
+	if err != nil || user.Name != "user1" || calls != 2 {
+		t.Fatalf("Get after DeleteDelayed = %+v, %v, loader called %d times", user, err, calls)
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	_, client := newTestClient(t)
+	bloom := NewBloomFilter(client, "bloom_metrics", 1000, 0.01)
+	bloom.Load(context.Background(), func(add func(items ...string) error) error {
+		return add("1")
+	})
+	cache := NewCache[testUser](client, Options{Prefix: "metrics_", TTL: time.Minute, EmptyTTL: time.Minute,
+		LocalSize: 10, LocalTTL: time.Minute, Bloom: bloom})
+	other := NewCache[testUser](client, Options{Prefix: "metrics_other_", TTL: time.Minute, EmptyTTL: time.Minute})
+	ctx := context.Background()
+	var calls int32
+	cache.Get(ctx, "1", countingLoader(&calls, 0, 1))
+	cache.Get(ctx, "1", countingLoader(&calls, 0, 1))
+	cache.Get(ctx, "2", countingLoader(&calls, 0, 2))
+	other.Get(ctx, "1", countingLoader(&calls, 0, 1))
+	other.Get(ctx, "1", countingLoader(&calls, 0, 1))
+
+	for _, c := range []struct {
+		prefix, result string
+		want           float64
+	}{
+		{"metrics_", metrics.CacheMiss, 1},
+		{"metrics_", metrics.CacheLocalHit, 1},
+		{"metrics_", metrics.CacheBloomReject, 1},
+		{"metrics_", metrics.CacheHit, 0},
+		{"metrics_other_", metrics.CacheMiss, 1},
+		{"metrics_other_", metrics.CacheHit, 1},
+	} {
+		if got := testutil.ToFloat64(metrics.CacheRequests.WithLabelValues(c.prefix, c.result)); got != c.want {
+			t.Errorf("cache_requests_total{prefix=%q,result=%q} = %v, want %v", c.prefix, c.result, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cacheUtils
+
+import "sync"
+
+// call 一次正在进行或已经完成的调用
+type call struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+}
+
+// Group 协程共享调用，相同key的并发请求只有第一个真正执行，其他请求等待并共享它的结果
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do 执行fn并返回结果，shared表示结果是否来自其他请求发起的调用
+func (g *Group) Do(key string, fn func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.value, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	// fn发生panic时也要唤醒等待者，避免等待者永远阻塞
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.err = errPanicked
+	c.value, c.err = fn()
+	return c.value, c.err, false
+}
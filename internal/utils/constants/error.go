@@ -9,6 +9,10 @@ var (
 	InvalidTokenErr      = errors.New(api.ErrorCodeToMsg[api.TokenInvalidErr])
 	NoVideoErr           = errors.New(api.ErrorCodeToMsg[api.NoVideoErr])
 	UnKnownActionTypeErr = errors.New(api.ErrorCodeToMsg[api.UnKnownActionType])
+	InvalidCursorErr     = errors.New(api.ErrorCodeToMsg[api.InvalidCursorErr])
+	PermissionDeniedErr  = errors.New(api.ErrorCodeToMsg[api.PermissionDeniedErr])
+	InputFormatCheckErr  = errors.New(api.ErrorCodeToMsg[api.InputFormatCheckErr])
+	AccountLockedErr     = errors.New(api.ErrorCodeToMsg[api.AccountLockedErr])
 
 	UserNotExistErr       = errors.New(api.ErrorCodeToMsg[api.UserNotExistErr])
 	UserAlreadyExistErr   = errors.New(api.ErrorCodeToMsg[api.UserAlreadyExistErr])
@@ -21,6 +25,7 @@ var (
 	KafkaServerErr        = errors.New(api.ErrorCodeToMsg[api.KafkaServerErr])
 	KafkaClientErr        = errors.New(api.ErrorCodeToMsg[api.KafkaClientErr])
 	CreateDataErr         = errors.New(api.ErrorCodeToMsg[api.CreateDataErr])
+	ServiceUnavailableErr = errors.New(api.ErrorCodeToMsg[api.ServiceUnavailableErr])
 
 	VideoFormatErr = errors.New(api.ErrorCodeToMsg[api.VideoFormationErr])
 	VideoSizeErr   = errors.New(api.ErrorCodeToMsg[api.VideoSizeErr])
@@ -1,14 +1,24 @@
 package cronUtils
 
 import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
 	"github.com/robfig/cron/v3"
 )
 
 //CronLab 分布式定时任务所使用的组件
 var CronLab *cron.Cron
 
-// InitCron 启动分布式定时任务
+// InitCron 启动分布式定时任务，关闭时不再调度新的任务，并等待正在运行的任务完成
 func InitCron() {
 	CronLab = cron.New()
 	CronLab.Start()
+	lifecycle.OnShutdown(lifecycle.PhaseFlush, "cron", func(ctx context.Context) error {
+		select {
+		case <-CronLab.Stop().Done():
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
 }
@@ -0,0 +1,263 @@
+package cronUtils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/redisUtils"
+	"github.com/go-redis/redis/v8"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	jobRegistryKey    = "cron_jobs"         // 已注册的定时任务，hash，field为任务名
+	jobLeaderKey      = "cron_leader"       // 定时任务的leader，值为实例名
+	jobHistoryPrefix  = "cron_job_history_" // 任务的运行记录，list，最新的在最前
+	jobTriggerPrefix  = "cron_job_trigger_" // 手动触发任务的请求
+	jobLockPrefix     = "cron_job_lock_"    // 任务运行时持有的分布式锁
+	jobTriggerExpire  = 10 * time.Minute
+	JobTriggerCron    = "cron"
+	JobTriggerManual  = "manual"
+	renewLeaderScript = `
+if redis.call("get",KEYS[1]) == ARGV[1] then
+    return redis.call("pexpire",KEYS[1],ARGV[2])
+else
+    return 0
+end`
+)
+
+// JobInfo 定时任务的注册信息以及最近一次运行记录
+type JobInfo struct {
+	Name         string  `json:"name"`
+	Spec         string  `json:"spec"`
+	Instance     string  `json:"instance"`      // 最近注册该任务的实例
+	RegisteredAt int64   `json:"registered_at"` // 注册的时间(毫秒)
+	Running      bool    `json:"running"`       // 是否有实例正在运行该任务
+	LastRun      *JobRun `json:"last_run,omitempty"`
+}
+
+// JobRun 定时任务的一次运行记录
+type JobRun struct {
+	Name      string `json:"name"`
+	Instance  string `json:"instance"`
+	Trigger   string `json:"trigger"`    // cron或manual
+	StartedAt int64  `json:"started_at"` // 开始的时间(毫秒)
+	Duration  int64  `json:"duration"`   // 耗时(毫秒)
+	Error     string `json:"error,omitempty"`
+}
+
+type job struct {
+	name string
+	spec string
+	run  func() error
+}
+
+// JobRegistry 分布式定时任务的注册表
+// 定时触发只在leader实例上运行，leader通过redis中带有效期的租约选举；
+// 每次运行还需要取得任务的分布式锁，因此手动触发与定时触发、新旧leader之间都不会同时运行同一任务
+type JobRegistry struct {
+	client   *redis.Client
+	locker   *redisUtils.Locker
+	renew    *redis.Script
+	instance string
+	leader   int32
+	mu       sync.RWMutex
+	jobs     map[string]*job
+	loopOnce sync.Once
+}
+
+// NewJobRegistry 创建一个使用client保存任务信息的注册表
+func NewJobRegistry(client *redis.Client) *JobRegistry {
+	hostname, _ := os.Hostname()
+	return &JobRegistry{
+		client:   client,
+		locker:   redisUtils.NewDefaultLocker(client),
+		renew:    redis.NewScript(renewLeaderScript),
+		instance: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		jobs:     make(map[string]*job),
+	}
+}
+
+// Register 注册一个定时任务，并开始参与leader选举以及处理手动触发的请求
+func (r *JobRegistry) Register(name, spec string, run func() error) error {
+	j := &job{name: name, spec: spec, run: run}
+	if _, err := CronLab.AddFunc(spec, func() {
+		if atomic.LoadInt32(&r.leader) == 1 {
+			r.runJob(j, JobTriggerCron)
+		}
+	}); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.jobs[name] = j
+	r.mu.Unlock()
+
+	value, _ := json.Marshal(&JobInfo{Name: name, Spec: spec, Instance: r.instance, RegisteredAt: time.Now().UnixMilli()})
+	if err := r.client.HSet(context.Background(), jobRegistryKey, name, value).Err(); err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to save job %v to registry", name)
+	}
+	r.loopOnce.Do(func() {
+		go r.loop()
+	})
+	return nil
+}
+
+// loop 定时续约或竞选leader，并处理本实例注册过的任务的手动触发请求
+func (r *JobRegistry) loop() {
+	ticker := time.NewTicker(time.Duration(initialization.JobConf.PollInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		r.elect()
+		r.mu.RLock()
+		jobs := make([]*job, 0, len(r.jobs))
+		for _, j := range r.jobs {
+			jobs = append(jobs, j)
+		}
+		r.mu.RUnlock()
+		for _, j := range jobs {
+			// 删除成功的实例负责运行，同一请求只会被一个实例取走
+			n, err := r.client.Del(context.Background(), jobTriggerPrefix+j.name).Result()
+			if err == nil && n == 1 {
+				go r.runJob(j, JobTriggerManual)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// elect 已是leader时续约，否则在租约过期后尝试成为leader
+func (r *JobRegistry) elect() {
+	ctx := context.Background()
+	lease := time.Duration(initialization.JobConf.LeaderLease) * time.Second
+	renewed, err := r.renew.Run(ctx, r.client, []string{jobLeaderKey}, r.instance, lease.Milliseconds()).Int()
+	if err == nil && renewed == 1 {
+		atomic.StoreInt32(&r.leader, 1)
+		return
+	}
+	ok, err := r.client.SetNX(ctx, jobLeaderKey, r.instance, lease).Result()
+	if err != nil {
+		// 无法确认租约时放弃leader，宁可少运行一次也不重复运行
+		atomic.StoreInt32(&r.leader, 0)
+		return
+	}
+	if ok {
+		logger.GlobalLogger.Info().Msgf("instance %v becomes the leader of cron jobs", r.instance)
+		atomic.StoreInt32(&r.leader, 1)
+	} else {
+		atomic.StoreInt32(&r.leader, 0)
+	}
+}
+
+// runJob 取得任务的分布式锁后运行任务并记录运行结果，任务正在其他实例上运行时跳过
+func (r *JobRegistry) runJob(j *job, trigger string) {
+	ctx := context.Background()
+	lock := r.locker.GetLock(jobLockPrefix + j.name)
+	if err := lock.TryLock(ctx); err != nil {
+		if !errors.Is(err, constants.LockFailedErr) {
+			logger.GlobalLogger.Error().Err(err).Msgf("fail to lock job %v", j.name)
+		}
+		return
+	}
+	defer lock.UnLock(ctx)
+
+	logger.GlobalLogger.Debug().Msgf("In %v, trigger = %v", j.name, trigger)
+	start := time.Now()
+	err := j.run()
+	run := &JobRun{
+		Name:      j.name,
+		Instance:  r.instance,
+		Trigger:   trigger,
+		StartedAt: start.UnixMilli(),
+		Duration:  time.Since(start).Milliseconds(),
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+		run.Error = err.Error()
+		logger.GlobalLogger.Error().Err(err).Msgf("error occurs in %v", j.name)
+	}
+	metrics.CronRuns.WithLabelValues(j.name, trigger, result).Inc()
+	metrics.CronDuration.WithLabelValues(j.name).Observe(metrics.Since(start))
+	value, _ := json.Marshal(run)
+	pipe := r.client.TxPipeline()
+	pipe.LPush(ctx, jobHistoryPrefix+j.name, value)
+	pipe.LTrim(ctx, jobHistoryPrefix+j.name, 0, int64(initialization.JobConf.HistoryLength-1))
+	if _, err = pipe.Exec(ctx); err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to save history of job %v", j.name)
+	}
+}
+
+// ListJobs 获取所有实例注册过的任务，以及当前的leader
+func (r *JobRegistry) ListJobs() ([]JobInfo, string, error) {
+	ctx := context.Background()
+	values, err := r.client.HGetAll(ctx, jobRegistryKey).Result()
+	if err != nil {
+		return nil, "", constants.RedisDBErr
+	}
+	jobs := make([]JobInfo, 0, len(values))
+	for _, value := range values {
+		var info JobInfo
+		if err = json.Unmarshal([]byte(value), &info); err != nil {
+			continue
+		}
+		if history, err := r.JobHistory(info.Name, 1); err == nil && len(history) > 0 {
+			info.LastRun = &history[0]
+		}
+		running, err := r.client.Exists(ctx, jobLockPrefix+info.Name).Result()
+		if err != nil {
+			return nil, "", constants.RedisDBErr
+		}
+		info.Running = running == 1
+		jobs = append(jobs, info)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].Name < jobs[k].Name
+	})
+	leader, err := r.client.Get(ctx, jobLeaderKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, "", constants.RedisDBErr
+	}
+	return jobs, leader, nil
+}
+
+// JobHistory 获取任务最近limit次的运行记录，最新的在最前
+func (r *JobRegistry) JobHistory(name string, limit int) ([]JobRun, error) {
+	values, err := r.client.LRange(context.Background(), jobHistoryPrefix+name, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, constants.RedisDBErr
+	}
+	history := make([]JobRun, 0, len(values))
+	for _, value := range values {
+		var run JobRun
+		if err = json.Unmarshal([]byte(value), &run); err == nil {
+			history = append(history, run)
+		}
+	}
+	return history, nil
+}
+
+// TriggerJob 请求立即运行一次任务，由注册过该任务的某一个实例在下一次轮询时运行
+// 可能返回的错误类型：RecordNotExistErr, RedisDBErr
+func (r *JobRegistry) TriggerJob(name string) error {
+	ctx := context.Background()
+	exists, err := r.client.HExists(ctx, jobRegistryKey, name).Result()
+	if err != nil {
+		return constants.RedisDBErr
+	}
+	if !exists {
+		return constants.RecordNotExistErr
+	}
+	if err = r.client.Set(ctx, jobTriggerPrefix+name, time.Now().UnixMilli(), jobTriggerExpire).Err(); err != nil {
+		return constants.RedisDBErr
+	}
+	return nil
+}
@@ -1,15 +1,60 @@
 package idGenerator
 
-import "github.com/google/uuid"
+import (
+	"context"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// 每种实体使用单独的生成器，序列号互不影响
+var (
+	videoIdGenerator   *Snowflake
+	userIdGenerator    *Snowflake
+	messageIdGenerator *Snowflake
+)
+
+// InitIdGenerator 按配置初始化各实体的ID生成器
+// 配置的WorkerId为-1时通过client租用机器ID并在后台续约，多个实例部署时需保证机器ID不重复
+func InitIdGenerator(config initialization.IdGeneratorConfig, client *redis.Client) {
+	workerId := config.WorkerId
+	var lease *WorkerLease
+	if workerId < 0 {
+		lease = NewWorkerLease(client, time.Duration(config.WorkerLease)*time.Second, func(workerId int64) {
+			for _, generator := range []*Snowflake{videoIdGenerator, userIdGenerator, messageIdGenerator} {
+				_ = generator.SetWorkerId(workerId)
+			}
+		})
+		var err error
+		if workerId, err = lease.Acquire(context.Background()); err != nil {
+			panic(err)
+		}
+	}
+	maxBackward := time.Duration(config.MaxBackward) * time.Millisecond
+	generators := make([]*Snowflake, 3)
+	for i := range generators {
+		generator, err := NewSnowflake(config.Epoch, workerId, maxBackward)
+		if err != nil {
+			panic(err)
+		}
+		generators[i] = generator
+	}
+	videoIdGenerator, userIdGenerator, messageIdGenerator = generators[0], generators[1], generators[2]
+	if lease != nil {
+		go lease.KeepAlive(context.Background())
+	}
+	logger.GlobalLogger.Info().Msgf("id generator uses worker id %v", workerId)
+}
 
 func GenerateVideoId() int64 {
-	return int64(uuid.New().ID())
+	return videoIdGenerator.NextId()
 }
 
 func GenerateUserId() int64 {
-	return int64(uuid.New().ID())
+	return userIdGenerator.NextId()
 }
 
 func GenerateMessageId() int64 {
-	return int64(uuid.New().ID())
+	return messageIdGenerator.NextId()
 }
@@ -0,0 +1,98 @@
+package idGenerator
+
+import (
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 雪花ID的组成：1位符号位(恒为0)、41位毫秒时间戳、10位机器ID、12位序列号
+const (
+	workerIdBits   = 10
+	sequenceBits   = 12
+	MaxWorkerId    = 1<<workerIdBits - 1
+	maxSequence    = 1<<sequenceBits - 1
+	timestampShift = workerIdBits + sequenceBits
+)
+
+var ErrInvalidWorkerId = errors.New("worker id out of range")
+
+// Snowflake 雪花ID生成器，同一个实例生成的ID严格递增，不同机器ID的实例生成的ID不会重复
+type Snowflake struct {
+	epoch       int64 // 时间戳的起点(毫秒)
+	maxBackward int64 // 等待时钟回拨的最大时长(毫秒)
+	workerId    int64
+	now         func() int64
+
+	mu            sync.Mutex
+	lastTimestamp int64
+	sequence      int64
+	rolledBack    bool // 是否正处于时钟回拨中，用于只在回拨开始时打印一次日志
+}
+
+// NewSnowflake 创建一个雪花ID生成器
+func NewSnowflake(epoch time.Time, workerId int64, maxBackward time.Duration) (*Snowflake, error) {
+	if workerId < 0 || workerId > MaxWorkerId {
+		return nil, ErrInvalidWorkerId
+	}
+	return &Snowflake{
+		epoch:       epoch.UnixMilli(),
+		maxBackward: maxBackward.Milliseconds(),
+		workerId:    workerId,
+		now: func() int64 {
+			return time.Now().UnixMilli()
+		},
+	}, nil
+}
+
+// SetWorkerId 更换机器ID，用于租用的机器ID丢失后重新租用
+func (s *Snowflake) SetWorkerId(workerId int64) error {
+	if workerId < 0 || workerId > MaxWorkerId {
+		return ErrInvalidWorkerId
+	}
+	atomic.StoreInt64(&s.workerId, workerId)
+	return nil
+}
+
+// NextId 生成下一个ID
+// 时钟回拨不超过maxBackward时等待时钟追上；超过时不等待，继续使用上一次的时间戳(逻辑时钟)，ID仍然唯一且递增
+// 同一毫秒内序列号用尽时，同样将逻辑时钟向前推进一毫秒，之后的调用会等待真实时钟追上
+func (s *Snowflake) NextId() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now < s.lastTimestamp {
+		if backward := s.lastTimestamp - now; backward <= s.maxBackward {
+			time.Sleep(time.Duration(backward) * time.Millisecond)
+			now = s.now()
+		}
+		if now < s.lastTimestamp {
+			if !s.rolledBack {
+				logger.GlobalLogger.Warn().Msgf("clock moved backwards by %v ms, keep using the last timestamp", s.lastTimestamp-now)
+				s.rolledBack = true
+			}
+			now = s.lastTimestamp
+		}
+	} else {
+		s.rolledBack = false
+	}
+
+	if now == s.lastTimestamp {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 {
+			// 序列号用尽
+			now++
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.lastTimestamp = now
+	return (now-s.epoch)<<timestampShift | atomic.LoadInt64(&s.workerId)<<sequenceBits | s.sequence
+}
+
+// TimeOf 获取ID中记录的生成时间，可用于按时间范围的分页
+func (s *Snowflake) TimeOf(id int64) time.Time {
+	return time.UnixMilli(id>>timestampShift + s.epoch)
+}
@@ -0,0 +1,146 @@
+package idGenerator
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testEpoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSnowflakeUniqueAndOrdered(t *testing.T) {
+	s, err := NewSnowflake(testEpoch, 1, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSnowflake: %v", err)
+	}
+	const workers, perWorker = 8, 5000
+	ids := make(chan int64, workers*perWorker)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := int64(0)
+			for k := 0; k < perWorker; k++ {
+				id := s.NextId()
+				if id <= last {
+					t.Errorf("id %v not greater than previous %v", id, last)
+					return
+				}
+				last = id
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	seen := make(map[int64]bool, workers*perWorker)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate id %v", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSnowflakeLayout(t *testing.T) {
+	s, _ := NewSnowflake(testEpoch, MaxWorkerId, 0)
+	now := time.UnixMilli(testEpoch.UnixMilli() + 12345)
+	s.now = func() int64 {
+		return now.UnixMilli()
+	}
+	id := s.NextId()
+	if got := (id >> sequenceBits) & MaxWorkerId; got != MaxWorkerId {
+		t.Fatalf("worker id = %v, want %v", got, MaxWorkerId)
+	}
+	if got := s.TimeOf(id); !got.Equal(now) {
+		t.Fatalf("TimeOf = %v, want %v", got, now)
+	}
+	if _, err := NewSnowflake(testEpoch, MaxWorkerId+1, 0); err != ErrInvalidWorkerId {
+		t.Fatalf("NewSnowflake with invalid worker id = %v, want ErrInvalidWorkerId", err)
+	}
+}
+
+func TestSnowflakeClockRollback(t *testing.T) {
+	s, _ := NewSnowflake(testEpoch, 1, 0)
+	clock := testEpoch.UnixMilli() + 1000
+	s.now = func() int64 {
+		return clock
+	}
+	first := s.NextId()
+	// 时钟回拨超过允许等待的时长，继续使用上一次的时间戳
+	clock -= 500
+	second := s.NextId()
+	if second <= first {
+		t.Fatalf("id after rollback %v not greater than %v", second, first)
+	}
+	if !s.TimeOf(second).Equal(s.TimeOf(first)) {
+		t.Fatalf("id after rollback uses timestamp %v, want %v", s.TimeOf(second), s.TimeOf(first))
+	}
+	// 序列号用尽时推进逻辑时钟
+	last := second
+	for i := 0; i < maxSequence+1; i++ {
+		id := s.NextId()
+		if id <= last {
+			t.Fatalf("id %v not greater than previous %v", id, last)
+		}
+		last = id
+	}
+	if !s.TimeOf(last).After(s.TimeOf(first)) {
+		t.Fatalf("logical clock not advanced after sequence exhausted")
+	}
+}
+
+func TestWorkerLease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	first := NewWorkerLease(client, time.Minute, nil)
+	second := NewWorkerLease(client, time.Minute, nil)
+	id1, err := first.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	id2, err := second.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("second Acquire: %v", err)
+	}
+	if id1 == id2 {
+		t.Fatalf("two leases got the same worker id %v", id1)
+	}
+}
+
+func TestWorkerLeaseReacquireAfterLost(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan int64, 1)
+	lease := NewWorkerLease(client, 300*time.Millisecond, func(workerId int64) {
+		changed <- workerId
+	})
+	lost, err := lease.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	go lease.KeepAlive(ctx)
+	// 租约过期后被其他实例占用
+	mr.Set(workerKeyPrefix+strconv.FormatInt(lost, 10), "other")
+
+	select {
+	case workerId := <-changed:
+		if workerId == lost {
+			t.Fatalf("reacquired the lost worker id %v", lost)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("worker id not changed after lease lost")
+	}
+}
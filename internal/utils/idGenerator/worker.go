@@ -0,0 +1,97 @@
+package idGenerator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/go-redis/redis/v8"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	workerKeyPrefix = "id_worker_" // 已被租用的机器ID，值为租用的实例
+	renewScript     = `
+if redis.call("get",KEYS[1]) == ARGV[1] then
+    return redis.call("pexpire",KEYS[1],ARGV[2])
+else
+    return 0
+end`
+)
+
+var ErrNoFreeWorkerId = errors.New("no free worker id")
+
+// WorkerLease 通过redis租用的机器ID，租约到期前定时续约
+type WorkerLease struct {
+	client   *redis.Client
+	renew    *redis.Script
+	instance string
+	lease    time.Duration
+	workerId int64
+	onChange func(workerId int64) // 机器ID丢失并重新租用后的回调
+}
+
+// NewWorkerLease 创建一个租约，lease为租约的有效期
+func NewWorkerLease(client *redis.Client, lease time.Duration, onChange func(workerId int64)) *WorkerLease {
+	hostname, _ := os.Hostname()
+	return &WorkerLease{
+		client:   client,
+		renew:    redis.NewScript(renewScript),
+		instance: fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), rand.Int63()),
+		lease:    lease,
+		onChange: onChange,
+	}
+}
+
+// Acquire 从随机位置开始依次尝试租用空闲的机器ID
+func (w *WorkerLease) Acquire(ctx context.Context) (int64, error) {
+	start := rand.Int63n(MaxWorkerId + 1)
+	for i := int64(0); i <= MaxWorkerId; i++ {
+		workerId := (start + i) % (MaxWorkerId + 1)
+		ok, err := w.client.SetNX(ctx, workerKeyPrefix+strconv.FormatInt(workerId, 10), w.instance, w.lease).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			w.workerId = workerId
+			return workerId, nil
+		}
+	}
+	return 0, ErrNoFreeWorkerId
+}
+
+// KeepAlive 每隔租约的1/3续约一次，直到ctx结束
+// 租约已被其他实例占用时(redis长时间不可用导致租约过期)重新租用新的机器ID并通知onChange
+func (w *WorkerLease) KeepAlive(ctx context.Context) {
+	ticker := time.NewTicker(w.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		key := workerKeyPrefix + strconv.FormatInt(w.workerId, 10)
+		n, err := w.renew.Run(ctx, w.client, []string{key}, w.instance, w.lease.Milliseconds()).Int()
+		if err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("fail to renew worker id %v", w.workerId)
+			continue
+		}
+		if n == 1 {
+			continue
+		}
+		// 租约已经丢失
+		workerId, err := w.Acquire(ctx)
+		if err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("lost worker id %v and fail to acquire a new one", w.workerId)
+			continue
+		}
+		logger.GlobalLogger.Warn().Msgf("lost worker id lease, switch to worker id %v", workerId)
+		if w.onChange != nil {
+			w.onChange(workerId)
+		}
+	}
+}
@@ -9,14 +9,17 @@ import (
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/loginGuard"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rateLimiter"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/rpcUtils"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/sessionUtils"
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/cloudwego/hertz/pkg/common/utils"
-	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/go-redis/redis/v8"
 	"github.com/hertz-contrib/jwt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 	"net/http"
 	"time"
@@ -25,6 +28,16 @@ import (
 var (
 	JwtMiddleware *jwt.HertzJWTMiddleware
 	IdentityKey   = "identity"
+
+	// SessionStore 登录会话，每次登录创建一个会话，token中携带会话ID
+	SessionStore *sessionUtils.Store
+	// Guard 记录登录失败次数，失败过多时锁定用户名或IP，并限制同一IP注册的频率
+	Guard *loginGuard.Guard
+
+	keys           *keySet
+	redisClient    *redis.Client
+	accessTimeout  time.Duration
+	refreshTimeout time.Duration
 )
 
 type UserStruct struct {
@@ -32,117 +45,128 @@ type UserStruct struct {
 	Password string `form:"password" json:"password" query:"password" vd:"(len($) > 0 && len($) < 128); msg:'Illegal format'"`
 }
 
-func LoginResponse(content context.Context, requestContext *app.RequestContext, token string) {
-	username := requestContext.Query("username")
-	password := requestContext.Query("password")
-
+// authenticate 通过用户名与密码获取用户，用户名或IP被锁定时不校验密码
+func authenticate(content context.Context, requestContext *app.RequestContext) (*model.User, error) {
+	var userStruct UserStruct
+	if err := requestContext.BindAndValidate(&userStruct); err != nil {
+		return nil, constants.InputFormatCheckErr
+	}
+	ip := requestContext.ClientIP()
+	remaining, err := Guard.Locked(content, userStruct.Username, ip)
+	if err != nil {
+		logger.Ctx(content).Error().Err(err).Msg("fail to check login lockout")
+	} else if remaining > 0 {
+		requestContext.Header("Retry-After", rateLimiter.RetryAfter(remaining))
+		return nil, constants.AccountLockedErr
+	}
 	address := initialization.RpcCSConf.UserServiceHost + initialization.RpcCSConf.UserServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(address, rpcUtils.DialOptions()...)
 	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+		logger.Ctx(content).Error().Err(err).Msg("did not connect")
 	}
 	defer conn.Close()
 	grpcClient := pbuser.NewUserServiceInfoClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(content, time.Second)
 	defer cancel()
 	userInfoResp, err := grpcClient.GetUserInfo(ctx, &pbuser.UserServicePost{
-		Username: username,
-		Password: password,
+		Username: userStruct.Username,
+		Password: userStruct.Password,
 	})
+	if errors.Is(status.Errorf(codes.NotFound, constants.UserNotExistErr.Error()), err) {
+		recordFailure(content, userStruct.Username, ip)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+	if err = Guard.Succeed(content, userStruct.Username); err != nil {
+		logger.Ctx(content).Error().Err(err).Msgf("fail to reset login failures of %v", userStruct.Username)
+	}
+	return &model.User{
+		UserID:        userInfoResp.Id,
+		UserName:      userInfoResp.Name,
+		FollowCount:   userInfoResp.FollowCnt,
+		FollowerCount: userInfoResp.FollowerCnt,
+	}, nil
+}
 
+// recordFailure 记录一次用户名或密码错误，并为导致的每次锁定记录审计日志
+func recordFailure(ctx context.Context, username, ip string) {
+	lockouts, err := Guard.Fail(ctx, username, ip)
 	if err != nil {
-		logger.GlobalLogger.Printf("Got error from RPC = %v", err)
-		if errors.Is(status.Errorf(codes.AlreadyExists, constants.UserAlreadyExistErr.Error()), err) {
-			requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
-				Response: api.Response{
-					StatusCode: int32(api.UserAlreadyExistErr),
-					StatusMsg:  api.ErrorCodeToMsg[api.UserAlreadyExistErr],
-				},
-			})
-		} else if errors.Is(status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error()), err) {
-			requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
-				Response: api.Response{
-					StatusCode: int32(api.InnerDataBaseErr),
-					StatusMsg:  api.ErrorCodeToMsg[api.InnerDataBaseErr],
-				},
-			})
-		} else {
-			requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
-				Response: api.Response{
-					StatusCode: int32(api.InnerConnectionErr),
-					StatusMsg:  api.ErrorCodeToMsg[api.InnerConnectionErr],
-				},
-			})
-		}
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to record login failure of %v", username)
 	}
+	for _, lockout := range lockouts {
+		logger.Ctx(ctx).Warn().
+			Str("audit", "login_lockout").
+			Str("subject", lockout.Subject).
+			Str("key", lockout.Key).
+			Str("username", username).
+			Str("ip", ip).
+			Dur("duration", lockout.Duration).
+			Msg("too many failed logins, locked")
+	}
+}
 
-	requestContext.JSON(consts.StatusOK, api.UserLoginResponse{
-		Response: api.Response{
-			StatusCode: 0,
-		},
-		UserId: userInfoResp.Id,
-		Token:  token,
-	})
+// errorResponse 将登录时的错误转换为返回的错误码
+func errorResponse(err error) api.Response {
+	errorType := api.InnerConnectionErr
+	if errors.Is(constants.InputFormatCheckErr, err) {
+		errorType = api.InputFormatCheckErr
+	} else if errors.Is(constants.AccountLockedErr, err) {
+		errorType = api.AccountLockedErr
+	} else if errors.Is(rpcUtils.ErrBreakerOpen, err) {
+		errorType = api.ServiceUnavailableErr
+	} else if errors.Is(status.Errorf(codes.NotFound, constants.UserNotExistErr.Error()), err) {
+		errorType = api.UserNotExistErr
+	} else if errors.Is(status.Errorf(codes.Internal, constants.InnerDataBaseErr.Error()), err) {
+		errorType = api.InnerDataBaseErr
+	}
+	return api.Response{
+		StatusCode: int32(errorType),
+		StatusMsg:  api.ErrorCodeToMsg[errorType],
+	}
 }
 
-func InitJwt() {
+// InitJwt 按配置初始化签发与校验token的密钥，client用于保存登录会话与已经轮换的refresh token
+// 登录、刷新与退出登录分别由LoginHandler、RefreshHandler与LogoutHandler处理，JwtMiddleware只用于校验access token
+func InitJwt(conf initialization.JwtConfig, client *redis.Client) {
 	var err error
+	if keys, err = newKeySet(conf); err != nil {
+		panic(err)
+	}
+	redisClient = client
+	accessTimeout = time.Duration(conf.AccessTimeout) * time.Minute
+	refreshTimeout = time.Duration(conf.RefreshTimeout) * time.Hour
+	SessionStore = sessionUtils.NewStore(client, refreshTimeout, conf.MaxSessions)
+	loginConf := initialization.LoginConf
+	Guard = loginGuard.NewGuard(client, loginGuard.Options{
+		UserMaxFailures: loginConf.UserMaxFailures,
+		IpMaxFailures:   loginConf.IpMaxFailures,
+		FailureWindow:   time.Duration(loginConf.FailureWindow) * time.Minute,
+		LockoutBase:     time.Duration(loginConf.LockoutBase) * time.Second,
+		LockoutMax:      time.Duration(loginConf.LockoutMax) * time.Second,
+		RegisterLimit:   loginConf.RegisterLimit,
+		RegisterWindow:  time.Duration(loginConf.RegisterWindow) * time.Minute,
+	})
 	JwtMiddleware, err = jwt.New(&jwt.HertzJWTMiddleware{
-		Realm:         "test zone",
-		Key:           []byte("secret key"),
-		Timeout:       time.Hour,
-		MaxRefresh:    time.Hour,
-		TokenLookup:   "header: Authorization, query: token, cookie: jwt",
-		TokenHeadName: "Bearer",
-		LoginResponse: func(ctx context.Context, c *app.RequestContext, code int, token string, expire time.Time) {
-			LoginResponse(ctx, c, token)
-		},
-		Authenticator: func(content context.Context, requestContext *app.RequestContext) (interface{}, error) {
-			var userStruct UserStruct
-			if err := requestContext.BindAndValidate(&userStruct); err != nil {
-				return nil, err
-			}
-			address := initialization.RpcCSConf.UserServiceHost + initialization.RpcCSConf.UserServicePort
-			conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
-			if err != nil {
-				logger.GlobalLogger.Printf("did not connect: %v", err)
-			}
-			defer conn.Close()
-			grpcClient := pbuser.NewUserServiceInfoClient(conn)
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-			userInfoResp, err := grpcClient.GetUserInfo(ctx, &pbuser.UserServicePost{
-				Username: userStruct.Username,
-				Password: userStruct.Password,
-			})
-			if err != nil {
-				return nil, err
-			}
-			userInfo := &model.User{
-				UserID:        userInfoResp.Id,
-				UserName:      userInfoResp.Name,
-				FollowCount:   userInfoResp.FollowCnt,
-				FollowerCount: userInfoResp.FollowerCnt,
-			}
-			if err != nil {
-				return nil, err
-			}
-			return userInfo, nil
-		},
-		IdentityKey: IdentityKey,
+		Realm:            "test zone",
+		SigningAlgorithm: conf.Algorithm,
+		KeyFunc:          keys.keyFunc,
+		Timeout:          accessTimeout,
+		TokenLookup:      "header: Authorization, query: token, cookie: jwt",
+		TokenHeadName:    "Bearer",
+		IdentityKey:      IdentityKey,
 		IdentityHandler: func(ctx context.Context, c *app.RequestContext) interface{} {
-			claims := jwt.ExtractClaims(ctx, c)
-			return &model.User{
-				UserName: claims[IdentityKey].(string),
+			if user := userFromClaims(jwt.ExtractClaims(ctx, c)); user != nil {
+				return user
 			}
+			return nil
 		},
-		PayloadFunc: func(data interface{}) jwt.MapClaims {
-			if v, ok := data.(*model.User); ok {
-				return jwt.MapClaims{
-					IdentityKey: v.UserName,
-				}
-			}
-			return jwt.MapClaims{}
+		// 只接受access token，且所属的会话没有失效
+		Authorizator: func(data interface{}, ctx context.Context, c *app.RequestContext) bool {
+			claims := jwt.ExtractClaims(ctx, c)
+			user, ok := data.(*model.User)
+			return ok && claimString(claims, claimType) == accessToken && sessionActive(ctx, c, user, claimString(claims, claimSession))
 		},
 		HTTPStatusMessageFunc: func(e error, ctx context.Context, c *app.RequestContext) string {
 			hlog.CtxErrorf(ctx, "jwt biz err = %+v", e.Error())
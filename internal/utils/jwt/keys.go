@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"errors"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/hertz-contrib/jwt"
+	"os"
+)
+
+var (
+	errUnknownKeyId      = errors.New("unknown key id")
+	errMissingSignKey    = errors.New("no key for the configured key id")
+	errUnsupportedKeyAlg = errors.New("unsupported signing algorithm, use HS256 or RS256")
+)
+
+// keySet 签发token使用的密钥与校验token使用的所有密钥，通过token头部的kid选择校验的密钥
+// 轮换密钥时新增一个kid并用于签发，旧的kid保留到用其签发的token全部过期
+type keySet struct {
+	method     gojwt.SigningMethod
+	kid        string
+	signKey    interface{}
+	verifyKeys map[string]interface{}
+}
+
+// newKeySet 按配置加载密钥，未配置密钥时随机生成一个，此时重启或多实例部署后之前签发的token失效
+func newKeySet(conf initialization.JwtConfig) (*keySet, error) {
+	k := &keySet{
+		method:     gojwt.GetSigningMethod(conf.Algorithm),
+		kid:        conf.KeyId,
+		verifyKeys: make(map[string]interface{}, len(conf.Keys)),
+	}
+	switch k.method.(type) {
+	case *gojwt.SigningMethodHMAC:
+		if len(conf.Keys) == 0 {
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			logger.GlobalLogger.Warn().Msg("no jwt key configured, use a random key, tokens become invalid after restart")
+			k.kid = "random"
+			k.verifyKeys[k.kid] = secret
+		}
+		for kid, secret := range conf.Keys {
+			k.verifyKeys[kid] = []byte(secret)
+		}
+		k.signKey = k.verifyKeys[k.kid]
+	case *gojwt.SigningMethodRSA:
+		for kid, file := range conf.Keys {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, jwt.ErrNoPubKeyFile
+			}
+			if k.verifyKeys[kid], err = gojwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+				return nil, jwt.ErrInvalidPubKey
+			}
+		}
+		data, err := os.ReadFile(conf.PrivateKeyFile)
+		if err != nil {
+			return nil, jwt.ErrNoPrivKeyFile
+		}
+		if k.signKey, err = gojwt.ParseRSAPrivateKeyFromPEM(data); err != nil {
+			return nil, jwt.ErrInvalidPrivKey
+		}
+		if _, ok := k.verifyKeys[k.kid]; !ok {
+			return nil, errMissingSignKey
+		}
+	default:
+		return nil, errUnsupportedKeyAlg
+	}
+	if k.signKey == nil {
+		return nil, errMissingSignKey
+	}
+	return k, nil
+}
+
+// sign 签发一个token，头部带有签发密钥的kid
+func (k *keySet) sign(claims gojwt.MapClaims) (string, error) {
+	token := gojwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	return token.SignedString(k.signKey)
+}
+
+// keyFunc 按token头部的kid选择校验的密钥，签名算法必须与配置一致
+func (k *keySet) keyFunc(token *gojwt.Token) (interface{}, error) {
+	if token.Method != k.method {
+		return nil, jwt.ErrInvalidSigningAlgorithm
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.verifyKeys[kid]
+	if !ok {
+		return nil, errUnknownKeyId
+	}
+	return key, nil
+}
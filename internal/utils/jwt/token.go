@@ -0,0 +1,216 @@
+package jwt
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/sessionUtils"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/hertz-contrib/jwt"
+	"strconv"
+	"time"
+)
+
+const (
+	claimUserId  = "user_id" // 以字符串保存，解析为float64时雪花ID会丢失精度
+	claimType    = "typ"
+	claimId      = "jti"
+	claimSession = "sid" // 同一次登录签发及轮换得到的所有token属于同一个会话，会话失效后全部失效
+
+	accessToken  = "access"
+	refreshToken = "refresh"
+
+	refreshUsedPrefix = "jwt_refresh_used_" // 已经被轮换的refresh token
+
+	maxDeviceNameLen = 128
+)
+
+// issueTokens 为用户在会话sessionId中签发一对access token与refresh token
+func issueTokens(user *model.User, sessionId string) (string, string, error) {
+	now := time.Now()
+	claims := func(typ string, timeout time.Duration) gojwt.MapClaims {
+		return gojwt.MapClaims{
+			IdentityKey:  user.UserName,
+			claimUserId:  strconv.FormatInt(user.UserID, 10),
+			claimType:    typ,
+			claimId:      uuid.New().String(),
+			claimSession: sessionId,
+			"iat":        now.Unix(),
+			"exp":        now.Add(timeout).Unix(),
+		}
+	}
+	access, err := keys.sign(claims(accessToken, accessTimeout))
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err := keys.sign(claims(refreshToken, refreshTimeout))
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// userFromClaims 从token中获取用户，token中没有用户ID时返回nil
+func userFromClaims(claims map[string]interface{}) *model.User {
+	userIdStr, _ := claims[claimUserId].(string)
+	userId, err := strconv.ParseInt(userIdStr, 10, 64)
+	if err != nil || userId == 0 {
+		return nil
+	}
+	username, _ := claims[IdentityKey].(string)
+	return &model.User{UserID: userId, UserName: username}
+}
+
+func claimString(claims map[string]interface{}, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
+
+// sessionActive 判断会话是否属于该用户且没有失效，并记录最近一次请求的时间与IP
+// redis不可用时不拦截access token，避免所有请求失败
+func sessionActive(ctx context.Context, c *app.RequestContext, user *model.User, sessionId string) bool {
+	if sessionId == "" {
+		return false
+	}
+	active, err := SessionStore.Touch(ctx, sessionId, user.UserID, c.ClientIP())
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to check session %v", sessionId)
+		return true
+	}
+	return active
+}
+
+// GetSessionId 获取JwtMiddleware校验通过的token所属的会话
+func GetSessionId(ctx context.Context, c *app.RequestContext) string {
+	return claimString(jwt.ExtractClaims(ctx, c), claimSession)
+}
+
+// deviceName 登录设备的名称，客户端未上报时使用User-Agent
+func deviceName(c *app.RequestContext) string {
+	name := c.Query("device_name")
+	if name == "" {
+		name = c.PostForm("device_name")
+	}
+	if name == "" {
+		name = string(c.UserAgent())
+	}
+	if len(name) > maxDeviceNameLen {
+		name = name[:maxDeviceNameLen]
+	}
+	return name
+}
+
+// LoginHandler 校验用户名与密码，签发access token与refresh token
+func LoginHandler(ctx context.Context, c *app.RequestContext) {
+	user, err := authenticate(ctx, c)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("login failed")
+		c.JSON(consts.StatusOK, api.UserLoginResponse{Response: errorResponse(err)})
+		return
+	}
+	session, err := SessionStore.Create(ctx, user.UserID, deviceName(c), c.ClientIP())
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to create session of user %v", user.UserID)
+		redisError(c)
+		return
+	}
+	writeTokens(c, user, session.Id)
+}
+
+// RefreshHandler 使用refresh token换取一对新的token并延长会话的有效期，旧的refresh token随即失效
+// 已经使用过的refresh token再次使用时，认为token已经泄露，使整个会话失效
+func RefreshHandler(ctx context.Context, c *app.RequestContext) {
+	tokenString := c.Query("refresh_token")
+	if tokenString == "" {
+		tokenString = c.PostForm("refresh_token")
+	}
+	token, err := JwtMiddleware.ParseTokenString(tokenString)
+	if err != nil || !token.Valid {
+		tokenInvalid(c)
+		return
+	}
+	claims := token.Claims.(gojwt.MapClaims)
+	user, sessionId := userFromClaims(claims), claimString(claims, claimSession)
+	if user == nil || claimString(claims, claimType) != refreshToken {
+		tokenInvalid(c)
+		return
+	}
+	active, err := SessionStore.Touch(ctx, sessionId, user.UserID, c.ClientIP())
+	if err != nil {
+		redisError(c)
+		return
+	}
+	if !active {
+		tokenInvalid(c)
+		return
+	}
+	first, err := redisClient.SetNX(ctx, refreshUsedPrefix+claimString(claims, claimId), 1, refreshTimeout).Result()
+	if err != nil {
+		redisError(c)
+		return
+	}
+	if !first {
+		logger.Ctx(ctx).Warn().Msgf("refresh token of user %v reused, revoke session %v", user.UserID, sessionId)
+		if err = SessionStore.Revoke(ctx, user.UserID, sessionId); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msgf("fail to revoke session %v", sessionId)
+		}
+		tokenInvalid(c)
+		return
+	}
+	if err = SessionStore.Extend(ctx, sessionId, user.UserID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to extend session %v", sessionId)
+	}
+	writeTokens(c, user, sessionId)
+}
+
+// LogoutHandler 使当前会话签发的所有token失效，需要在JwtMiddleware之后调用
+func LogoutHandler(ctx context.Context, c *app.RequestContext) {
+	userId, err := GetUserId(ctx, c)
+	if err != nil {
+		tokenInvalid(c)
+		return
+	}
+	sessionId := GetSessionId(ctx, c)
+	if err = SessionStore.Revoke(ctx, userId, sessionId); err != nil && err != sessionUtils.ErrSessionNotExist {
+		logger.Ctx(ctx).Error().Err(err).Msgf("fail to revoke session %v", sessionId)
+		redisError(c)
+		return
+	}
+	c.JSON(consts.StatusOK, api.Response{StatusCode: 0})
+}
+
+func writeTokens(c *app.RequestContext, user *model.User, sessionId string) {
+	access, refresh, err := issueTokens(user, sessionId)
+	if err != nil {
+		logger.GlobalLogger.Error().Err(err).Msg("fail to sign token")
+		c.JSON(consts.StatusOK, api.UserLoginResponse{Response: api.Response{
+			StatusCode: int32(api.CreateDataErr),
+			StatusMsg:  api.ErrorCodeToMsg[api.CreateDataErr],
+		}})
+		return
+	}
+	c.JSON(consts.StatusOK, api.UserLoginResponse{
+		Response:     api.Response{StatusCode: 0},
+		UserId:       user.UserID,
+		Token:        access,
+		RefreshToken: refresh,
+	})
+}
+
+func tokenInvalid(c *app.RequestContext) {
+	c.JSON(consts.StatusOK, api.UserLoginResponse{Response: api.Response{
+		StatusCode: int32(api.TokenInvalidErr),
+		StatusMsg:  api.ErrorCodeToMsg[api.TokenInvalidErr],
+	}})
+}
+
+func redisError(c *app.RequestContext) {
+	c.JSON(consts.StatusOK, api.UserLoginResponse{Response: api.Response{
+		StatusCode: int32(api.RedisDBErr),
+		StatusMsg:  api.ErrorCodeToMsg[api.RedisDBErr],
+	}})
+}
@@ -2,42 +2,48 @@ package jwt
 
 import (
 	"context"
-	pbuser "github.com/YOJIA-yukino/simple-douyin-backend/api/rpc_controller_service/user"
-	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/model"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
-	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
 	"github.com/cloudwego/hertz/pkg/app"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"time"
 )
 
+// GetUserId 获取JwtMiddleware校验通过的token中的用户ID
 func GetUserId(content context.Context, requestContext *app.RequestContext) (int64, error) {
 	user, exists := requestContext.Get(IdentityKey)
 	if !exists {
 		return 0, constants.InvalidTokenErr
 	}
+	return user.(*model.User).UserID, nil
+}
 
-	loginUserInfo := user.(*model.User)
-	logger.GlobalLogger.Printf("Time = %v, In GetUserId, Got Login Username =%v", time.Now(), loginUserInfo.UserName)
-	address := initialization.RpcCSConf.UserServiceHost + initialization.RpcCSConf.UserServicePort
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		logger.GlobalLogger.Printf("did not connect: %v", err)
+// UserIdFromToken 解析请求中的access token获取用户ID，用于在JwtMiddleware之前识别用户，不检查会话是否失效
+func UserIdFromToken(content context.Context, requestContext *app.RequestContext) (int64, bool) {
+	claims, err := JwtMiddleware.GetClaimsFromJWT(content, requestContext)
+	if err != nil || claimString(claims, claimType) != accessToken {
+		return 0, false
+	}
+	user := userFromClaims(claims)
+	if user == nil {
+		return 0, false
 	}
-	defer conn.Close()
-	grpcClient := pbuser.NewUserServiceInfoClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	userResp, err := grpcClient.GetUserIdByUserName(ctx, &pbuser.UserServicePost{
-		Username: loginUserInfo.UserName,
-	})
+	return user.UserID, true
+}
 
-	if err != nil {
-		logger.GlobalLogger.Printf("Can't get RPC From UserService, err = %v", err)
-		return 0, err
+// OptionalUserId 获取无需登录的接口中请求携带的token所属的用户ID，没有携带token时返回0
+// 与JwtMiddleware的校验相同，只接受所属会话没有失效的access token，否则返回InvalidTokenErr，不写入响应
+func OptionalUserId(content context.Context, requestContext *app.RequestContext) (int64, error) {
+	if requestContext.Query("token") == "" {
+		return 0, nil
+	}
+	claims, err := JwtMiddleware.GetClaimsFromJWT(content, requestContext)
+	if err != nil || claimString(claims, claimType) != accessToken {
+		return 0, constants.InvalidTokenErr
+	}
+	user := userFromClaims(claims)
+	if user == nil || !sessionActive(content, requestContext, user, claimString(claims, claimSession)) {
+		return 0, constants.InvalidTokenErr
 	}
-	logger.GlobalLogger.Printf("Time = %v, In GetUserId, Got Login UserId =%v", time.Now(), userResp.UserId)
-	return userResp.UserId, err
+	requestContext.Set("JWT_PAYLOAD", claims)
+	requestContext.Set(IdentityKey, user)
+	return user.UserID, nil
 }
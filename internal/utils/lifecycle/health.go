@@ -0,0 +1,126 @@
+package lifecycle
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"sync"
+	"time"
+)
+
+const (
+	checkTimeout        = 2 * time.Second // 单个依赖检查的超时时间
+	healthCheckInterval = 5 * time.Second // gRPC健康状态的刷新间隔
+	StatusOk            = "ok"
+	StatusShuttingDown  = "shutting down"
+)
+
+type checker struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+var (
+	checkersMu sync.RWMutex
+	checkers   []checker
+
+	healthServer = health.NewServer()
+	healthOnce   sync.Once
+	healthMu     sync.Mutex
+	serviceNames = make(map[string]struct{})
+)
+
+// AddChecker 添加一个依赖(数据库、redis、消息队列、对象存储等)的就绪检查
+func AddChecker(name string, check func(ctx context.Context) error) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+	checkers = append(checkers, checker{name: name, check: check})
+}
+
+// Ready 并发检查所有依赖，返回是否就绪以及每个依赖的状态，开始关闭后总是未就绪
+func Ready(ctx context.Context) (bool, map[string]string) {
+	if rootCtx.Err() != nil {
+		return false, map[string]string{"server": StatusShuttingDown}
+	}
+	checkersMu.RLock()
+	list := make([]checker, len(checkers))
+	copy(list, checkers)
+	checkersMu.RUnlock()
+
+	statuses := make(map[string]string, len(list))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	ready := true
+	for _, c := range list {
+		wg.Add(1)
+		go func(c checker) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			status := StatusOk
+			if err := c.check(checkCtx); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[c.name] = status
+			if status != StatusOk {
+				ready = false
+			}
+		}(c)
+	}
+	wg.Wait()
+	return ready, statuses
+}
+
+// RegisterHealth 在s上注册标准的grpc.health.v1服务，应在注册其他服务之后调用
+// 进程内的所有gRPC服务共用一个健康状态，按Ready的结果定时刷新，开始关闭后一直为NOT_SERVING
+func RegisterHealth(s *grpc.Server) {
+	healthOnce.Do(func() {
+		go refreshHealth()
+	})
+	healthMu.Lock()
+	for name := range s.GetServiceInfo() {
+		serviceNames[name] = struct{}{}
+	}
+	healthMu.Unlock()
+	healthpb.RegisterHealthServer(s, healthServer)
+	updateHealth()
+}
+
+func refreshHealth() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rootCtx.Done():
+			return
+		case <-ticker.C:
+			updateHealth()
+		}
+	}
+}
+
+func updateHealth() {
+	ready, _ := Ready(rootCtx)
+	status := healthpb.HealthCheckResponse_SERVING
+	if !ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	healthMu.Lock()
+	names := make([]string, 0, len(serviceNames)+1)
+	names = append(names, "")
+	for name := range serviceNames {
+		names = append(names, name)
+	}
+	healthMu.Unlock()
+	for _, name := range names {
+		healthServer.SetServingStatus(name, status)
+	}
+}
+
+// stopHealth 将所有服务设置为NOT_SERVING，之后的刷新不再生效
+func stopHealth() {
+	healthServer.Shutdown()
+}
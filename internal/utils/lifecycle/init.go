@@ -0,0 +1,23 @@
+package lifecycle
+
+import (
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"sort"
+	"time"
+)
+
+// InitLifecycle 按配置设置关闭的超时时间，为已初始化的依赖添加就绪检查，并在关闭时关闭它们的连接
+// 应在依赖初始化之后调用
+func InitLifecycle(config initialization.ShutdownConfig) {
+	SetTimeout(time.Duration(config.Timeout)*time.Second, time.Duration(config.Delay)*time.Second)
+	checks := initialization.HealthCheckers()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		AddChecker(name, checks[name])
+	}
+	OnShutdown(PhaseStorage, "storage", initialization.CloseStorage)
+}
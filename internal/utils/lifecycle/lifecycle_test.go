@@ -0,0 +1,191 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reset 恢复包内的全局状态，每个测试相互独立
+func reset(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	hooks, timeout, delay = nil, 30*time.Second, 0
+	mu.Unlock()
+	checkersMu.Lock()
+	checkers = nil
+	checkersMu.Unlock()
+	rootCtx, rootCancel = context.WithCancel(context.Background())
+	shutdownOnce = sync.Once{}
+	healthServer = health.NewServer()
+	healthMu.Lock()
+	serviceNames = make(map[string]struct{})
+	healthMu.Unlock()
+	t.Cleanup(rootCancel)
+}
+
+func TestShutdownRunsHooksByPhase(t *testing.T) {
+	reset(t)
+	var order []string
+	record := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	OnShutdown(PhaseTelemetry, "tracing", record("tracing", nil))
+	OnShutdown(PhaseStorage, "storage", record("storage", nil))
+	OnShutdown(PhaseFlush, "favorite_flush", record("favorite_flush", errors.New("redis unavailable")))
+	OnShutdown(PhaseServer, "userService", record("userService", nil))
+	OnShutdown(PhaseFlush, "play_flush", record("play_flush", nil))
+	OnShutdown(PhaseQueue, "message queue", record("message queue", nil))
+
+	Shutdown()
+	want := []string{"userService", "favorite_flush", "play_flush", "message queue", "storage", "tracing"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	if Context().Err() == nil {
+		t.Fatal("Context not cancelled after Shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	reset(t)
+	SetTimeout(50*time.Millisecond, 0)
+	var afterTimeout error
+	OnShutdown(PhaseServer, "slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	OnShutdown(PhaseStorage, "storage", func(ctx context.Context) error {
+		afterTimeout = ctx.Err()
+		return nil
+	})
+	start := time.Now()
+	Shutdown()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Shutdown took %v, want about 50ms", elapsed)
+	}
+	// 超时后剩余的hook仍然执行，由hook自己决定是否放弃
+	if !errors.Is(afterTimeout, context.DeadlineExceeded) {
+		t.Fatalf("ctx err in later hook = %v, want DeadlineExceeded", afterTimeout)
+	}
+}
+
+func TestWaitReturnsServerError(t *testing.T) {
+	reset(t)
+	errCh := make(chan error, 1)
+	serveErr := errors.New("address already in use")
+	errCh <- serveErr
+	if err := Wait(errCh); err != serveErr {
+		t.Fatalf("Wait = %v, want %v", err, serveErr)
+	}
+	if ready, _ := Ready(context.Background()); ready {
+		t.Fatal("ready after Wait returned")
+	}
+}
+
+func TestReady(t *testing.T) {
+	reset(t)
+	AddChecker("mysql", func(ctx context.Context) error { return nil })
+	AddChecker("redis", func(ctx context.Context) error { return nil })
+	ready, statuses := Ready(context.Background())
+	if !ready || statuses["mysql"] != StatusOk || statuses["redis"] != StatusOk {
+		t.Fatalf("Ready = %v %v, want ready", ready, statuses)
+	}
+
+	AddChecker("kafka", func(ctx context.Context) error {
+		// 超时的检查由Ready的超时结束
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ready, statuses = Ready(ctx)
+	if ready || statuses["kafka"] != context.DeadlineExceeded.Error() || statuses["mysql"] != StatusOk {
+		t.Fatalf("Ready = %v %v, want kafka not ready", ready, statuses)
+	}
+
+	beginShutdown()
+	ready, statuses = Ready(context.Background())
+	if ready || statuses["server"] != StatusShuttingDown {
+		t.Fatalf("Ready after shutdown = %v %v, want shutting down", ready, statuses)
+	}
+}
+
+func TestGrpcHealthAndGracefulStop(t *testing.T) {
+	reset(t)
+	failing := errors.New("connection refused")
+	var mysqlErr error
+	var checkMu sync.Mutex
+	AddChecker("mysql", func(ctx context.Context) error {
+		checkMu.Lock()
+		defer checkMu.Unlock()
+		return mysqlErr
+	})
+
+	s := grpc.NewServer()
+	RegisterHealth(s)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(lis)
+	}()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+	if status := check(); status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("status = %v, want SERVING", status)
+	}
+
+	checkMu.Lock()
+	mysqlErr = failing
+	checkMu.Unlock()
+	updateHealth()
+	if status := check(); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("status with failing dependency = %v, want NOT_SERVING", status)
+	}
+
+	checkMu.Lock()
+	mysqlErr = nil
+	checkMu.Unlock()
+	OnShutdown(PhaseServer, "health", StopGrpcServer(s))
+	beginShutdown()
+	// 开始关闭后即使依赖恢复也保持NOT_SERVING
+	updateHealth()
+	if status := check(); status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("status after shutdown began = %v, want NOT_SERVING", status)
+	}
+	Shutdown()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve = %v, want nil after GracefulStop", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server not stopped")
+	}
+}
@@ -0,0 +1,128 @@
+package lifecycle
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"google.golang.org/grpc"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 关闭的阶段，按从小到大的顺序执行，同一阶段的hook按注册的顺序执行
+const (
+	PhaseServer    = iota // 停止接收请求并等待处理中的请求完成
+	PhaseFlush            // 将redis中的计数写入数据库
+	PhaseQueue            // 关闭消息队列的生产者与消费者
+	PhaseStorage          // 关闭redis与数据库的连接
+	PhaseTelemetry        // 导出剩余的链路
+)
+
+type hook struct {
+	phase int
+	name  string
+	fn    func(ctx context.Context) error
+}
+
+var (
+	mu      sync.Mutex
+	hooks   []hook
+	timeout = 30 * time.Second
+	delay   time.Duration
+
+	rootCtx, rootCancel = context.WithCancel(context.Background())
+	shutdownOnce        sync.Once
+)
+
+// SetTimeout 设置关闭的超时时间，以及收到信号后、停止接收请求前等待的时间
+// 等待期间就绪检查已经失败，负载均衡有时间将实例摘除
+func SetTimeout(shutdownTimeout, shutdownDelay time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	timeout, delay = shutdownTimeout, shutdownDelay
+}
+
+// OnShutdown 注册一个在关闭的phase阶段执行的hook
+func OnShutdown(phase int, name string, fn func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook{phase: phase, name: name, fn: fn})
+}
+
+// Context 在开始关闭时取消，后台任务(例如消费组)据此退出
+func Context() context.Context {
+	return rootCtx
+}
+
+// Wait 等待SIGINT或SIGTERM，errCh收到服务的错误时立即返回该错误
+// 收到信号后就绪检查开始失败，经过设置的等待时间后返回nil，之后由调用方停止服务
+// 签名与hertz的SetCustomSignalWaiter一致，返回nil时hertz等待处理中的请求完成后退出
+func Wait(errCh chan error) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case sig := <-signals:
+		logger.GlobalLogger.Info().Str("signal", sig.String()).Msg("begin graceful shutdown")
+	case err := <-errCh:
+		beginShutdown()
+		return err
+	}
+	beginShutdown()
+	mu.Lock()
+	d := delay
+	mu.Unlock()
+	time.Sleep(d)
+	return nil
+}
+
+// beginShutdown 标记为未就绪并通知后台任务退出
+func beginShutdown() {
+	shutdownOnce.Do(func() {
+		rootCancel()
+		stopHealth()
+	})
+}
+
+// Shutdown 按阶段依次执行所有的hook，所有hook共用设置的超时时间，hook出错时记录日志并继续执行
+func Shutdown() {
+	beginShutdown()
+	mu.Lock()
+	ordered := make([]hook, len(hooks))
+	copy(ordered, hooks)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), timeout)
+	mu.Unlock()
+	defer cancelShutdown()
+	sort.SliceStable(ordered, func(i, k int) bool {
+		return ordered[i].phase < ordered[k].phase
+	})
+	for _, h := range ordered {
+		start := time.Now()
+		if err := h.fn(shutdownCtx); err != nil {
+			logger.GlobalLogger.Error().Err(err).Str("hook", h.name).Msg("shutdown hook failed")
+			continue
+		}
+		logger.GlobalLogger.Info().Str("hook", h.name).Dur("duration", time.Since(start)).Msg("shutdown hook finished")
+	}
+}
+
+// StopGrpcServer 停止接收新的请求并等待处理中的请求完成，超时后强制关闭连接
+func StopGrpcServer(s *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			s.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+// fields 随请求传递的日志字段，各层之间通过gRPC metadata传递
+type fields struct {
+	requestId string
+	userId    int64
+	route     string
+}
+
+func fieldsFrom(ctx context.Context) fields {
+	f, _ := ctx.Value(contextKey{}).(fields)
+	return f
+}
+
+// NewRequestId 生成一个新的请求ID
+func NewRequestId() string {
+	return uuid.NewString()
+}
+
+// WithRequestId 返回带有请求ID的ctx
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	f := fieldsFrom(ctx)
+	f.requestId = requestId
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// WithUserId 返回带有登录用户ID的ctx
+func WithUserId(ctx context.Context, userId int64) context.Context {
+	f := fieldsFrom(ctx)
+	f.userId = userId
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// WithRoute 返回带有HTTP路由模板的ctx
+func WithRoute(ctx context.Context, route string) context.Context {
+	f := fieldsFrom(ctx)
+	f.route = route
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// RequestId ctx中的请求ID，没有时为空
+func RequestId(ctx context.Context) string {
+	return fieldsFrom(ctx).requestId
+}
+
+// UserId ctx中的登录用户ID，没有时为0
+func UserId(ctx context.Context) int64 {
+	return fieldsFrom(ctx).userId
+}
+
+// Route ctx中的HTTP路由模板，没有时为空
+func Route(ctx context.Context) string {
+	return fieldsFrom(ctx).route
+}
+
+// Ctx 带有ctx中的request_id、user_id、route以及trace_id的GlobalLogger，ctx中没有的字段不输出
+func Ctx(ctx context.Context) *zerolog.Logger {
+	f := fieldsFrom(ctx)
+	spanContext := trace.SpanContextFromContext(ctx)
+	if f == (fields{}) && !spanContext.IsValid() {
+		return &GlobalLogger
+	}
+	c := GlobalLogger.With()
+	if f.requestId != "" {
+		c = c.Str("request_id", f.requestId)
+	}
+	if f.userId != 0 {
+		c = c.Int64("user_id", f.userId)
+	}
+	if f.route != "" {
+		c = c.Str("route", f.route)
+	}
+	if spanContext.IsValid() {
+		c = c.Str("trace_id", spanContext.TraceID().String())
+	}
+	l := c.Logger()
+	return &l
+}
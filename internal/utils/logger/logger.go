@@ -2,31 +2,35 @@ package logger
 
 import (
 	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
-	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/files"
 	"github.com/rs/zerolog"
+	"io"
 	"os"
+	"time"
 )
 
-// GlobalLogger 全局Logger
+// GlobalLogger 全局Logger，处理请求时使用Ctx(ctx)带上请求的字段
 var GlobalLogger zerolog.Logger
 
-// InitLogger 初始化GlobalLogger
+// InitLogger 初始化GlobalLogger，按配置的级别输出到标准输出或按大小与时间切割的日志文件，输出前脱敏
 func InitLogger(config initialization.LogConfig) {
-	var err error
-	var file *os.File
+	level, err := zerolog.ParseLevel(config.Level)
+	if err != nil || level == zerolog.NoLevel {
+		level = zerolog.InfoLevel
+	}
+	var writer io.Writer = os.Stdout
 	if config.LogFileWritten {
-		if exists, _ := files.PathExists(config.LogFilePath); exists {
-			file, err = os.OpenFile(config.LogFilePath, os.O_APPEND, 0666)
-		} else {
-			file, err = os.Create(config.LogFilePath)
-		}
+		file, err := NewRotateWriter(RotateOptions{
+			Path:       config.LogFilePath,
+			MaxSize:    int64(config.MaxSize) << 20,
+			MaxAge:     time.Duration(config.MaxAge) * time.Hour,
+			MaxBackups: config.MaxBackups,
+		})
 		if err != nil {
-			GlobalLogger = initialization.GetStdOutLogger()
-			GlobalLogger.Error().Msg("Get Logger failed")
+			stdOutLogger := initialization.GetStdOutLogger()
+			stdOutLogger.Error().Err(err).Msg("Get Logger failed")
+		} else {
+			writer = file
 		}
-		GlobalLogger = zerolog.New(file)
-	} else {
-		GlobalLogger = initialization.GetStdOutLogger()
 	}
-	GlobalLogger.Level(zerolog.InfoLevel)
+	GlobalLogger = zerolog.New(NewRedactWriter(writer)).Level(level).With().Timestamp().Logger()
 }
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"io"
+	"regexp"
+)
+
+const redacted = "***"
+
+var (
+	// 字段名包含password、token、secret等的值，匹配json的"password":"x"与Printf输出的password = x、Password:x
+	sensitiveFieldPattern = regexp.MustCompile(`(?i)((?:password|passwd|token|secret|authorization)\w*\\?"?\s*[:=]\s*\\?"?)([^\s",}\\]+)`)
+	// Authorization头中的Bearer token
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)([\w\-.~+/]+=*)`)
+	// 出现在任意位置的JWT
+	jwtPattern = regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]+`)
+)
+
+// RedactWriter 将日志中的密码、token等敏感信息替换为***后写入下层的Writer
+type RedactWriter struct {
+	w io.Writer
+}
+
+// NewRedactWriter 创建写入w的RedactWriter
+func NewRedactWriter(w io.Writer) *RedactWriter {
+	return &RedactWriter{w: w}
+}
+
+func (r *RedactWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write(Redact(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Redact 返回将敏感信息替换为***之后的日志
+func Redact(p []byte) []byte {
+	p = jwtPattern.ReplaceAll(p, []byte(redacted))
+	p = bearerPattern.ReplaceAll(p, []byte("${1}"+redacted))
+	return sensitiveFieldPattern.ReplaceAll(p, []byte("${1}"+redacted))
+}
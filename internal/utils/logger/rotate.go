@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateOptions 日志文件的切割配置
+type RotateOptions struct {
+	Path       string        // 日志文件的路径，切割后的文件在同一目录下，文件名加上切割的时间
+	MaxSize    int64         // 文件超过该字节数后切割，0表示不按大小切割
+	MaxAge     time.Duration // 文件打开后写入超过该时长后切割，0表示不按时间切割
+	MaxBackups int           // 保留的切割后的文件数，超过时删除最旧的，0表示全部保留
+}
+
+// RotateWriter 按大小与时间切割的日志文件，可以被多个协程同时写入
+type RotateWriter struct {
+	opts     RotateOptions
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+}
+
+// NewRotateWriter 以追加的方式打开日志文件，文件或目录不存在时创建
+func NewRotateWriter(opts RotateOptions) (*RotateWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		return nil, err
+	}
+	w := &RotateWriter{opts: opts, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotateWriter) open() error {
+	file, err := os.OpenFile(w.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file, w.size, w.openedAt = file, info.Size(), w.now()
+	return nil
+}
+
+// Write 写入一条日志，写入后文件会超过MaxSize或者文件已写入超过MaxAge时先切割
+// 切割失败时继续写入当前的文件，并返回切割的错误，下次写入时重试切割
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var rotateErr error
+	if w.shouldRotate(int64(len(p))) {
+		rotateErr = w.rotate()
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+func (w *RotateWriter) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.MaxAge > 0 && w.now().Sub(w.openedAt) >= w.opts.MaxAge
+}
+
+// rotate 将当前文件重命名为带有切割时间的文件，打开新的文件，并删除多余的旧文件
+// 新的文件打开之后才关闭旧的文件，失败时恢复原来的文件名并保留旧的文件，不影响之后的写入
+func (w *RotateWriter) rotate() error {
+	old, backup := w.file, w.backupName(w.now())
+	if err := os.Rename(w.opts.Path, backup); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		_ = os.Rename(backup, w.opts.Path)
+		return err
+	}
+	_ = old.Close()
+	return w.removeOldBackups()
+}
+
+func (w *RotateWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.opts.Path)
+	return strings.TrimSuffix(w.opts.Path, ext) + "-" + t.Format(backupTimeFormat) + ext
+}
+
+// Backups 切割后的文件，按切割时间从旧到新排序
+func (w *RotateWriter) Backups() ([]string, error) {
+	ext := filepath.Ext(w.opts.Path)
+	prefix := strings.TrimSuffix(w.opts.Path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	backups := matches[:0]
+	for _, name := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (w *RotateWriter) removeOldBackups() error {
+	if w.opts.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := w.Backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > w.opts.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Close 关闭当前的日志文件
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
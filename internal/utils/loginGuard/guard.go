@@ -0,0 +1,162 @@
+package loginGuard
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+const (
+	SubjectUser = "user"
+	SubjectIp   = "ip"
+
+	failPrefix     = "login_fail_"     // 有效期内登录失败的次数
+	lockPrefix     = "login_lock_"     // 被锁定的用户名或IP，有效期为锁定时长
+	lockoutPrefix  = "login_lockouts_" // 被锁定的次数，用于计算下一次锁定的时长
+	registerPrefix = "register_ip_"    // 有效期内IP注册的次数
+
+	lockoutsTTL = 24 * time.Hour // 超过该时间没有再次被锁定时，锁定时长恢复为初始值
+
+	// failScript 记录一次登录失败，达到阈值时锁定并清空失败次数，返回锁定的时长(毫秒)，未锁定时返回0
+	// 每次锁定的时长是上一次的两倍，不超过最大锁定时长
+	failScript = `
+local failures = redis.call("incr", KEYS[1])
+if failures == 1 then
+    redis.call("pexpire", KEYS[1], ARGV[2])
+end
+if failures < tonumber(ARGV[1]) then
+    return 0
+end
+local lockouts = redis.call("incr", KEYS[3])
+redis.call("pexpire", KEYS[3], ARGV[5])
+local duration = math.floor(tonumber(ARGV[3]) * 2 ^ math.min(lockouts - 1, 30))
+duration = math.min(duration, tonumber(ARGV[4]))
+redis.call("set", KEYS[2], lockouts, "px", duration)
+redis.call("del", KEYS[1])
+return duration`
+
+	// registerScript 记录一次注册，返回窗口内的注册次数与窗口剩余的时间(毫秒)
+	registerScript = `
+local count = redis.call("incr", KEYS[1])
+if count == 1 then
+    redis.call("pexpire", KEYS[1], ARGV[1])
+end
+return {count, redis.call("pttl", KEYS[1])}`
+)
+
+// Options 登录失败锁定与注册限流的配置，阈值为0时不限制
+type Options struct {
+	UserMaxFailures int           // 同一用户名在FailureWindow内连续失败该次数后锁定
+	IpMaxFailures   int           // 同一IP在FailureWindow内失败该次数后锁定，应大于UserMaxFailures以免误伤共用出口IP的用户
+	FailureWindow   time.Duration // 失败次数的统计窗口
+	LockoutBase     time.Duration // 第一次锁定的时长
+	LockoutMax      time.Duration // 最大锁定时长
+	RegisterLimit   int           // 同一IP在RegisterWindow内最多注册的次数
+	RegisterWindow  time.Duration // 注册次数的统计窗口
+}
+
+// Lockout 一次登录失败导致的锁定
+type Lockout struct {
+	Subject  string // SubjectUser或SubjectIp
+	Key      string // 被锁定的用户名或IP
+	Duration time.Duration
+}
+
+// Guard 在redis中记录登录失败次数，防止暴力破解密码，并限制同一IP注册的频率
+type Guard struct {
+	client   *redis.Client
+	fail     *redis.Script
+	register *redis.Script
+	opts     Options
+}
+
+// NewGuard 创建一个Guard，锁定时长必须大于0
+func NewGuard(client *redis.Client, opts Options) *Guard {
+	return &Guard{
+		client:   client,
+		fail:     redis.NewScript(failScript),
+		register: redis.NewScript(registerScript),
+		opts:     opts,
+	}
+}
+
+// Locked 返回用户名或IP剩余的锁定时长，都没有被锁定时返回0
+func (g *Guard) Locked(ctx context.Context, username, ip string) (time.Duration, error) {
+	pipe := g.client.Pipeline()
+	userTTL := pipe.PTTL(ctx, lockKey(SubjectUser, username))
+	ipTTL := pipe.PTTL(ctx, lockKey(SubjectIp, ip))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	remaining := userTTL.Val()
+	if ipTTL.Val() > remaining {
+		remaining = ipTTL.Val()
+	}
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+// Fail 记录一次密码错误，返回本次失败导致的锁定
+func (g *Guard) Fail(ctx context.Context, username, ip string) ([]Lockout, error) {
+	lockouts := make([]Lockout, 0)
+	for _, subject := range []struct {
+		name, key string
+		max       int
+	}{
+		{SubjectUser, username, g.opts.UserMaxFailures},
+		{SubjectIp, ip, g.opts.IpMaxFailures},
+	} {
+		if subject.max <= 0 {
+			continue
+		}
+		duration, err := g.fail.Run(ctx, g.client,
+			[]string{failKey(subject.name, subject.key), lockKey(subject.name, subject.key), lockoutKey(subject.name, subject.key)},
+			subject.max, g.opts.FailureWindow.Milliseconds(), g.opts.LockoutBase.Milliseconds(),
+			g.opts.LockoutMax.Milliseconds(), lockoutsTTL.Milliseconds()).Int64()
+		if err != nil {
+			return lockouts, err
+		}
+		if duration > 0 {
+			lockouts = append(lockouts, Lockout{
+				Subject:  subject.name,
+				Key:      subject.key,
+				Duration: time.Duration(duration) * time.Millisecond,
+			})
+		}
+	}
+	return lockouts, nil
+}
+
+// Succeed 登录成功后清空用户名的失败次数，IP的失败次数不清空，避免用一个自己的账号绕过IP的限制
+func (g *Guard) Succeed(ctx context.Context, username string) error {
+	return g.client.Del(ctx, failKey(SubjectUser, username), lockoutKey(SubjectUser, username)).Err()
+}
+
+// AllowRegister 记录IP的一次注册，超过限制时返回需要等待的时长
+func (g *Guard) AllowRegister(ctx context.Context, ip string) (time.Duration, error) {
+	if g.opts.RegisterLimit <= 0 {
+		return 0, nil
+	}
+	result, err := g.register.Run(ctx, g.client, []string{registerPrefix + ip}, g.opts.RegisterWindow.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	if result[0] <= int64(g.opts.RegisterLimit) {
+		return 0, nil
+	}
+	return time.Duration(result[1]) * time.Millisecond, nil
+}
+
+func failKey(subject, key string) string {
+	return failPrefix + subject + "_" + key
+}
+
+func lockKey(subject, key string) string {
+	return lockPrefix + subject + "_" + key
+}
+
+func lockoutKey(subject, key string) string {
+	return lockoutPrefix + subject + "_" + key
+}
@@ -0,0 +1,112 @@
+package loginGuard
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"testing"
+	"time"
+)
+
+func newTestGuard(t *testing.T, opts Options) (*miniredis.Miniredis, *Guard) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return mr, NewGuard(client, opts)
+}
+
+var testOptions = Options{
+	UserMaxFailures: 3,
+	IpMaxFailures:   5,
+	FailureWindow:   time.Minute,
+	LockoutBase:     time.Minute,
+	LockoutMax:      3 * time.Minute,
+	RegisterLimit:   2,
+	RegisterWindow:  time.Hour,
+}
+
+func TestUserLockout(t *testing.T) {
+	mr, guard := newTestGuard(t, testOptions)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if lockouts, err := guard.Fail(ctx, "alice", "10.0.0.1"); err != nil || len(lockouts) != 0 {
+			t.Fatalf("Fail #%d = %v, %v, want no lockout", i, lockouts, err)
+		}
+	}
+	if remaining, _ := guard.Locked(ctx, "alice", "10.0.0.1"); remaining != 0 {
+		t.Fatalf("locked before reaching threshold: %v", remaining)
+	}
+	lockouts, err := guard.Fail(ctx, "alice", "10.0.0.1")
+	if err != nil || len(lockouts) != 1 || lockouts[0].Subject != SubjectUser || lockouts[0].Duration != time.Minute {
+		t.Fatalf("Fail = %+v, %v, want user locked for 1m", lockouts, err)
+	}
+	if remaining, _ := guard.Locked(ctx, "alice", "10.0.0.2"); remaining <= 0 || remaining > time.Minute {
+		t.Fatalf("Locked = %v, want (0, 1m]", remaining)
+	}
+	if remaining, _ := guard.Locked(ctx, "bob", "10.0.0.1"); remaining != 0 {
+		t.Fatalf("other user locked: %v", remaining)
+	}
+
+	// 再次被锁定时锁定时长翻倍，且不超过最大锁定时长
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+		mr.FastForward(time.Minute * 3)
+		var lockouts []Lockout
+		for i := 0; i < 3; i++ {
+			lockouts, _ = guard.Fail(ctx, "alice", "10.0.0.9")
+		}
+		if len(lockouts) != 1 || lockouts[0].Duration != want {
+			t.Fatalf("lockout = %+v, want %v", lockouts, want)
+		}
+	}
+}
+
+func TestSucceedResetsUser(t *testing.T) {
+	_, guard := newTestGuard(t, testOptions)
+	ctx := context.Background()
+	guard.Fail(ctx, "alice", "10.0.0.1")
+	guard.Fail(ctx, "alice", "10.0.0.1")
+	if err := guard.Succeed(ctx, "alice"); err != nil {
+		t.Fatalf("Succeed: %v", err)
+	}
+	if lockouts, _ := guard.Fail(ctx, "alice", "10.0.0.1"); len(lockouts) != 0 {
+		t.Fatalf("failures not reset after success: %+v", lockouts)
+	}
+}
+
+func TestIpLockout(t *testing.T) {
+	_, guard := newTestGuard(t, testOptions)
+	ctx := context.Background()
+	var lockouts []Lockout
+	for _, username := range []string{"a", "b", "c", "d", "e"} {
+		lockouts, _ = guard.Fail(ctx, username, "10.0.0.1")
+	}
+	if len(lockouts) != 1 || lockouts[0].Subject != SubjectIp || lockouts[0].Key != "10.0.0.1" {
+		t.Fatalf("lockout = %+v, want ip locked", lockouts)
+	}
+	if remaining, _ := guard.Locked(ctx, "f", "10.0.0.1"); remaining <= 0 {
+		t.Fatalf("ip not locked")
+	}
+}
+
+func TestAllowRegister(t *testing.T) {
+	mr, guard := newTestGuard(t, testOptions)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if wait, err := guard.AllowRegister(ctx, "10.0.0.1"); err != nil || wait != 0 {
+			t.Fatalf("AllowRegister #%d = %v, %v, want allowed", i, wait, err)
+		}
+	}
+	if wait, _ := guard.AllowRegister(ctx, "10.0.0.1"); wait <= 0 {
+		t.Fatalf("register not throttled")
+	}
+	if wait, _ := guard.AllowRegister(ctx, "10.0.0.2"); wait != 0 {
+		t.Fatalf("other ip throttled")
+	}
+	mr.FastForward(time.Hour)
+	if wait, _ := guard.AllowRegister(ctx, "10.0.0.1"); wait != 0 {
+		t.Fatalf("still throttled after window: %v", wait)
+	}
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const namespace = "douyin"
+
+// 业务事件，作为BusinessEvents的event标签
+const (
+	EventRegister   = "register"
+	EventUpload     = "upload"
+	EventFavorite   = "favorite"
+	EventUnfavorite = "unfavorite"
+	EventComment    = "comment"
+	EventMessage    = "message"
+)
+
+// 缓存查询的结果，作为CacheRequests的result标签
+const (
+	CacheLocalHit    = "local_hit"    // 命中进程内缓存
+	CacheHit         = "hit"          // 命中redis
+	CacheMiss        = "miss"         // 未命中，从数据源加载
+	CacheBloomReject = "bloom_reject" // 被布隆过滤器拦截
+)
+
+var (
+	HttpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP请求数，route为路由模板，未匹配路由的请求为unmatched",
+	}, []string{"route", "method", "code"})
+	HttpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求的耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	GrpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC调用数，side为client或server",
+	}, []string{"side", "method", "code"})
+	GrpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC调用的耗时，流式调用为整个流的耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"side", "method"})
+
+	BusinessEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "business_events_total",
+		Help:      "成功处理的业务事件数：注册、上传、点赞、取消点赞、评论、私信",
+	}, []string{"event"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "按redis key前缀统计的缓存查询数，result为local_hit、hit、miss或bloom_reject",
+	}, []string{"prefix", "result"})
+
+	KafkaProduced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_produced_total",
+		Help:      "发送的消息数，result为ok或error",
+	}, []string{"topic", "result"})
+	KafkaConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumed_total",
+		Help:      "消费的消息数",
+	}, []string{"topic"})
+	KafkaOffsetLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_offset_lag",
+		Help:      "分区最新的offset与最近消费的消息的offset之差",
+	}, []string{"topic", "partition"})
+	KafkaDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_dropped_total",
+		Help:      "无法处理且无法写入死信topic而被丢弃的消息数，不为0时需要告警并根据日志补偿",
+	}, []string{"topic"})
+	KafkaConsumeDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_consume_delay_seconds",
+		Help:      "消息从发送到被消费经过的时间",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"topic"})
+
+	FavoriteDrift = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "favorite_reconcile_drift",
+		Help:      "最近一次点赞数对账发现的不一致，kind为db(不一致的视频数)、redis(不一致的视频数)或total(点赞数之差的绝对值之和)",
+	}, []string{"kind"})
+
+	CronRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_job_runs_total",
+		Help:      "定时任务的运行次数，result为success或failure",
+	}, []string{"job", "trigger", "result"})
+	CronDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cron_job_duration_seconds",
+		Help:      "定时任务的耗时",
+		Buckets:   []float64{.1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"job"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		HttpRequests, HttpDuration,
+		GrpcRequests, GrpcDuration,
+		BusinessEvents, CacheRequests,
+		KafkaProduced, KafkaConsumed, KafkaOffsetLag, KafkaDropped, KafkaConsumeDelay,
+		FavoriteDrift,
+		CronRuns, CronDuration,
+	)
+}
+
+// Business 记录一次成功处理的业务事件
+func Business(event string) {
+	BusinessEvents.WithLabelValues(event).Inc()
+}
+
+// Since 距离start经过的秒数，用于Observe
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// RegisterDB 导出数据库连接池的统计信息(连接数、等待次数与时长等)，name区分不同的数据库
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Serve 在address上单独启动一个HTTP服务，通过/metrics导出本进程的指标，address为空时不启动
+func Serve(address string) {
+	if address == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(address, mux); err != nil {
+			panic(err)
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package mqUtils
+
+import (
+	"context"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
+)
+
+var (
+	publisher  Publisher
+	subscriber Subscriber
+)
+
+// InitMessageQueue 按配置初始化消息队列，Driver为memory时使用进程内的实现，不需要连接kafka
+// 进程内的实现只能在同一个进程中收发消息，因此只适用于单机版本与测试，分层部署时必须使用kafka
+// 关闭时先停止消费者，再关闭生产者；连接kafka失败时返回错误，由调用者决定是否退出
+func InitMessageQueue(config initialization.MQConfig) error {
+	switch config.Driver {
+	case "memory":
+		bus := NewMemoryBus(config.BufferSize)
+		publisher, subscriber = bus, bus
+	default:
+		if err := initialization.InitKafkaServer(); err != nil {
+			return err
+		}
+		if err := initialization.InitKafkaClient(); err != nil {
+			initialization.GetKafkaServer().Close()
+			return err
+		}
+		publisher = NewKafkaPublisher(initialization.GetKafkaServer())
+		subscriber = NewKafkaSubscriber(initialization.GetKafkaClient(), initialization.NewKafkaConsumerGroup)
+	}
+	lifecycle.OnShutdown(lifecycle.PhaseQueue, "message queue", closeMessageQueue)
+	return nil
+}
+
+// closeMessageQueue 关闭全局的Subscriber与Publisher，超过ctx的期限时不再等待
+func closeMessageQueue(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		err := subscriber.Close()
+		if pubErr := publisher.Close(); err == nil {
+			err = pubErr
+		}
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetPublisher 获取全局的Publisher，未初始化时为nil
+func GetPublisher() Publisher {
+	return publisher
+}
+
+// GetSubscriber 获取全局的Subscriber，未初始化时为nil
+func GetSubscriber() Subscriber {
+	return subscriber
+}
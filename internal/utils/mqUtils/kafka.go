@@ -0,0 +1,251 @@
+package mqUtils
+
+import (
+	"context"
+	"errors"
+	"github.com/Shopify/sarama"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const groupRestartInterval = 5 * time.Second
+
+// KafkaPublisher 基于sarama.SyncProducer的Publisher
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+}
+
+// NewKafkaPublisher 使用已连接的producer创建Publisher
+func NewKafkaPublisher(producer sarama.SyncProducer) *KafkaPublisher {
+	return &KafkaPublisher{producer: producer}
+}
+
+// Publish 同步发送消息，Key为空时由分区器随机选择分区
+func (p *KafkaPublisher) Publish(ctx context.Context, msg *Message) error {
+	producerMsg := &sarama.ProducerMessage{
+		Topic: msg.Topic,
+		Value: sarama.ByteEncoder(msg.Value),
+	}
+	if len(msg.Key) > 0 {
+		producerMsg.Key = sarama.ByteEncoder(msg.Key)
+	}
+	if !msg.Timestamp.IsZero() {
+		producerMsg.Timestamp = msg.Timestamp
+	}
+	for k, v := range msg.Headers {
+		producerMsg.Headers = append(producerMsg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	partition, offset, err := p.producer.SendMessage(producerMsg)
+	if err != nil {
+		metrics.KafkaProduced.WithLabelValues(msg.Topic, "error").Inc()
+		return err
+	}
+	metrics.KafkaProduced.WithLabelValues(msg.Topic, "ok").Inc()
+	msg.Partition, msg.Offset = partition, offset
+	return nil
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// KafkaSubscriber 基于sarama的Subscriber，广播消费直接消费分区，消费组的offset提交到kafka
+type KafkaSubscriber struct {
+	consumer sarama.Consumer
+	newGroup func(groupId string) (sarama.ConsumerGroup, error)
+
+	closed chan struct{}
+	once   sync.Once
+	groups sync.WaitGroup
+}
+
+// NewKafkaSubscriber 使用已连接的consumer创建Subscriber，newGroup用于创建加入消费组的ConsumerGroup
+func NewKafkaSubscriber(consumer sarama.Consumer, newGroup func(groupId string) (sarama.ConsumerGroup, error)) *KafkaSubscriber {
+	return &KafkaSubscriber{consumer: consumer, newGroup: newGroup, closed: make(chan struct{})}
+}
+
+// Subscribe 为topic下的每一个分区启动一个协程，从最新的消息开始消费
+func (s *KafkaSubscriber) Subscribe(topic string, handler func(msg *Message)) error {
+	partitionList, err := s.consumer.Partitions(topic)
+	if err != nil {
+		logger.GlobalLogger.Error().Err(err).Msgf("fail to get list of partition of %v", topic)
+		return err
+	}
+	for _, partition := range partitionList {
+		pc, err := s.consumer.ConsumePartition(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("failed to start consumer for %v partition %d", topic, partition)
+			return err
+		}
+		go func(pc sarama.PartitionConsumer) {
+			defer pc.AsyncClose()
+			for msg := range pc.Messages() {
+				observeConsumed(msg, pc.HighWaterMarkOffset())
+				handler(fromConsumerMessage(msg))
+			}
+		}(pc)
+	}
+	return nil
+}
+
+// SubscribeGroup 加入消费组并持续消费，每次再平衡后重新调用Consume加入新一代的消费组，消费组出错时重新创建
+// ctx结束或Subscriber关闭时退出
+func (s *KafkaSubscriber) SubscribeGroup(ctx context.Context, groupId string, topics []string, handler GroupHandler) error {
+	select {
+	case <-s.closed:
+		return ErrClosed
+	default:
+	}
+	s.groups.Add(1)
+	defer s.groups.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for ctx.Err() == nil {
+		group, err := s.newGroup(groupId)
+		if err != nil {
+			logger.GlobalLogger.Error().Err(err).Msgf("fail to create consumer group %v", groupId)
+			sleepContext(ctx, groupRestartInterval)
+			continue
+		}
+		go func() {
+			for err := range group.Errors() {
+				logger.GlobalLogger.Error().Err(err).Msgf("error occurs in consumer group %v", groupId)
+			}
+		}()
+		for ctx.Err() == nil {
+			err = group.Consume(ctx, topics, &kafkaGroupHandler{handler: handler})
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				break
+			}
+			if err != nil {
+				logger.GlobalLogger.Error().Err(err).Msgf("fail to consume %v in group %v", topics, groupId)
+				sleepContext(ctx, groupRestartInterval)
+			}
+		}
+		group.Close()
+	}
+	return ctx.Err()
+}
+
+// sleepContext 等待d或ctx结束
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// Close 停止所有的消费组，等待已分发的消息处理完成并提交offset后关闭consumer
+func (s *KafkaSubscriber) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+	s.groups.Wait()
+	return s.consumer.Close()
+}
+
+// kafkaGroupHandler 将sarama.ConsumerGroupHandler适配为GroupHandler
+type kafkaGroupHandler struct {
+	handler GroupHandler
+}
+
+// Setup 在新一代消费组分配分区后调用
+func (h *kafkaGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	logger.GlobalLogger.Info().Msgf("consumer joined generation %v, claims = %v", session.GenerationID(), session.Claims())
+	return nil
+}
+
+// Cleanup 在所有ConsumeClaim退出后、提交offset之前调用
+func (h *kafkaGroupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	logger.GlobalLogger.Info().Msgf("consumer left generation %v", session.GenerationID())
+	return nil
+}
+
+// ConsumeClaim 将分区中的消息转换为Message后交给handler
+func (h *kafkaGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	messages := make(chan *Message)
+	go func() {
+		defer close(messages)
+		for msg := range claim.Messages() {
+			observeConsumed(msg, claim.HighWaterMarkOffset())
+			select {
+			case messages <- fromConsumerMessage(msg):
+			case <-session.Context().Done():
+				return
+			}
+		}
+	}()
+	return h.handler.ConsumeClaim(&kafkaSession{session: session}, &kafkaClaim{claim: claim, messages: messages})
+}
+
+type kafkaSession struct {
+	session sarama.ConsumerGroupSession
+}
+
+func (s *kafkaSession) Context() context.Context {
+	return s.session.Context()
+}
+
+func (s *kafkaSession) MarkOffset(topic string, partition int32, offset int64) {
+	s.session.MarkOffset(topic, partition, offset, "")
+}
+
+type kafkaClaim struct {
+	claim    sarama.ConsumerGroupClaim
+	messages chan *Message
+}
+
+func (c *kafkaClaim) Topic() string {
+	return c.claim.Topic()
+}
+
+func (c *kafkaClaim) Partition() int32 {
+	return c.claim.Partition()
+}
+
+func (c *kafkaClaim) Messages() <-chan *Message {
+	return c.messages
+}
+
+// observeConsumed 统计消费的消息数、分区的积压以及消息从发送到被消费的延迟
+// highWaterMark为分区中下一条消息的offset
+func observeConsumed(msg *sarama.ConsumerMessage, highWaterMark int64) {
+	metrics.KafkaConsumed.WithLabelValues(msg.Topic).Inc()
+	metrics.KafkaOffsetLag.WithLabelValues(msg.Topic, strconv.FormatInt(int64(msg.Partition), 10)).
+		Set(float64(highWaterMark - msg.Offset - 1))
+	if msg.Timestamp.Unix() > 0 {
+		metrics.KafkaConsumeDelay.WithLabelValues(msg.Topic).Observe(metrics.Since(msg.Timestamp))
+	}
+}
+
+func fromConsumerMessage(msg *sarama.ConsumerMessage) *Message {
+	m := &Message{
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+	}
+	if len(msg.Headers) > 0 {
+		m.Headers = make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			if header != nil {
+				m.Headers[string(header.Key)] = string(header.Value)
+			}
+		}
+	}
+	return m
+}
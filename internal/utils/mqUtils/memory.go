@@ -0,0 +1,209 @@
+package mqUtils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBus 基于channel的进程内消息队列，同时实现Publisher与Subscriber，用于单机运行与测试
+// 每个topic只有一个分区，消息只保存在内存中：订阅之前发送的消息不会被收到，进程退出后未处理的消息丢失
+// 消费组的最后一个成员退出后消费组被删除，之后发送的消息不再投递给该消费组
+type MemoryBus struct {
+	bufferSize int
+	mu         sync.Mutex
+	topics     map[string]*memoryTopic
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+type memoryTopic struct {
+	offset      int64
+	subscribers []chan *Message
+	groups      map[string]*memoryGroup
+}
+
+// memoryGroup 消费组在一个topic上共享的channel，members为组内的订阅者数，最后一个订阅者退出时关闭left
+type memoryGroup struct {
+	messages chan *Message
+	members  int
+	left     chan struct{}
+}
+
+// memoryTarget 一次投递的目标，left关闭时不再投递，广播订阅者的left为nil
+type memoryTarget struct {
+	messages chan *Message
+	left     chan struct{}
+}
+
+// NewMemoryBus 创建一个进程内的消息队列，bufferSize为每个订阅者的待处理消息数，队列满时发送阻塞
+func NewMemoryBus(bufferSize int) *MemoryBus {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &MemoryBus{
+		bufferSize: bufferSize,
+		topics:     make(map[string]*memoryTopic),
+		done:       make(chan struct{}),
+	}
+}
+
+// topic 获取topic，不存在时创建，调用时需持有锁
+func (b *MemoryBus) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{groups: make(map[string]*memoryGroup)}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Publish 将消息的副本投递给topic的每一个订阅者以及每一个消费组
+func (b *MemoryBus) Publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	select {
+	case <-b.done:
+		b.mu.Unlock()
+		return ErrClosed
+	default:
+	}
+	t := b.topic(msg.Topic)
+	msg.Partition = 0
+	msg.Offset = t.offset
+	t.offset++
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	targets := make([]memoryTarget, 0, len(t.subscribers)+len(t.groups))
+	for _, ch := range t.subscribers {
+		targets = append(targets, memoryTarget{messages: ch})
+	}
+	for _, g := range t.groups {
+		targets = append(targets, memoryTarget{messages: g.messages, left: g.left})
+	}
+	b.mu.Unlock()
+
+	// 投递时不持有锁，处理消息时可以继续发送消息；消费组的成员全部退出后不再等待该消费组
+	for _, target := range targets {
+		copied := *msg
+		select {
+		case target.messages <- &copied:
+		case <-target.left:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.done:
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+// Subscribe 注册一个订阅者，在单独的协程中按顺序处理消息
+func (b *MemoryBus) Subscribe(topic string, handler func(msg *Message)) error {
+	ch := make(chan *Message, b.bufferSize)
+	b.mu.Lock()
+	t := b.topic(topic)
+	t.subscribers = append(t.subscribers, ch)
+	b.mu.Unlock()
+	go func() {
+		for {
+			select {
+			case msg := <-ch:
+				handler(msg)
+			case <-b.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// SubscribeGroup 同一消费组在每个topic上共享一个channel，组内的多个订阅者竞争消费
+// ctx结束或消息队列关闭时退出，最后一个成员退出时删除消费组
+func (b *MemoryBus) SubscribeGroup(ctx context.Context, groupId string, topics []string, handler GroupHandler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	session := &memorySession{ctx: ctx}
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		g := b.joinGroup(topic, groupId)
+		defer b.leaveGroup(topic, groupId, g)
+		wg.Add(1)
+		go func(claim *memoryClaim) {
+			defer wg.Done()
+			_ = handler.ConsumeClaim(session, claim)
+		}(&memoryClaim{topic: topic, messages: g.messages})
+	}
+	select {
+	case <-ctx.Done():
+	case <-b.done:
+	}
+	cancel()
+	wg.Wait()
+	return nil
+}
+
+// joinGroup 加入topic上的消费组，不存在时创建
+func (b *MemoryBus) joinGroup(topic, groupId string) *memoryGroup {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(topic)
+	g, ok := t.groups[groupId]
+	if !ok {
+		g = &memoryGroup{messages: make(chan *Message, b.bufferSize), left: make(chan struct{})}
+		t.groups[groupId] = g
+	}
+	g.members++
+	return g
+}
+
+// leaveGroup 退出topic上的消费组，最后一个成员退出时删除消费组并唤醒等待投递的Publish
+func (b *MemoryBus) leaveGroup(topic, groupId string, g *memoryGroup) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g.members--
+	if g.members > 0 {
+		return
+	}
+	t := b.topic(topic)
+	if t.groups[groupId] == g {
+		delete(t.groups, groupId)
+	}
+	close(g.left)
+}
+
+// Close 停止所有订阅者，之后的发送返回ErrClosed
+func (b *MemoryBus) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+	return nil
+}
+
+// memorySession 进程内的消息不需要提交offset
+type memorySession struct {
+	ctx context.Context
+}
+
+func (s *memorySession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *memorySession) MarkOffset(topic string, partition int32, offset int64) {}
+
+type memoryClaim struct {
+	topic    string
+	messages chan *Message
+}
+
+func (c *memoryClaim) Topic() string {
+	return c.topic
+}
+
+func (c *memoryClaim) Partition() int32 {
+	return 0
+}
+
+func (c *memoryClaim) Messages() <-chan *Message {
+	return c.messages
+}
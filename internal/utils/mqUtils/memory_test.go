@@ -0,0 +1,258 @@
+package mqUtils
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordHandler 记录消费组收到的消息，每条消息都标记offset
+type recordHandler struct {
+	received chan *Message
+}
+
+func (h *recordHandler) ConsumeClaim(session GroupSession, claim GroupClaim) error {
+	for {
+		select {
+		case msg := <-claim.Messages():
+			select {
+			case h.received <- msg:
+			case <-session.Context().Done():
+				return nil
+			}
+			session.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1)
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// stuckHandler 不读取消息，直到session结束
+type stuckHandler struct{}
+
+func (stuckHandler) ConsumeClaim(session GroupSession, claim GroupClaim) error {
+	<-session.Context().Done()
+	return nil
+}
+
+func receive(t *testing.T, ch <-chan *Message) *Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func expectNone(t *testing.T, ch <-chan *Message) {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		t.Fatalf("unexpected message %q", msg.Value)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// joinGroup 在协程中加入消费组，等待加入完成后返回SubscribeGroup的结束信号
+func joinGroup(t *testing.T, bus *MemoryBus, ctx context.Context, groupId, topic string, handler GroupHandler) <-chan struct{} {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = bus.SubscribeGroup(ctx, groupId, []string{topic}, handler)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		bus.mu.Lock()
+		g, ok := bus.topic(topic).groups[groupId]
+		joined := ok && g.members > 0
+		bus.mu.Unlock()
+		if joined {
+			return done
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("group %v did not join %v", groupId, topic)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMemoryBusPublishSubscribe(t *testing.T) {
+	bus := NewMemoryBus(4)
+	defer bus.Close()
+	received := make(chan *Message, 4)
+	if err := bus.Subscribe("play", func(msg *Message) { received <- msg }); err != nil {
+		t.Fatalf("Subscribe = %v", err)
+	}
+	for _, value := range []string{"a", "b"} {
+		msg := &Message{Topic: "play", Key: []byte("k"), Value: []byte(value), Headers: map[string]string{"event_id": value}}
+		if err := bus.Publish(context.Background(), msg); err != nil {
+			t.Fatalf("Publish = %v", err)
+		}
+	}
+	for i, value := range []string{"a", "b"} {
+		msg := receive(t, received)
+		if string(msg.Value) != value || msg.Offset != int64(i) || msg.Header("event_id") != value {
+			t.Fatalf("message %d = %q offset %d header %q", i, msg.Value, msg.Offset, msg.Header("event_id"))
+		}
+		if msg.Timestamp.IsZero() {
+			t.Fatalf("message %d has no timestamp", i)
+		}
+	}
+	// 其他topic的消息不会收到
+	if err := bus.Publish(context.Background(), &Message{Topic: "favorite", Value: []byte("c")}); err != nil {
+		t.Fatalf("Publish = %v", err)
+	}
+	expectNone(t, received)
+}
+
+func TestMemoryBusGroupFanOut(t *testing.T) {
+	bus := NewMemoryBus(8)
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hot1 := &recordHandler{received: make(chan *Message, 8)}
+	hot2 := &recordHandler{received: make(chan *Message, 8)}
+	creator := &recordHandler{received: make(chan *Message, 8)}
+	joinGroup(t, bus, ctx, "hot", "favorite_applied", hot1)
+	joinGroup(t, bus, ctx, "hot", "favorite_applied", hot2)
+	joinGroup(t, bus, ctx, "creator", "favorite_applied", creator)
+
+	const n = 6
+	for i := 0; i < n; i++ {
+		if err := bus.Publish(context.Background(), &Message{Topic: "favorite_applied", Value: []byte{byte('0' + i)}}); err != nil {
+			t.Fatalf("Publish = %v", err)
+		}
+	}
+	// 每个消费组都收到全部消息，同一消费组的成员分摊消息
+	seen := make(map[int64]bool)
+	for i := 0; i < n; i++ {
+		var msg *Message
+		select {
+		case msg = <-hot1.received:
+		case msg = <-hot2.received:
+		case <-time.After(time.Second):
+			t.Fatalf("hot group received %d messages, want %d", i, n)
+		}
+		if seen[msg.Offset] {
+			t.Fatalf("offset %d delivered twice in hot group", msg.Offset)
+		}
+		seen[msg.Offset] = true
+	}
+	expectNone(t, hot1.received)
+	expectNone(t, hot2.received)
+	for i := 0; i < n; i++ {
+		if msg := receive(t, creator.received); msg.Offset != int64(i) {
+			t.Fatalf("creator group message %d offset = %d", i, msg.Offset)
+		}
+	}
+}
+
+func TestMemoryBusPublishAfterGroupLeft(t *testing.T) {
+	bus := NewMemoryBus(1)
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	// 不读取消息的消费者，channel满后Publish等待
+	left := joinGroup(t, bus, ctx, "play", "play", stuckHandler{})
+	if err := bus.Publish(context.Background(), &Message{Topic: "play", Value: []byte("1")}); err != nil {
+		t.Fatalf("Publish = %v", err)
+	}
+	published := make(chan error, 1)
+	go func() {
+		published <- bus.Publish(context.Background(), &Message{Topic: "play", Value: []byte("2")})
+	}()
+	select {
+	case err := <-published:
+		t.Fatalf("Publish to a full group returned %v before the group left", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// 消费组的成员全部退出后，等待中的Publish返回，之后的发送不再投递给该消费组
+	cancel()
+	<-left
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatalf("Publish after group left = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked after the group left")
+	}
+	if err := bus.Publish(context.Background(), &Message{Topic: "play", Value: []byte("3")}); err != nil {
+		t.Fatalf("Publish = %v", err)
+	}
+}
+
+func TestMemoryBusPublishContextCanceled(t *testing.T) {
+	bus := NewMemoryBus(1)
+	defer bus.Close()
+	block := make(chan struct{})
+	defer close(block)
+	if err := bus.Subscribe("play", func(msg *Message) { <-block }); err != nil {
+		t.Fatalf("Subscribe = %v", err)
+	}
+	// 第一条消息被订阅者取走后阻塞，第二条填满channel
+	for i := 0; i < 2; i++ {
+		if err := bus.Publish(context.Background(), &Message{Topic: "play"}); err != nil {
+			t.Fatalf("Publish = %v", err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		bus.mu.Lock()
+		full := len(bus.topic("play").subscribers[0]) == 1
+		bus.mu.Unlock()
+		if full {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber channel is not full")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Publish(ctx, &Message{Topic: "play"}); err != context.DeadlineExceeded {
+		t.Fatalf("Publish to a full subscriber = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestMemoryBusClose(t *testing.T) {
+	bus := NewMemoryBus(1)
+	handler := &recordHandler{received: make(chan *Message, 1)}
+	left := joinGroup(t, bus, context.Background(), "hot", "favorite_applied", handler)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 关闭时可能仍有发送在等待投递，此时返回ErrClosed
+		for i := 0; i < 3; i++ {
+			if err := bus.Publish(context.Background(), &Message{Topic: "favorite_applied"}); err != nil {
+				if err != ErrClosed {
+					t.Errorf("Publish while closing = %v, want ErrClosed", err)
+				}
+				return
+			}
+		}
+	}()
+	receive(t, handler.received)
+	if err := bus.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	// 关闭后消费组退出，发送返回ErrClosed
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("SubscribeGroup did not return after Close")
+	}
+	wg.Wait()
+	if err := bus.Publish(context.Background(), &Message{Topic: "favorite_applied"}); err != ErrClosed {
+		t.Fatalf("Publish after Close = %v, want ErrClosed", err)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatalf("second Close = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package mqUtils
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrClosed 消息队列已经关闭
+var ErrClosed = errors.New("message queue closed")
+
+// Message 消息队列中的一条消息，与具体的实现无关
+type Message struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Partition int32     // 由实现在发送或消费时填写
+	Offset    int64     // 由实现在发送或消费时填写
+	Timestamp time.Time // 为空时由实现填写发送的时间
+}
+
+// Header 获取消息Header中key对应的值，不存在时返回空串
+func (m *Message) Header(key string) string {
+	if m.Headers == nil {
+		return ""
+	}
+	return m.Headers[key]
+}
+
+// Publisher 向topic发送消息
+type Publisher interface {
+	// Publish 同步发送一条消息，成功后msg的Partition与Offset为消息所在的位置
+	Publish(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// GroupSession 消费组的一次分配，分配变化时Context结束
+type GroupSession interface {
+	Context() context.Context
+	// MarkOffset 标记分区中offset之前的消息都已处理完成，由实现决定何时提交
+	MarkOffset(topic string, partition int32, offset int64)
+}
+
+// GroupClaim 分配给消费者的一个分区
+type GroupClaim interface {
+	Topic() string
+	Partition() int32
+	Messages() <-chan *Message
+}
+
+// GroupHandler 处理消费组分配到的分区，每个分区在单独的协程中调用ConsumeClaim
+// ConsumeClaim应在Messages关闭或者session的Context结束时返回
+type GroupHandler interface {
+	ConsumeClaim(session GroupSession, claim GroupClaim) error
+}
+
+// Subscriber 订阅topic中的消息
+type Subscriber interface {
+	// Subscribe 从最新的消息开始广播消费topic，每个订阅者都会收到全部消息，已处理的位置不会保存
+	Subscribe(topic string, handler func(msg *Message)) error
+	// SubscribeGroup 加入groupId消费组消费topics，同一消费组的订阅者分摊消息，出错时重新加入，直到ctx结束
+	SubscribeGroup(ctx context.Context, groupId string, topics []string, handler GroupHandler) error
+	Close() error
+}
@@ -0,0 +1,58 @@
+package pageUtils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"strconv"
+)
+
+// Cursor 列表分页的游标，编码后对客户端不透明
+// 数据库中的列表按主键倒序进行keyset分页，使用Seq；redis中的列表按下标窗口分页，使用Offset
+type Cursor struct {
+	Seq    int64 `json:"s,omitempty"` // 上一页最后一条记录的主键
+	Offset int64 `json:"o,omitempty"` // 已经返回的记录条数
+}
+
+// Encode 将游标编码为字符串，零值游标编码为空字符串
+func (c Cursor) Encode() string {
+	if c.Seq == 0 && c.Offset == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode 解析客户端传入的游标，空字符串表示第一页
+// 可能返回的错误类型：InvalidCursorErr
+func Decode(s string) (Cursor, error) {
+	var c Cursor
+	if s == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, constants.InvalidCursorErr
+	}
+	if err = json.Unmarshal(data, &c); err != nil || c.Seq < 0 || c.Offset < 0 {
+		return Cursor{}, constants.InvalidCursorErr
+	}
+	return c, nil
+}
+
+// ParseLimit 解析每页数量，为空时使用默认值，超过上限时取上限
+// 可能返回的错误类型：InvalidCursorErr
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return initialization.PageConf.DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit <= 0 {
+		return 0, constants.InvalidCursorErr
+	}
+	if limit > initialization.PageConf.MaxLimit {
+		limit = initialization.PageConf.MaxLimit
+	}
+	return limit, nil
+}
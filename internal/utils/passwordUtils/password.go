@@ -0,0 +1,130 @@
+package passwordUtils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/md5"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidCost      = errors.New("invalid password hash cost")
+	errArgon2Format     = errors.New("invalid argon2id hash")
+)
+
+// Options 密码哈希的配置
+type Options struct {
+	Algorithm     string // 新密码使用的算法，Bcrypt或Argon2id
+	BcryptCost    int    // bcrypt的计算代价，取值[4, 31]
+	Argon2Time    uint32 // argon2id的迭代次数
+	Argon2Memory  uint32 // argon2id使用的内存(KiB)
+	Argon2Threads uint8  // argon2id的并行度
+	LegacyMD5     bool   // 未升级的旧密码是否为不加盐的MD5，为false时旧密码为明文
+}
+
+// Hasher 使用随机盐的bcrypt或argon2id保存密码，并能校验旧版本以明文或MD5保存的密码
+type Hasher struct {
+	opts Options
+}
+
+// NewHasher 创建一个Hasher，算法或代价不合法时返回错误
+func NewHasher(opts Options) (*Hasher, error) {
+	switch opts.Algorithm {
+	case Bcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return nil, ErrInvalidCost
+		}
+	case Argon2id:
+		if opts.Argon2Time == 0 || opts.Argon2Memory == 0 || opts.Argon2Threads == 0 {
+			return nil, ErrInvalidCost
+		}
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+	return &Hasher{opts: opts}, nil
+}
+
+// Hash 使用配置的算法计算密码的哈希，结果中包含算法、参数与盐
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.opts.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		return string(hash), err
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.opts.Argon2Time, h.opts.Argon2Memory, h.opts.Argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		h.opts.Argon2Memory, h.opts.Argon2Time, h.opts.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验password与保存的encoded是否匹配
+// rehash表示校验成功但encoded是旧的明文/MD5，或者算法、代价与当前配置不同，调用方应使用Hash的结果替换
+func (h *Hasher) Verify(encoded, password string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(encoded, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return true, h.opts.Algorithm != Bcrypt || err != nil || cost != h.opts.BcryptCost
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, salt, key, err := decodeArgon2(encoded)
+		if err != nil {
+			return false, false
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(actual, key) != 1 {
+			return false, false
+		}
+		return true, h.opts.Algorithm != Argon2id || params.Argon2Time != h.opts.Argon2Time ||
+			params.Argon2Memory != h.opts.Argon2Memory || params.Argon2Threads != h.opts.Argon2Threads
+	default:
+		// 旧版本的密码，校验成功后总是需要升级
+		if h.opts.LegacyMD5 {
+			password = md5.MD5(password)
+		}
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(password)) == 1, true
+	}
+}
+
+// decodeArgon2 解析$argon2id$v=19$m=65536,t=1,p=2$salt$key格式的哈希
+func decodeArgon2(encoded string) (*Options, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errArgon2Format
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errArgon2Format
+	}
+	params := &Options{Algorithm: Argon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return nil, nil, nil, errArgon2Format
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errArgon2Format
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errArgon2Format
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,93 @@
+package passwordUtils
+
+import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/md5"
+	"strings"
+	"testing"
+)
+
+var (
+	bcryptOptions = Options{Algorithm: Bcrypt, BcryptCost: 4}
+	argon2Options = Options{Algorithm: Argon2id, Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1}
+)
+
+func newTestHasher(t *testing.T, opts Options) *Hasher {
+	t.Helper()
+	h, err := NewHasher(opts)
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	return h
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, opts := range []Options{bcryptOptions, argon2Options} {
+		h := newTestHasher(t, opts)
+		first, err := h.Hash("douyin123")
+		if err != nil {
+			t.Fatalf("%v Hash: %v", opts.Algorithm, err)
+		}
+		second, _ := h.Hash("douyin123")
+		if first == second {
+			t.Fatalf("%v hashes of the same password are equal, salt not used", opts.Algorithm)
+		}
+		if ok, rehash := h.Verify(first, "douyin123"); !ok || rehash {
+			t.Fatalf("%v Verify = %v, %v, want true, false", opts.Algorithm, ok, rehash)
+		}
+		if ok, _ := h.Verify(first, "douyin124"); ok {
+			t.Fatalf("%v wrong password verified", opts.Algorithm)
+		}
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	plain := newTestHasher(t, bcryptOptions)
+	if ok, rehash := plain.Verify("douyin123", "douyin123"); !ok || !rehash {
+		t.Fatalf("plaintext Verify = %v, %v, want true, true", ok, rehash)
+	}
+	legacyMD5 := bcryptOptions
+	legacyMD5.LegacyMD5 = true
+	h := newTestHasher(t, legacyMD5)
+	if ok, rehash := h.Verify(md5.MD5("douyin123"), "douyin123"); !ok || !rehash {
+		t.Fatalf("md5 Verify = %v, %v, want true, true", ok, rehash)
+	}
+	// 知道MD5的值不能直接登录
+	if ok, _ := h.Verify(md5.MD5("douyin123"), md5.MD5("douyin123")); ok {
+		t.Fatalf("md5 value accepted as password")
+	}
+}
+
+func TestVerifyRehashAfterConfigChange(t *testing.T) {
+	encoded, _ := newTestHasher(t, bcryptOptions).Hash("douyin123")
+	stronger := bcryptOptions
+	stronger.BcryptCost = 5
+	if ok, rehash := newTestHasher(t, stronger).Verify(encoded, "douyin123"); !ok || !rehash {
+		t.Fatalf("Verify with higher cost = %v, %v, want true, true", ok, rehash)
+	}
+	if ok, rehash := newTestHasher(t, argon2Options).Verify(encoded, "douyin123"); !ok || !rehash {
+		t.Fatalf("Verify bcrypt hash with argon2id = %v, %v, want true, true", ok, rehash)
+	}
+}
+
+func TestVerifyMalformedArgon2(t *testing.T) {
+	h := newTestHasher(t, argon2Options)
+	encoded, _ := h.Hash("douyin123")
+	for _, malformed := range []string{
+		strings.Replace(encoded, "v=19", "v=16", 1),
+		encoded[:strings.LastIndex(encoded, "$")],
+		"$argon2id$",
+	} {
+		if ok, _ := h.Verify(malformed, "douyin123"); ok {
+			t.Fatalf("malformed hash %q verified", malformed)
+		}
+	}
+}
+
+func TestNewHasherInvalid(t *testing.T) {
+	if _, err := NewHasher(Options{Algorithm: "sha1"}); err != ErrUnknownAlgorithm {
+		t.Fatalf("NewHasher unknown algorithm = %v", err)
+	}
+	if _, err := NewHasher(Options{Algorithm: Bcrypt, BcryptCost: 40}); err != ErrInvalidCost {
+		t.Fatalf("NewHasher invalid cost = %v", err)
+	}
+}
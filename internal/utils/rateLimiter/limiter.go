@@ -0,0 +1,179 @@
+package rateLimiter
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+const (
+	keyPrefix = "ratelimit_"
+
+	sweepInterval = time.Minute // 进程内限流器清理空闲key的间隔
+
+	// tokenBucketScript 令牌桶，返回0表示允许，否则返回需要等待的时长(毫秒)
+	tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("hmget", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) / interval)
+local wait = 0
+if tokens >= 1 then
+    tokens = tokens - 1
+else
+    wait = math.ceil((1 - tokens) * interval)
+end
+redis.call("hset", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("pexpire", KEYS[1], math.ceil(capacity * interval) + 1000)
+return wait`
+
+	// slidingWindowScript 滑动窗口，以请求时间为score记录窗口内的请求，返回0表示允许，否则返回需要等待的时长(毫秒)
+	slidingWindowScript = `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call("zremrangebyscore", KEYS[1], "-inf", now - window)
+if redis.call("zcard", KEYS[1]) < limit then
+    redis.call("zadd", KEYS[1], now, ARGV[4])
+    redis.call("pexpire", KEYS[1], window)
+    return 0
+end
+local oldest = redis.call("zrange", KEYS[1], 0, 0, "withscores")
+return math.max(1, tonumber(oldest[2]) + window - now)`
+)
+
+// Limiter 限流器，Allow允许请求时返回0，否则返回客户端需要等待的时长
+type Limiter interface {
+	Allow(ctx context.Context, key string) (time.Duration, error)
+}
+
+// NewLimiter 按规则创建限流器，client为nil时在进程内计数，否则在redis中计数，所有实例共用限额
+func NewLimiter(rule Rule, client *redis.Client) Limiter {
+	if client == nil {
+		return newLocalLimiter(rule)
+	}
+	return newRedisLimiter(rule, client)
+}
+
+// redisLimiter 在redis中计数的限流器，使用本机时间，各实例的时钟偏差会影响限流的精度
+type redisLimiter struct {
+	rule   Rule
+	client *redis.Client
+	script *redis.Script
+}
+
+func newRedisLimiter(rule Rule, client *redis.Client) *redisLimiter {
+	script := tokenBucketScript
+	if rule.Algorithm == SlidingWindow {
+		script = slidingWindowScript
+	}
+	return &redisLimiter{rule: rule, client: client, script: redis.NewScript(script)}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	now := time.Now().UnixMilli()
+	var args []interface{}
+	if l.rule.Algorithm == SlidingWindow {
+		args = []interface{}{l.rule.Limit, l.rule.Window.Milliseconds(), now, uuid.New().String()}
+	} else {
+		interval := float64(l.rule.interval()) / float64(time.Millisecond)
+		args = []interface{}{l.rule.capacity(), interval, now}
+	}
+	wait, err := l.script.Run(ctx, l.client, []string{keyPrefix + key}, args...).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// localLimiter 在进程内计数的限流器，多实例部署时每个实例单独计算限额
+type localLimiter struct {
+	rule      Rule
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string][]time.Time
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLocalLimiter(rule Rule) *localLimiter {
+	return &localLimiter{
+		rule:      rule,
+		buckets:   make(map[string]*bucket),
+		windows:   make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *localLimiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	if l.rule.Algorithm == SlidingWindow {
+		return l.allowWindow(key, now), nil
+	}
+	return l.allowBucket(key, now), nil
+}
+
+func (l *localLimiter) allowBucket(key string, now time.Time) time.Duration {
+	capacity, interval := float64(l.rule.capacity()), l.rule.interval()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += float64(now.Sub(b.last)) / float64(interval)
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(interval))
+}
+
+func (l *localLimiter) allowWindow(key string, now time.Time) time.Duration {
+	requests := l.windows[key]
+	start := now.Add(-l.rule.Window)
+	i := 0
+	for i < len(requests) && !requests[i].After(start) {
+		i++
+	}
+	requests = requests[i:]
+	if len(requests) >= l.rule.Limit {
+		l.windows[key] = requests
+		return requests[0].Add(l.rule.Window).Sub(now)
+	}
+	l.windows[key] = append(requests, now)
+	return 0
+}
+
+// sweep 清理令牌已经补满的令牌桶与窗口内没有请求的key
+func (l *localLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	full := l.rule.interval() * time.Duration(l.rule.capacity())
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+	for key, requests := range l.windows {
+		if len(requests) == 0 || now.Sub(requests[len(requests)-1]) >= l.rule.Window {
+			delete(l.windows, key)
+		}
+	}
+}
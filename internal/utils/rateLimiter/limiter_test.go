@@ -0,0 +1,91 @@
+package rateLimiter
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("user token_bucket 10/1m 20")
+	if err != nil || rule != (Rule{Key: KeyUser, Algorithm: TokenBucket, Limit: 10, Window: time.Minute, Burst: 20}) {
+		t.Fatalf("ParseRule = %+v, %v", rule, err)
+	}
+	for _, s := range []string{"", "user token_bucket", "device token_bucket 1/1s", "ip leaky_bucket 1/1s",
+		"ip sliding_window 0/1s", "ip sliding_window 1/x", "ip sliding_window 1", "ip token_bucket 1/1s -1"} {
+		if _, err = ParseRule(s); err != ErrInvalidRule {
+			t.Fatalf("ParseRule(%q) = %v, want ErrInvalidRule", s, err)
+		}
+	}
+}
+
+// limiters 返回进程内与redis中计数的两种限流器
+func limiters(t *testing.T, rule Rule) map[string]Limiter {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return map[string]Limiter{
+		"local": NewLimiter(rule, nil),
+		"redis": NewLimiter(rule, client),
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	rule := Rule{Key: KeyIp, Algorithm: TokenBucket, Limit: 10, Window: time.Second, Burst: 3}
+	for name, limiter := range limiters(t, rule) {
+		ctx := context.Background()
+		for i := 0; i < 3; i++ {
+			if wait, err := limiter.Allow(ctx, "a"); err != nil || wait != 0 {
+				t.Fatalf("%s: burst request #%d = %v, %v, want allowed", name, i, wait, err)
+			}
+		}
+		wait, err := limiter.Allow(ctx, "a")
+		if err != nil || wait <= 0 || wait > 100*time.Millisecond {
+			t.Fatalf("%s: request over burst = %v, %v, want wait in (0, 100ms]", name, wait, err)
+		}
+		if wait, _ = limiter.Allow(ctx, "b"); wait != 0 {
+			t.Fatalf("%s: other key limited", name)
+		}
+		time.Sleep(120 * time.Millisecond)
+		if wait, _ = limiter.Allow(ctx, "a"); wait != 0 {
+			t.Fatalf("%s: token not refilled, wait %v", name, wait)
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rule := Rule{Key: KeyUser, Algorithm: SlidingWindow, Limit: 2, Window: 200 * time.Millisecond}
+	for name, limiter := range limiters(t, rule) {
+		ctx := context.Background()
+		limiter.Allow(ctx, "a")
+		time.Sleep(100 * time.Millisecond)
+		limiter.Allow(ctx, "a")
+		wait, err := limiter.Allow(ctx, "a")
+		if err != nil || wait <= 0 || wait > 100*time.Millisecond {
+			t.Fatalf("%s: request over limit = %v, %v, want wait in (0, 100ms]", name, wait, err)
+		}
+		// 第一个请求移出窗口后允许一个请求
+		time.Sleep(wait + 10*time.Millisecond)
+		if wait, _ = limiter.Allow(ctx, "a"); wait != 0 {
+			t.Fatalf("%s: request after oldest expired limited, wait %v", name, wait)
+		}
+		if wait, _ = limiter.Allow(ctx, "a"); wait <= 0 {
+			t.Fatalf("%s: request over limit allowed", name)
+		}
+	}
+}
+
+func TestLocalSweep(t *testing.T) {
+	limiter := newLocalLimiter(Rule{Key: KeyIp, Algorithm: SlidingWindow, Limit: 1, Window: time.Millisecond})
+	limiter.Allow(context.Background(), "a")
+	time.Sleep(2 * time.Millisecond)
+	limiter.sweep(time.Now())
+	if len(limiter.windows) != 0 {
+		t.Fatalf("idle key not swept: %v", limiter.windows)
+	}
+}
@@ -0,0 +1,82 @@
+package rateLimiter
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	KeyIp    = "ip"    // 按客户端IP限流
+	KeyUser  = "user"  // 按登录用户限流，未登录时按IP
+	KeyRoute = "route" // 接口的所有请求共用一个限额
+
+	TokenBucket   = "token_bucket"   // 令牌桶，允许不超过Burst的突发请求
+	SlidingWindow = "sliding_window" // 滑动窗口，任意Window时长内不超过Limit个请求
+)
+
+var ErrInvalidRule = errors.New("invalid rate limit rule, want \"<ip|user|route> <token_bucket|sliding_window> <limit>/<window> [burst]\"")
+
+// Rule 一条限流规则
+type Rule struct {
+	Key       string        // KeyIp、KeyUser或KeyRoute
+	Algorithm string        // TokenBucket或SlidingWindow
+	Limit     int           // Window内允许的请求数
+	Window    time.Duration // 统计窗口，令牌桶中每Window/Limit补充一个令牌
+	Burst     int           // 令牌桶的容量，为0时等于Limit；滑动窗口忽略该值
+}
+
+// ParseRule 解析配置中的限流规则，例如"user sliding_window 5/1m"或"ip token_bucket 20/1s 40"
+func ParseRule(s string) (Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 && len(fields) != 4 {
+		return Rule{}, ErrInvalidRule
+	}
+	rule := Rule{Key: fields[0], Algorithm: fields[1]}
+	switch rule.Key {
+	case KeyIp, KeyUser, KeyRoute:
+	default:
+		return Rule{}, ErrInvalidRule
+	}
+	switch rule.Algorithm {
+	case TokenBucket, SlidingWindow:
+	default:
+		return Rule{}, ErrInvalidRule
+	}
+	limit, window, ok := strings.Cut(fields[2], "/")
+	if !ok {
+		return Rule{}, ErrInvalidRule
+	}
+	var err error
+	if rule.Limit, err = strconv.Atoi(limit); err != nil || rule.Limit <= 0 {
+		return Rule{}, ErrInvalidRule
+	}
+	if rule.Window, err = time.ParseDuration(window); err != nil || rule.Window <= 0 {
+		return Rule{}, ErrInvalidRule
+	}
+	if len(fields) == 4 {
+		if rule.Burst, err = strconv.Atoi(fields[3]); err != nil || rule.Burst <= 0 {
+			return Rule{}, ErrInvalidRule
+		}
+	}
+	return rule, nil
+}
+
+// RetryAfter 将需要等待的时长转换为Retry-After头部的秒数，不足一秒时向上取整
+func RetryAfter(wait time.Duration) string {
+	return strconv.FormatInt(int64((wait+time.Second-1)/time.Second), 10)
+}
+
+// capacity 令牌桶的容量
+func (r Rule) capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// interval 令牌桶补充一个令牌的间隔
+func (r Rule) interval() time.Duration {
+	return r.Window / time.Duration(r.Limit)
+}
@@ -9,11 +9,146 @@ const (
 	resetTTLInterval = ttl / 3
 	// 重新获取锁间隔
 	tryLockInterval = time.Second
+	// 公平锁的等待者超过该倍数的重新获取锁间隔没有刷新时视为已放弃
+	fairWaiterTimeoutFactor = 3
+	// 解锁时发布消息的频道后缀，等待者订阅该频道
+	lockChannelSuffix = "_channel"
+	// 公平锁等待队列的后缀，list，按到达顺序保存等待者
+	fairQueueSuffix = "_queue"
+	// 公平锁等待者超时时间的后缀，zset，分数为等待者的超时时间(毫秒)
+	fairTimeoutSuffix = "_timeout"
+	// 读写锁中记录模式的字段
+	rwModeField = "mode"
+	// 红锁的时钟漂移为过期时间的1%再加2毫秒
+	clockDriftFactor   = 0.01
+	clockDriftConstant = 2 * time.Millisecond
+	// 红锁在单个节点上操作的超时时间为过期时间的1/10
+	nodeTimeoutDivisor = 10
 	//解锁脚本
 	unlockScript = `
 if redis.call("get",KEYS[1]) == ARGV[1] then
     return redis.call("del",KEYS[1])
 else
     return 0
+end`
+	//解锁脚本，解锁成功后通知等待者
+	unlockNotifyScript = `
+if redis.call("get",KEYS[1]) == ARGV[1] then
+    redis.call("del",KEYS[1])
+    redis.call("publish",ARGV[2],"unlock")
+    return 1
+else
+    return 0
+end`
+	//可重入锁加锁脚本，hash中保存持有者及其重入次数
+	reentrantLockScript = `
+if redis.call("exists",KEYS[1]) == 0 or redis.call("hexists",KEYS[1],ARGV[1]) == 1 then
+    redis.call("hincrby",KEYS[1],ARGV[1],1)
+    redis.call("pexpire",KEYS[1],ARGV[2])
+    return 1
+end
+return 0`
+	//读锁加锁脚本，没有写锁时所有读者都可以加锁
+	readLockScript = `
+local mode = redis.call("hget",KEYS[1],"` + rwModeField + `")
+if mode == false then
+    redis.call("hset",KEYS[1],"` + rwModeField + `","read")
+    mode = "read"
+end
+if mode == "read" then
+    redis.call("hincrby",KEYS[1],ARGV[1],1)
+    redis.call("pexpire",KEYS[1],ARGV[2])
+    return 1
+end
+return 0`
+	//写锁加锁脚本，只有没有任何读者和写者时才能加锁，写锁可重入
+	writeLockScript = `
+local mode = redis.call("hget",KEYS[1],"` + rwModeField + `")
+if mode == false then
+    redis.call("hset",KEYS[1],"` + rwModeField + `","write")
+    mode = "write"
+elseif mode ~= "write" or redis.call("hexists",KEYS[1],ARGV[1]) == 0 then
+    return 0
+end
+redis.call("hincrby",KEYS[1],ARGV[1],1)
+redis.call("pexpire",KEYS[1],ARGV[2])
+return 1`
+	//hash锁的解锁脚本，重入次数减为0时删除持有者，没有其他持有者时删除锁并通知等待者
+	//返回-1表示锁不由自己持有
+	hashUnlockScript = `
+if redis.call("hexists",KEYS[1],ARGV[1]) == 0 then
+    return -1
+end
+local count = redis.call("hincrby",KEYS[1],ARGV[1],-1)
+if count <= 0 then
+    redis.call("hdel",KEYS[1],ARGV[1])
+    count = 0
+end
+local holders = redis.call("hlen",KEYS[1])
+if redis.call("hexists",KEYS[1],"` + rwModeField + `") == 1 then
+    holders = holders - 1
+end
+if holders > 0 then
+    redis.call("pexpire",KEYS[1],ARGV[2])
+    return count
+end
+redis.call("del",KEYS[1])
+redis.call("publish",ARGV[3],"unlock")
+return 0`
+	//hash锁的续期脚本
+	hashRenewScript = `
+if redis.call("hexists",KEYS[1],ARGV[1]) == 1 then
+    return redis.call("pexpire",KEYS[1],ARGV[2])
+else
+    return 0
+end`
+	//公平锁加锁脚本，KEYS为锁、等待队列、等待者超时时间，ARGV为持有者、过期时间、当前时间、等待超时时间、是否排队
+	//先移除队首已经超时的等待者，锁空闲且自己位于队首(或队列为空)时加锁成功，否则按需排队并刷新等待的超时时间
+	fairLockScript = `
+local now = tonumber(ARGV[3])
+while true do
+    local first = redis.call("lindex",KEYS[2],0)
+    if first == false then
+        break
+    end
+    local deadline = redis.call("zscore",KEYS[3],first)
+    if deadline ~= false and tonumber(deadline) > now then
+        break
+    end
+    redis.call("lpop",KEYS[2])
+    redis.call("zrem",KEYS[3],first)
+end
+if redis.call("exists",KEYS[1]) == 0 then
+    local first = redis.call("lindex",KEYS[2],0)
+    if first == false or first == ARGV[1] then
+        redis.call("set",KEYS[1],ARGV[1],"PX",ARGV[2])
+        if first ~= false then
+            redis.call("lpop",KEYS[2])
+            redis.call("zrem",KEYS[3],ARGV[1])
+        end
+        return 1
+    end
+end
+if ARGV[5] == "1" then
+    if redis.call("zscore",KEYS[3],ARGV[1]) == false then
+        redis.call("rpush",KEYS[2],ARGV[1])
+    end
+    redis.call("zadd",KEYS[3],now + tonumber(ARGV[4]),ARGV[1])
+    redis.call("pexpire",KEYS[2],ARGV[4])
+    redis.call("pexpire",KEYS[3],ARGV[4])
+end
+return 0`
+	//公平锁放弃等待的脚本，离开队列后通知其他等待者
+	fairLeaveScript = `
+redis.call("lrem",KEYS[1],0,ARGV[1])
+redis.call("zrem",KEYS[2],ARGV[1])
+redis.call("publish",ARGV[2],"leave")
+return 1`
+	//续期脚本，只有锁仍由自己持有时才延长过期时间
+	renewScript = `
+if redis.call("get",KEYS[1]) == ARGV[1] then
+    return redis.call("pexpire",KEYS[1],ARGV[2])
+else
+    return 0
 end`
 )
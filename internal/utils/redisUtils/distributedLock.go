@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
 	"github.com/go-redis/redis/v8"
+	"sync"
 	"time"
 )
 
@@ -29,32 +30,7 @@ type Lock struct {
 }
 
 func (l *Lock) Lock(ctx context.Context) error {
-	// 尝试加锁
-	err := l.TryLock(ctx)
-	if err == nil {
-		return nil
-	}
-	if !errors.Is(constants.LockFailedErr, err) {
-		return err
-	}
-	//加锁失败不断尝试
-	ticker := time.NewTicker(l.tryLockInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			// 超时
-			return constants.TimeOutErr
-		case <-ticker.C:
-			err := l.TryLock(ctx)
-			if err != nil {
-				return nil
-			}
-			if !errors.Is(constants.LockFailedErr, err) {
-				return err
-			}
-		}
-	}
+	return waitLock(ctx, l.client, l.resource, l.tryLockInterval, l.TryLock)
 }
 
 func (l *Lock) TryLock(ctx context.Context) error {
@@ -78,10 +54,10 @@ func (l *Lock) startWatchDog() {
 		case <-ticker.C:
 			// 延长锁的过期时间
 			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3*2)
-			ok, err := l.client.Expire(ctx, l.resource, l.ttl).Result()
+			n, err := renew.Run(ctx, l.client, []string{l.resource}, l.randomValue, l.ttl.Milliseconds()).Int()
 			cancel()
-			// 异常或锁已经不存在则不再续期
-			if err != nil || !ok {
+			// 异常或锁已经不由自己持有则不再续期
+			if err != nil || n != 1 {
 				return
 			}
 		case <-l.watchDog:
@@ -92,8 +68,132 @@ func (l *Lock) startWatchDog() {
 }
 
 func (l *Lock) UnLock(ctx context.Context) error {
-	err := l.script.Run(ctx, l.client, []string{l.resource}, l.randomValue).Err()
+	err := l.script.Run(ctx, l.client, []string{l.resource}, l.randomValue, l.resource+lockChannelSuffix).Err()
 	// 关闭看门狗
 	close(l.watchDog)
 	return err
 }
+
+// waitLock 不断尝试加锁直到成功或ctx结束
+// 加锁失败后订阅锁的解锁频道，锁释放时立即重试；订阅失败或错过消息时每隔tryLockInterval轮询一次
+func waitLock(ctx context.Context, client *redis.Client, resource string, tryLockInterval time.Duration, tryLock func(context.Context) error) error {
+	err := tryLock(ctx)
+	if !errors.Is(constants.LockFailedErr, err) {
+		return err
+	}
+	pubsub := client.Subscribe(ctx, resource+lockChannelSuffix)
+	defer pubsub.Close()
+	var wakeup <-chan *redis.Message
+	if _, err = pubsub.Receive(ctx); err == nil {
+		wakeup = pubsub.Channel()
+	}
+	ticker := time.NewTicker(tryLockInterval)
+	defer ticker.Stop()
+	for {
+		// 订阅成功后先重试一次，避免错过订阅之前的解锁消息
+		err = tryLock(ctx)
+		if err != nil && deadlineExceeded(ctx) {
+			// 超时
+			return constants.TimeOutErr
+		}
+		if !errors.Is(constants.LockFailedErr, err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			// 超时
+			return constants.TimeOutErr
+		case <-wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deadlineExceeded ctx是否已经结束
+// redis客户端按ctx的截止时间设置连接的超时，连接超时可能略早于ctx结束，因此同时比较截止时间
+func deadlineExceeded(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+// hashLock 以hash保存持有者及其重入次数的锁，可重入锁与读写锁共用，区别只在于加锁脚本
+type hashLock struct {
+	client          *redis.Client
+	lockScript      *redis.Script // 加锁脚本
+	resource        string        // 锁定的资源
+	owner           string        // 持有者
+	ttl             time.Duration // 过期时间
+	tryLockInterval time.Duration // 重新获取锁间隔
+
+	mu       sync.Mutex
+	count    int           // 本地记录的重入次数
+	watchDog chan struct{} // 看门狗
+}
+
+func (l *hashLock) Lock(ctx context.Context) error {
+	return waitLock(ctx, l.client, l.resource, l.tryLockInterval, l.TryLock)
+}
+
+func (l *hashLock) TryLock(ctx context.Context) error {
+	n, err := l.lockScript.Run(ctx, l.client, []string{l.resource}, l.owner, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	// 加锁失败
+	if n != 1 {
+		return constants.LockFailedErr
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	// 只在第一次加锁时启动看门狗
+	if l.count == 1 {
+		l.watchDog = make(chan struct{})
+		go startHashWatchDog(l.client, l.resource, l.owner, l.ttl, l.watchDog)
+	}
+	return nil
+}
+
+func (l *hashLock) UnLock(ctx context.Context) error {
+	n, err := hashUnlock.Run(ctx, l.client, []string{l.resource}, l.owner, l.ttl.Milliseconds(), l.resource+lockChannelSuffix).Int()
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n < 0 {
+		// 锁已经过期，不再由自己持有
+		l.count = 0
+	} else if l.count > 0 {
+		l.count--
+	}
+	if l.count == 0 && l.watchDog != nil {
+		// 关闭看门狗
+		close(l.watchDog)
+		l.watchDog = nil
+	}
+	return nil
+}
+
+// startHashWatchDog 定时延长hash锁的过期时间，锁不再由owner持有时停止
+func startHashWatchDog(client *redis.Client, resource, owner string, ttl time.Duration, watchDog chan struct{}) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), ttl/3*2)
+			n, err := hashRenew.Run(ctx, client, []string{resource}, owner, ttl.Milliseconds()).Int()
+			cancel()
+			if err != nil || n != 1 {
+				return
+			}
+		case <-watchDog:
+			// 已经解锁
+			return
+		}
+	}
+}
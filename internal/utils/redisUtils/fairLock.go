@@ -0,0 +1,98 @@
+package redisUtils
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+// FairLock 公平锁，等待者在redis的list中按到达顺序排队，锁释放后由队首的等待者获得
+// 等待者需要定时刷新自己的超时时间，崩溃的等待者超时后被移出队列，不会一直阻塞后面的等待者
+type FairLock struct {
+	client          *redis.Client
+	resource        string        // 锁定的资源
+	randomValue     string        // 随机值
+	ttl             time.Duration // 过期时间
+	tryLockInterval time.Duration // 重新获取锁间隔
+
+	mu       sync.Mutex
+	watchDog chan struct{} // 看门狗
+}
+
+// Lock 排队等待加锁，超时后离开队列
+func (l *FairLock) Lock(ctx context.Context) error {
+	err := waitLock(ctx, l.client, l.resource, l.tryLockInterval, func(ctx context.Context) error {
+		return l.acquire(ctx, true)
+	})
+	if err != nil {
+		// 离开队列并通知其他等待者，避免后面的等待者等到超时
+		leaveCtx, cancel := context.WithTimeout(context.Background(), l.tryLockInterval)
+		defer cancel()
+		fairLeave.Run(leaveCtx, l.client, []string{l.resource + fairQueueSuffix, l.resource + fairTimeoutSuffix},
+			l.randomValue, l.resource+lockChannelSuffix)
+	}
+	return err
+}
+
+// TryLock 只在锁空闲且没有其他等待者时加锁成功，失败时不排队
+func (l *FairLock) TryLock(ctx context.Context) error {
+	return l.acquire(ctx, false)
+}
+
+func (l *FairLock) acquire(ctx context.Context, enqueue bool) error {
+	waiterTimeout := l.tryLockInterval * fairWaiterTimeoutFactor
+	flag := "0"
+	if enqueue {
+		flag = "1"
+	}
+	n, err := fairLock.Run(ctx, l.client,
+		[]string{l.resource, l.resource + fairQueueSuffix, l.resource + fairTimeoutSuffix},
+		l.randomValue, l.ttl.Milliseconds(), time.Now().UnixMilli(), waiterTimeout.Milliseconds(), flag).Int()
+	if err != nil {
+		return err
+	}
+	// 加锁失败
+	if n != 1 {
+		return constants.LockFailedErr
+	}
+	l.mu.Lock()
+	l.watchDog = make(chan struct{})
+	watchDog := l.watchDog
+	l.mu.Unlock()
+	go l.startWatchDog(watchDog)
+	return nil
+}
+
+func (l *FairLock) startWatchDog(watchDog chan struct{}) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// 延长锁的过期时间
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3*2)
+			n, err := renew.Run(ctx, l.client, []string{l.resource}, l.randomValue, l.ttl.Milliseconds()).Int()
+			cancel()
+			// 异常或锁已经不由自己持有则不再续期
+			if err != nil || n != 1 {
+				return
+			}
+		case <-watchDog:
+			// 已经解锁
+			return
+		}
+	}
+}
+
+func (l *FairLock) UnLock(ctx context.Context) error {
+	l.mu.Lock()
+	if l.watchDog != nil {
+		// 关闭看门狗
+		close(l.watchDog)
+		l.watchDog = nil
+	}
+	l.mu.Unlock()
+	return unlockNotify.Run(ctx, l.client, []string{l.resource}, l.randomValue, l.resource+lockChannelSuffix).Err()
+}
@@ -7,8 +7,20 @@ import (
 	"time"
 )
 
+var (
+	unlockNotify = redis.NewScript(unlockNotifyScript)
+	renew        = redis.NewScript(renewScript)
+	reentrant    = redis.NewScript(reentrantLockScript)
+	readLock     = redis.NewScript(readLockScript)
+	writeLock    = redis.NewScript(writeLockScript)
+	hashUnlock   = redis.NewScript(hashUnlockScript)
+	hashRenew    = redis.NewScript(hashRenewScript)
+	fairLock     = redis.NewScript(fairLockScript)
+	fairLeave    = redis.NewScript(fairLeaveScript)
+)
+
 // Locker 可以通过Locker获得一把分布式锁
-// 其主要用于配置锁
+// 其主要用于配置锁，普通锁、可重入锁、公平锁与读写锁共用同一套配置
 type Locker struct {
 	client          *redis.Client
 	script          *redis.Script
@@ -20,7 +32,7 @@ type Locker struct {
 func NewDefaultLocker(client *redis.Client) *Locker {
 	return &Locker{
 		client:          client,
-		script:          redis.NewScript(unlockScript),
+		script:          unlockNotify,
 		ttl:             ttl,
 		tryLockInterval: tryLockInterval,
 	}
@@ -30,7 +42,7 @@ func NewDefaultLocker(client *redis.Client) *Locker {
 func NewLocker(client *redis.Client, ttl, tryLockInterval time.Duration) *Locker {
 	return &Locker{
 		client:          client,
-		script:          redis.NewScript(unlockScript),
+		script:          unlockNotify,
 		ttl:             ttl,
 		tryLockInterval: tryLockInterval,
 	}
@@ -47,3 +59,55 @@ func (l *Locker) GetLock(resource string) DistributedLock {
 		tryLockInterval: l.tryLockInterval,
 	}
 }
+
+// GetReentrantLock 获取一把可重入锁，同一把锁可以多次加锁，解锁相同次数后才释放
+func (l *Locker) GetReentrantLock(resource string) DistributedLock {
+	return l.newHashLock(reentrant, resource)
+}
+
+// GetFairLock 获取一把公平锁，等待者按调用Lock的顺序获得锁
+func (l *Locker) GetFairLock(resource string) DistributedLock {
+	return &FairLock{
+		client:          l.client,
+		resource:        resource,
+		randomValue:     uuid.New().String(),
+		ttl:             l.ttl,
+		tryLockInterval: l.tryLockInterval,
+	}
+}
+
+// GetReadWriteLock 获取一把读写锁
+func (l *Locker) GetReadWriteLock(resource string) *ReadWriteLock {
+	return &ReadWriteLock{
+		read:  l.newHashLock(readLock, resource),
+		write: l.newHashLock(writeLock, resource),
+	}
+}
+
+func (l *Locker) newHashLock(lockScript *redis.Script, resource string) *hashLock {
+	return &hashLock{
+		client:          l.client,
+		lockScript:      lockScript,
+		resource:        resource,
+		owner:           uuid.New().String(),
+		ttl:             l.ttl,
+		tryLockInterval: l.tryLockInterval,
+	}
+}
+
+// ReadWriteLock 读写锁，读锁之间共享，写锁与其他任何锁互斥，读锁与写锁都可重入
+// 读锁不能升级为写锁，持有读锁时获取写锁会一直失败
+type ReadWriteLock struct {
+	read  *hashLock
+	write *hashLock
+}
+
+// ReadLock 获取读锁
+func (l *ReadWriteLock) ReadLock() DistributedLock {
+	return l.read
+}
+
+// WriteLock 获取写锁
+func (l *ReadWriteLock) WriteLock() DistributedLock {
+	return l.write
+}
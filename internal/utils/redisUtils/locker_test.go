@@ -0,0 +1,187 @@
+package redisUtils
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLocker(t *testing.T, tryLockInterval time.Duration) *Locker {
+	t.Helper()
+	_, clients := startNodes(t, 1)
+	return NewLocker(clients[0], time.Second, tryLockInterval)
+}
+
+func TestLockWaitsForRelease(t *testing.T) {
+	// 轮询间隔很长，只有收到解锁消息才能及时获得锁
+	locker := newTestLocker(t, time.Minute)
+	holder := locker.GetLock(testResource)
+	if err := holder.TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		holder.UnLock(context.Background())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	waiter := locker.GetLock(testResource)
+	if err := waiter.Lock(ctx); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	waiter.UnLock(context.Background())
+}
+
+func TestLockTimeout(t *testing.T) {
+	locker := newTestLocker(t, 10*time.Millisecond)
+	holder := locker.GetLock(testResource)
+	if err := holder.TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	defer holder.UnLock(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := locker.GetLock(testResource).Lock(ctx); !errors.Is(constants.TimeOutErr, err) {
+		t.Fatalf("Lock = %v, want TimeOutErr", err)
+	}
+}
+
+func TestReentrantLock(t *testing.T) {
+	locker := newTestLocker(t, 10*time.Millisecond)
+	ctx := context.Background()
+	lock := locker.GetReentrantLock(testResource)
+	other := locker.GetReentrantLock(testResource)
+
+	for i := 0; i < 2; i++ {
+		if err := lock.TryLock(ctx); err != nil {
+			t.Fatalf("TryLock %d: %v", i, err)
+		}
+	}
+	if err := other.TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("other TryLock = %v, want LockFailedErr", err)
+	}
+	// 解锁一次后仍然持有
+	lock.UnLock(ctx)
+	if err := other.TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("other TryLock after one UnLock = %v, want LockFailedErr", err)
+	}
+	lock.UnLock(ctx)
+	if err := other.TryLock(ctx); err != nil {
+		t.Fatalf("other TryLock after release: %v", err)
+	}
+	other.UnLock(ctx)
+}
+
+func TestReadWriteLock(t *testing.T) {
+	locker := newTestLocker(t, 10*time.Millisecond)
+	ctx := context.Background()
+	reader1 := locker.GetReadWriteLock(testResource)
+	reader2 := locker.GetReadWriteLock(testResource)
+	writer := locker.GetReadWriteLock(testResource)
+
+	// 读锁之间共享
+	if err := reader1.ReadLock().TryLock(ctx); err != nil {
+		t.Fatalf("reader1 TryLock: %v", err)
+	}
+	if err := reader2.ReadLock().TryLock(ctx); err != nil {
+		t.Fatalf("reader2 TryLock: %v", err)
+	}
+	if err := writer.WriteLock().TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("write TryLock with readers = %v, want LockFailedErr", err)
+	}
+	reader1.ReadLock().UnLock(ctx)
+	if err := writer.WriteLock().TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("write TryLock with one reader = %v, want LockFailedErr", err)
+	}
+	reader2.ReadLock().UnLock(ctx)
+
+	// 写锁与读锁互斥，写锁可重入
+	if err := writer.WriteLock().TryLock(ctx); err != nil {
+		t.Fatalf("write TryLock: %v", err)
+	}
+	if err := writer.WriteLock().TryLock(ctx); err != nil {
+		t.Fatalf("reentrant write TryLock: %v", err)
+	}
+	if err := reader1.ReadLock().TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("read TryLock with writer = %v, want LockFailedErr", err)
+	}
+	writer.WriteLock().UnLock(ctx)
+	writer.WriteLock().UnLock(ctx)
+	if err := reader1.ReadLock().TryLock(ctx); err != nil {
+		t.Fatalf("read TryLock after writer released: %v", err)
+	}
+	reader1.ReadLock().UnLock(ctx)
+}
+
+func TestFairLockOrder(t *testing.T) {
+	locker := newTestLocker(t, 50*time.Millisecond)
+	holder := locker.GetFairLock(testResource)
+	if err := holder.TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+
+	const waiters = 5
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			lock := locker.GetFairLock(testResource)
+			if err := lock.Lock(ctx); err != nil {
+				t.Errorf("waiter %d Lock: %v", i, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			lock.UnLock(context.Background())
+		}(i)
+		// 保证等待者按顺序进入队列
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 队列中有等待者时不能插队
+	if err := locker.GetFairLock(testResource).TryLock(context.Background()); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("TryLock with waiters = %v, want LockFailedErr", err)
+	}
+	holder.UnLock(context.Background())
+	wg.Wait()
+	for i := range order {
+		if order[i] != i {
+			t.Fatalf("waiters acquired in order %v", order)
+		}
+	}
+	if len(order) != waiters {
+		t.Fatalf("%d of %d waiters acquired the lock", len(order), waiters)
+	}
+}
+
+func TestFairLockSkipsAbandonedWaiter(t *testing.T) {
+	locker := newTestLocker(t, 20*time.Millisecond)
+	holder := locker.GetFairLock(testResource)
+	if err := holder.TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	// 第一个等待者超时放弃
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := locker.GetFairLock(testResource).Lock(ctx); !errors.Is(constants.TimeOutErr, err) {
+		t.Fatalf("Lock = %v, want TimeOutErr", err)
+	}
+	holder.UnLock(context.Background())
+
+	// 放弃的等待者已经离开队列，不会阻塞后来者
+	if err := locker.GetFairLock(testResource).TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock after waiter left: %v", err)
+	}
+}
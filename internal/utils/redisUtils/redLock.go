@@ -1,3 +1,200 @@
 package redisUtils
 
-// 红锁，暂时未实现
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RedLocker 可以通过RedLocker获得一把红锁
+// 红锁在N个相互独立的redis节点上加锁，过半数的节点可用时锁仍然可用
+type RedLocker struct {
+	clients         []*redis.Client
+	script          *redis.Script
+	renewScript     *redis.Script
+	ttl             time.Duration
+	tryLockInterval time.Duration
+}
+
+// NewDefaultRedLocker 通过相互独立的clients和默认定义项获取一个RedLocker
+func NewDefaultRedLocker(clients []*redis.Client) *RedLocker {
+	return NewRedLocker(clients, ttl, tryLockInterval)
+}
+
+// NewRedLocker 通过自配置定义项获取RedLocker
+func NewRedLocker(clients []*redis.Client, ttl, tryLockInterval time.Duration) *RedLocker {
+	return &RedLocker{
+		clients:         clients,
+		script:          redis.NewScript(unlockScript),
+		renewScript:     redis.NewScript(renewScript),
+		ttl:             ttl,
+		tryLockInterval: tryLockInterval,
+	}
+}
+
+func (l *RedLocker) GetLock(resource string) DistributedLock {
+	return &RedLock{
+		clients:         l.clients,
+		script:          l.script,
+		renewScript:     l.renewScript,
+		resource:        resource,
+		randomValue:     uuid.New().String(),
+		quorum:          len(l.clients)/2 + 1,
+		ttl:             l.ttl,
+		tryLockInterval: l.tryLockInterval,
+	}
+}
+
+// RedLock 红锁，Redlock算法的实现
+// 并发地在所有节点上加锁，过半数节点加锁成功，且扣除加锁耗时与时钟漂移后锁仍在有效期内时视为加锁成功，否则释放所有节点上的锁
+type RedLock struct {
+	clients         []*redis.Client
+	script          *redis.Script // 解锁脚本
+	renewScript     *redis.Script // 续期脚本
+	resource        string        // 锁定的资源
+	randomValue     string        // 随机值
+	quorum          int           // 需要加锁成功的节点数
+	ttl             time.Duration // 过期时间
+	tryLockInterval time.Duration // 重新获取锁间隔
+
+	mu       sync.Mutex
+	watchDog chan struct{} // 看门狗
+	validity time.Time     // 锁的有效期
+}
+
+func (l *RedLock) Lock(ctx context.Context) error {
+	for {
+		err := l.TryLock(ctx)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(constants.LockFailedErr, err) {
+			return err
+		}
+		// 加锁失败后等待随机的时间，避免多个客户端同时重试而都无法获得多数节点
+		wait := l.tryLockInterval/2 + time.Duration(rand.Int63n(int64(l.tryLockInterval)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// 超时
+			return constants.TimeOutErr
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *RedLock) TryLock(ctx context.Context) error {
+	start := time.Now()
+	acquired := l.forEachNode(ctx, func(ctx context.Context, client *redis.Client) (bool, error) {
+		return client.SetNX(ctx, l.resource, l.randomValue, l.ttl).Result()
+	})
+	// 有效期需要扣除加锁的耗时以及节点之间的时钟漂移
+	validity := l.ttl - time.Since(start) - l.drift()
+	if acquired < l.quorum || validity <= 0 {
+		// 释放已经加锁成功的节点，无论这些节点是否真的加锁成功
+		l.forEachNode(context.Background(), func(ctx context.Context, client *redis.Client) (bool, error) {
+			n, err := l.script.Run(ctx, client, []string{l.resource}, l.randomValue).Int()
+			return n == 1, err
+		})
+		return constants.LockFailedErr
+	}
+	l.mu.Lock()
+	l.validity = start.Add(validity)
+	l.watchDog = make(chan struct{})
+	watchDog := l.watchDog
+	l.mu.Unlock()
+	go l.startWatchDog(watchDog)
+	return nil
+}
+
+// startWatchDog 定时在所有节点上延长锁的过期时间，续期成功的节点不足半数时视为锁已丢失
+func (l *RedLock) startWatchDog(watchDog chan struct{}) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			start := time.Now()
+			renewed := l.forEachNode(context.Background(), func(ctx context.Context, client *redis.Client) (bool, error) {
+				n, err := l.renewScript.Run(ctx, client, []string{l.resource}, l.randomValue, l.ttl.Milliseconds()).Int()
+				return n == 1, err
+			})
+			if renewed < l.quorum {
+				return
+			}
+			l.mu.Lock()
+			l.validity = start.Add(l.ttl - time.Since(start) - l.drift())
+			l.mu.Unlock()
+		case <-watchDog:
+			// 已经解锁
+			return
+		}
+	}
+}
+
+func (l *RedLock) UnLock(ctx context.Context) error {
+	l.mu.Lock()
+	if l.watchDog != nil {
+		// 关闭看门狗
+		close(l.watchDog)
+		l.watchDog = nil
+	}
+	l.validity = time.Time{}
+	l.mu.Unlock()
+
+	var errOnce sync.Once
+	var unlockErr error
+	l.forEachNode(ctx, func(ctx context.Context, client *redis.Client) (bool, error) {
+		n, err := l.script.Run(ctx, client, []string{l.resource}, l.randomValue).Int()
+		if err != nil {
+			errOnce.Do(func() {
+				unlockErr = err
+			})
+		}
+		return n == 1, err
+	})
+	return unlockErr
+}
+
+// Validity 获取锁的有效期，在有效期之前锁一定由当前持有者持有；未加锁或已经解锁时返回零值
+func (l *RedLock) Validity() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.validity
+}
+
+// drift 节点之间的时钟漂移，为过期时间的固定比例加上一个很小的常数
+func (l *RedLock) drift() time.Duration {
+	return time.Duration(float64(l.ttl)*clockDriftFactor) + clockDriftConstant
+}
+
+// forEachNode 并发地在每个节点上执行op，返回执行成功的节点数
+// 每个节点的超时时间远小于过期时间，避免在不可用的节点上等待过久
+func (l *RedLock) forEachNode(ctx context.Context, op func(ctx context.Context, client *redis.Client) (bool, error)) int {
+	timeout := l.ttl / nodeTimeoutDivisor
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	count := 0
+	for _, client := range l.clients {
+		wg.Add(1)
+		go func(client *redis.Client) {
+			defer wg.Done()
+			nodeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			ok, err := op(nodeCtx, client)
+			if err == nil && ok {
+				mu.Lock()
+				count++
+				mu.Unlock()
+			}
+		}(client)
+	}
+	wg.Wait()
+	return count
+}
@@ -0,0 +1,209 @@
+package redisUtils
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"testing"
+	"time"
+)
+
+const testResource = "red_lock_test"
+
+// startNodes 启动n个相互独立的redis替身
+func startNodes(t *testing.T, n int) ([]*miniredis.Miniredis, []*redis.Client) {
+	t.Helper()
+	servers := make([]*miniredis.Miniredis, 0, n)
+	clients := make([]*redis.Client, 0, n)
+	for i := 0; i < n; i++ {
+		s := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: s.Addr(), MaxRetries: -1})
+		t.Cleanup(func() {
+			client.Close()
+		})
+		servers = append(servers, s)
+		clients = append(clients, client)
+	}
+	return servers, clients
+}
+
+// heldNodes 统计资源在多少个节点上由value持有
+func heldNodes(servers []*miniredis.Miniredis, value string) int {
+	count := 0
+	for _, s := range servers {
+		if v, err := s.Get(testResource); err == nil && (value == "" || v == value) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRedLockMutualExclusion(t *testing.T) {
+	servers, clients := startNodes(t, 5)
+	locker := NewRedLocker(clients, time.Second, 10*time.Millisecond)
+	ctx := context.Background()
+
+	first := locker.GetLock(testResource)
+	if err := first.TryLock(ctx); err != nil {
+		t.Fatalf("first TryLock: %v", err)
+	}
+	if n := heldNodes(servers, first.(*RedLock).randomValue); n != 5 {
+		t.Fatalf("lock held on %d nodes, want 5", n)
+	}
+	if first.(*RedLock).Validity().Before(time.Now()) {
+		t.Fatalf("validity %v is already expired", first.(*RedLock).Validity())
+	}
+
+	second := locker.GetLock(testResource)
+	if err := second.TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("second TryLock = %v, want LockFailedErr", err)
+	}
+	if err := first.UnLock(ctx); err != nil {
+		t.Fatalf("UnLock: %v", err)
+	}
+	if n := heldNodes(servers, ""); n != 0 {
+		t.Fatalf("lock still held on %d nodes after UnLock", n)
+	}
+	if !first.(*RedLock).Validity().IsZero() {
+		t.Fatalf("validity should be reset after UnLock")
+	}
+	if err := second.TryLock(ctx); err != nil {
+		t.Fatalf("TryLock after UnLock: %v", err)
+	}
+	second.UnLock(ctx)
+}
+
+func TestRedLockQuorum(t *testing.T) {
+	servers, clients := startNodes(t, 5)
+	locker := NewRedLocker(clients, time.Second, 10*time.Millisecond)
+	ctx := context.Background()
+
+	// 5个节点中2个不可用时仍能获得多数
+	servers[0].Close()
+	servers[1].Close()
+	lock := locker.GetLock(testResource)
+	if err := lock.TryLock(ctx); err != nil {
+		t.Fatalf("TryLock with 3 of 5 nodes: %v", err)
+	}
+	lock.UnLock(ctx)
+
+	// 3个节点不可用时无法获得多数
+	servers[2].Close()
+	lock = locker.GetLock(testResource)
+	if err := lock.TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("TryLock with 2 of 5 nodes = %v, want LockFailedErr", err)
+	}
+	if n := heldNodes(servers[3:], ""); n != 0 {
+		t.Fatalf("partial lock not released, still held on %d nodes", n)
+	}
+}
+
+func TestRedLockReleasesPartialAcquisition(t *testing.T) {
+	servers, clients := startNodes(t, 5)
+	locker := NewRedLocker(clients, time.Second, 10*time.Millisecond)
+	ctx := context.Background()
+
+	// 其他客户端在3个节点上持有锁
+	for _, s := range servers[:3] {
+		s.Set(testResource, "other")
+	}
+	lock := locker.GetLock(testResource)
+	if err := lock.TryLock(ctx); !errors.Is(constants.LockFailedErr, err) {
+		t.Fatalf("TryLock = %v, want LockFailedErr", err)
+	}
+	if n := heldNodes(servers, lock.(*RedLock).randomValue); n != 0 {
+		t.Fatalf("partial lock not released, still held on %d nodes", n)
+	}
+	// 不能释放其他客户端持有的锁
+	if n := heldNodes(servers, "other"); n != 3 {
+		t.Fatalf("lock of other client held on %d nodes, want 3", n)
+	}
+}
+
+func TestRedLockUnLockKeepsOtherHolder(t *testing.T) {
+	servers, clients := startNodes(t, 3)
+	locker := NewRedLocker(clients, time.Second, 10*time.Millisecond)
+	ctx := context.Background()
+
+	lock := locker.GetLock(testResource)
+	if err := lock.TryLock(ctx); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	// 锁过期后被其他客户端获得
+	for _, s := range servers {
+		s.Set(testResource, "other")
+	}
+	if err := lock.UnLock(ctx); err != nil {
+		t.Fatalf("UnLock: %v", err)
+	}
+	if n := heldNodes(servers, "other"); n != 3 {
+		t.Fatalf("lock of other client held on %d nodes, want 3", n)
+	}
+}
+
+func TestRedLockWatchDogRenewsAllNodes(t *testing.T) {
+	servers, clients := startNodes(t, 3)
+	lockTTL := 300 * time.Millisecond
+	locker := NewRedLocker(clients, lockTTL, 10*time.Millisecond)
+	ctx := context.Background()
+
+	lock := locker.GetLock(testResource)
+	if err := lock.TryLock(ctx); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	defer lock.UnLock(ctx)
+	// 模拟时间流逝，若没有续期剩余的有效期会越来越短
+	for _, s := range servers {
+		s.FastForward(lockTTL / 2)
+	}
+	time.Sleep(lockTTL / 2)
+	for i, s := range servers {
+		if remain := s.TTL(testResource); remain <= lockTTL/2 {
+			t.Fatalf("node %d not renewed, ttl = %v", i, remain)
+		}
+	}
+	if n := heldNodes(servers, lock.(*RedLock).randomValue); n != 3 {
+		t.Fatalf("lock held on %d nodes, want 3", n)
+	}
+}
+
+func TestRedLockTimeout(t *testing.T) {
+	_, clients := startNodes(t, 3)
+	locker := NewRedLocker(clients, time.Second, 10*time.Millisecond)
+
+	holder := locker.GetLock(testResource)
+	if err := holder.TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	defer holder.UnLock(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := locker.GetLock(testResource).Lock(ctx); !errors.Is(constants.TimeOutErr, err) {
+		t.Fatalf("Lock = %v, want TimeOutErr", err)
+	}
+}
+
+func TestRedLockLockWaitsForRelease(t *testing.T) {
+	_, clients := startNodes(t, 3)
+	locker := NewRedLocker(clients, time.Second, 10*time.Millisecond)
+
+	holder := locker.GetLock(testResource)
+	if err := holder.TryLock(context.Background()); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		holder.UnLock(context.Background())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	waiter := locker.GetLock(testResource)
+	if err := waiter.Lock(ctx); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	waiter.UnLock(context.Background())
+}
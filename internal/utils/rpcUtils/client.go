@@ -0,0 +1,154 @@
+package rpcUtils
+
+import (
+	"context"
+	initialization "github.com/YOJIA-yukino/simple-douyin-backend/init"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/breaker"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/constants"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	breakers    *breaker.Group
+	retryPolicy RetryPolicy
+	clientOnce  sync.Once
+
+	// ErrBreakerOpen 熔断期间返回的错误，调用方可以通过errors.Is判断并降级
+	ErrBreakerOpen = status.Error(codes.Unavailable, constants.ServiceUnavailableErr.Error())
+)
+
+// RetryPolicy 读请求的重试策略
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数，为0时不重试
+	Backoff    time.Duration // 第一次重试前等待的时长，之后每次翻倍
+}
+
+// initClient 按配置创建各目标的熔断器，所有连接共用，每次请求新建的连接也能累计错误率
+func initClient() {
+	clientOnce.Do(func() {
+		conf := initialization.RpcClientConf
+		breakers = breaker.NewGroup(breaker.Options{
+			ErrorRate:        conf.BreakerErrorRate,
+			MinRequests:      conf.BreakerMinRequests,
+			Window:           time.Duration(conf.BreakerWindow) * time.Second,
+			OpenTimeout:      time.Duration(conf.BreakerOpenTimeout) * time.Millisecond,
+			HalfOpenRequests: conf.BreakerHalfOpenRequests,
+			OnStateChange: func(name string, from, to breaker.State) {
+				logger.GlobalLogger.Warn().Str("target", name).Str("from", from.String()).Str("to", to.String()).
+					Msg("grpc circuit breaker state changed")
+			},
+		})
+		retryPolicy = RetryPolicy{
+			MaxRetries: conf.MaxRetries,
+			Backoff:    time.Duration(conf.RetryBackoff) * time.Millisecond,
+		}
+	})
+}
+
+// DialOptions 调用其他层的gRPC服务时使用的连接选项，带有链路追踪、日志字段、指标、熔断与重试的拦截器
+// 链路追踪与指标的拦截器在外层，一次调用(包括重试与熔断的拒绝)记录为一个span与一次调用，链路与请求ID通过metadata传递给被调用方
+func DialOptions() []grpc.DialOption {
+	initClient()
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor(), unaryClientLogFields, unaryClientMetrics, UnaryClientInterceptor(breakers, retryPolicy)),
+		grpc.WithChainStreamInterceptor(otelgrpc.StreamClientInterceptor(), streamClientLogFields, streamClientMetrics, StreamClientInterceptor(breakers)),
+	}
+}
+
+// Breaker 获取调用目标address的熔断器，用于在熔断期间降级
+func Breaker(address string) *breaker.Breaker {
+	initClient()
+	return breakers.Get(address)
+}
+
+// IsFailure 判断gRPC调用的错误是否计入熔断的错误率，业务错误(记录不存在等)不计入
+func IsFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// idempotent 以get开头的方法只读取数据，可以安全地重试
+func idempotent(method string) bool {
+	name := method[strings.LastIndex(method, "/")+1:]
+	return strings.HasPrefix(strings.ToLower(name), "get")
+}
+
+// UnaryClientInterceptor 按目标地址熔断，熔断期间直接返回ErrBreakerOpen
+// 幂等的读请求在连接失败(Unavailable)时按指数退避重试，重试同样经过熔断器
+func UnaryClientInterceptor(group *breaker.Group, policy RetryPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		b := group.Get(cc.Target())
+		maxRetries := 0
+		if idempotent(method) {
+			maxRetries = policy.MaxRetries
+		}
+		backoff := policy.Backoff
+		for attempt := 0; ; attempt++ {
+			done, err := b.Allow()
+			if err != nil {
+				return ErrBreakerOpen
+			}
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			done(!IsFailure(err))
+			if err == nil || status.Code(err) != codes.Unavailable || attempt >= maxRetries {
+				return err
+			}
+			wait := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// StreamClientInterceptor 按目标地址熔断，流的结果在接收结束或出错时计入错误率，流式请求不重试
+func StreamClientInterceptor(group *breaker.Group) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		done, err := group.Get(cc.Target()).Allow()
+		if err != nil {
+			return nil, ErrBreakerOpen
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			done(!IsFailure(err))
+			return nil, err
+		}
+		return &breakerStream{ClientStream: stream, done: done}, nil
+	}
+}
+
+// breakerStream 在第一次接收到EOF或错误时记录流的结果
+type breakerStream struct {
+	grpc.ClientStream
+	done func(success bool)
+	once sync.Once
+}
+
+func (s *breakerStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			s.done(err == io.EOF || !IsFailure(err))
+		})
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package rpcUtils
+
+import (
+	"context"
+	"errors"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/breaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	conn, err := grpc.Dial("passthrough:///video-dao", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func newTestGroup() *breaker.Group {
+	return breaker.NewGroup(breaker.Options{
+		ErrorRate:        0.5,
+		MinRequests:      3,
+		Window:           time.Second,
+		OpenTimeout:      time.Minute,
+		HalfOpenRequests: 1,
+	})
+}
+
+// failingInvoker 前failures次调用返回err，之后成功
+func failingInvoker(failures int, err error, calls *int) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestRetryIdempotentRead(t *testing.T) {
+	conn := newTestConn(t)
+	policy := RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}
+	interceptor := UnaryClientInterceptor(newTestGroup(), policy)
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	calls := 0
+	err := interceptor(context.Background(), "/video.VideoDaoInfo/getVideoByVideoId", nil, nil, conn,
+		failingInvoker(2, unavailable, &calls))
+	if err != nil || calls != 3 {
+		t.Fatalf("read = %v after %d calls, want success after 3 calls", err, calls)
+	}
+
+	// 写请求不重试，重试计入了错误率，使用新的熔断器
+	interceptor = UnaryClientInterceptor(newTestGroup(), policy)
+	calls = 0
+	err = interceptor(context.Background(), "/video.VideoDaoInfo/addVideo", nil, nil, conn,
+		failingInvoker(1, unavailable, &calls))
+	if status.Code(err) != codes.Unavailable || calls != 1 {
+		t.Fatalf("write = %v after %d calls, want Unavailable after 1 call", err, calls)
+	}
+
+	// 业务错误不重试
+	calls = 0
+	notFound := status.Error(codes.NotFound, "not found")
+	err = interceptor(context.Background(), "/video.VideoDaoInfo/getVideoByVideoId", nil, nil, conn,
+		failingInvoker(1, notFound, &calls))
+	if !errors.Is(err, notFound) || calls != 1 {
+		t.Fatalf("read = %v after %d calls, want NotFound after 1 call", err, calls)
+	}
+}
+
+func TestBreakerOpensForTarget(t *testing.T) {
+	conn := newTestConn(t)
+	group := newTestGroup()
+	interceptor := UnaryClientInterceptor(group, RetryPolicy{})
+	internal := status.Error(codes.Internal, "database error")
+	calls := 0
+	for i := 0; i < 3; i++ {
+		interceptor(context.Background(), "/video.VideoDaoInfo/addVideo", nil, nil, conn, failingInvoker(3, internal, &calls))
+	}
+	err := interceptor(context.Background(), "/video.VideoDaoInfo/addVideo", nil, nil, conn, failingInvoker(0, nil, &calls))
+	if !errors.Is(err, ErrBreakerOpen) || calls != 3 {
+		t.Fatalf("call when open = %v after %d calls, want ErrBreakerOpen without calling", err, calls)
+	}
+	if group.Get(conn.Target()).State() != breaker.Open {
+		t.Fatalf("breaker of target not open")
+	}
+	if group.Get("other").State() != breaker.Closed {
+		t.Fatalf("breaker of other target opened")
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	for method, want := range map[string]bool{
+		"/user.UserDaoInfo/getUserInfoByUserId": true,
+		"/video.VideoDaoInfo/GetPublishIdList":  true,
+		"/user.UserDaoInfo/addUser":             false,
+		"/user.UserDaoInfo/updatePassword":      false,
+	} {
+		if got := idempotent(method); got != want {
+			t.Fatalf("idempotent(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
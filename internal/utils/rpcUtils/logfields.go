@@ -0,0 +1,88 @@
+package rpcUtils
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+)
+
+// 请求的日志字段在metadata中的key
+const (
+	requestIdKey = "x-request-id"
+	userIdKey    = "x-user-id"
+	routeKey     = "x-route"
+)
+
+// outgoingLogFields 将ctx中的请求ID、用户ID与路由写入发送给被调用方的metadata
+func outgoingLogFields(ctx context.Context) context.Context {
+	var kv []string
+	if requestId := logger.RequestId(ctx); requestId != "" {
+		kv = append(kv, requestIdKey, requestId)
+	}
+	if userId := logger.UserId(ctx); userId != 0 {
+		kv = append(kv, userIdKey, strconv.FormatInt(userId, 10))
+	}
+	if route := logger.Route(ctx); route != "" {
+		kv = append(kv, routeKey, route)
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// incomingLogFields 从调用方的metadata中恢复请求ID、用户ID与路由，调用方没有传递请求ID时生成一个
+func incomingLogFields(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestId := firstValue(md, requestIdKey)
+	if requestId == "" {
+		requestId = logger.NewRequestId()
+	}
+	ctx = logger.WithRequestId(ctx, requestId)
+	if userId, err := strconv.ParseInt(firstValue(md, userIdKey), 10, 64); err == nil {
+		ctx = logger.WithUserId(ctx, userId)
+	}
+	if route := firstValue(md, routeKey); route != "" {
+		ctx = logger.WithRoute(ctx, route)
+	}
+	return ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func unaryClientLogFields(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingLogFields(ctx), method, req, reply, cc, opts...)
+}
+
+func streamClientLogFields(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+	streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingLogFields(ctx), desc, cc, method, opts...)
+}
+
+func unaryServerLogFields(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(incomingLogFields(ctx), req)
+}
+
+func streamServerLogFields(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	return handler(srv, &contextServerStream{ServerStream: ss, ctx: incomingLogFields(ss.Context())})
+}
+
+// contextServerStream 替换了Context的ServerStream
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,79 @@
+package rpcUtils
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	sideClient = "client"
+	sideServer = "server"
+)
+
+func observe(side, method string, start time.Time, err error) {
+	metrics.GrpcRequests.WithLabelValues(side, method, status.Code(err).String()).Inc()
+	metrics.GrpcDuration.WithLabelValues(side, method).Observe(metrics.Since(start))
+}
+
+// unaryClientMetrics 统计调用方看到的结果，包括重试与熔断
+func unaryClientMetrics(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	observe(sideClient, method, start, err)
+	return err
+}
+
+// streamClientMetrics 在流接收结束或出错时统计
+func streamClientMetrics(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+	streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		observe(sideClient, method, start, err)
+		return nil, err
+	}
+	return &metricsStream{ClientStream: stream, method: method, start: start}, nil
+}
+
+type metricsStream struct {
+	grpc.ClientStream
+	method string
+	start  time.Time
+	once   sync.Once
+}
+
+func (s *metricsStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			result := err
+			if result == io.EOF {
+				result = nil
+			}
+			observe(sideClient, s.method, s.start, result)
+		})
+	}
+	return err
+}
+
+func unaryServerMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observe(sideServer, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamServerMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observe(sideServer, info.FullMethod, start, err)
+	return err
+}
@@ -0,0 +1,39 @@
+package rpcUtils
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestGrpcMetrics(t *testing.T) {
+	conn := newTestConn(t)
+	method := "/user.UserDaoInfo/getUserInfo"
+	calls := 0
+	unaryClientMetrics(context.Background(), method, nil, nil, conn, failingInvoker(0, nil, &calls))
+	unaryClientMetrics(context.Background(), method, nil, nil, conn,
+		failingInvoker(1, status.Error(codes.NotFound, "not found"), new(int)))
+
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	unaryServerMetrics(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "database error")
+	})
+
+	for _, c := range []struct {
+		side, code string
+		want       float64
+	}{
+		{sideClient, codes.OK.String(), 1},
+		{sideClient, codes.NotFound.String(), 1},
+		{sideServer, codes.Internal.String(), 1},
+		{sideServer, codes.OK.String(), 0},
+	} {
+		if got := testutil.ToFloat64(metrics.GrpcRequests.WithLabelValues(c.side, method, c.code)); got != c.want {
+			t.Errorf("grpc_requests_total{side=%q,code=%q} = %v, want %v", c.side, c.code, got, c.want)
+		}
+	}
+}
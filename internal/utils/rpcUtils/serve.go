@@ -0,0 +1,28 @@
+package rpcUtils
+
+import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/lifecycle"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"google.golang.org/grpc"
+	"net"
+)
+
+// Serve 在address上启动一个gRPC服务，register用于注册业务服务，之后注册grpc.health.v1服务
+// 监听或服务出错时把错误发送到errCh，关闭时停止接收请求并等待处理中的请求完成
+func Serve(name, address string, register func(s *grpc.Server), errCh chan<- error) {
+	s := grpc.NewServer(ServerOptions()...)
+	register(s)
+	lifecycle.RegisterHealth(s)
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	logger.GlobalLogger.Info().Msgf("Successfully Listen At port %v", address)
+	lifecycle.OnShutdown(lifecycle.PhaseServer, name, lifecycle.StopGrpcServer(s))
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			errCh <- err
+		}
+	}()
+}
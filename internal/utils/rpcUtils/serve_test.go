@@ -0,0 +1,61 @@
+package rpcUtils
+
+import (
+	"context"
+	"github.com/YOJIA-yukino/simple-douyin-backend/internal/utils/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"net"
+	"testing"
+)
+
+func TestServeRegistersHealth(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := lis.Addr().String()
+	errCh := make(chan error, 2)
+	// 端口被占用时把监听的错误发送到errCh
+	Serve("occupied", address, func(s *grpc.Server) {}, errCh)
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("listen error is nil")
+		}
+	default:
+		t.Fatal("no error for an occupied address")
+	}
+	lis.Close()
+
+	Serve("test", address, func(s *grpc.Server) {}, errCh)
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("status = %v, want SERVING", resp.Status)
+	}
+}
+
+func TestLogFieldsPropagation(t *testing.T) {
+	ctx := logger.WithRoute(logger.WithUserId(logger.WithRequestId(context.Background(), "req-1"), 42), "/douyin/user/")
+	md, _ := metadata.FromOutgoingContext(outgoingLogFields(ctx))
+	received := incomingLogFields(metadata.NewIncomingContext(context.Background(), md))
+	if logger.RequestId(received) != "req-1" || logger.UserId(received) != 42 || logger.Route(received) != "/douyin/user/" {
+		t.Fatalf("received fields = %v %v %v", logger.RequestId(received), logger.UserId(received), logger.Route(received))
+	}
+
+	// 调用方没有请求ID时(例如定时任务)生成一个
+	received = incomingLogFields(context.Background())
+	if logger.RequestId(received) == "" || logger.UserId(received) != 0 {
+		t.Fatal("want a generated request id and no user id")
+	}
+}
@@ -0,0 +1,14 @@
+package rpcUtils
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// ServerOptions 各层的gRPC服务使用的选项，从metadata中恢复调用方的链路与请求ID并为每次调用记录一个span，并统计调用数与耗时
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor(), unaryServerLogFields, unaryServerMetrics),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor(), streamServerLogFields, streamServerMetrics),
+	}
+}
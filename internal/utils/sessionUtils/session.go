@@ -0,0 +1,210 @@
+package sessionUtils
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	sessionPrefix      = "session_"       // 登录会话，hash
+	userSessionsPrefix = "user_sessions_" // 用户的所有会话ID，zset，score为创建时间
+
+	touchInterval = time.Minute // 最近活跃时间的更新间隔，避免每个请求都写入redis
+
+	// touchScript 会话存在且属于该用户时返回1，并在距离上一次更新超过间隔时更新最近活跃时间与IP
+	touchScript = `
+if redis.call("hget", KEYS[1], "user_id") ~= ARGV[1] then
+    return 0
+end
+local last = tonumber(redis.call("hget", KEYS[1], "last_seen") or "0")
+if tonumber(ARGV[2]) - last >= tonumber(ARGV[3]) then
+    redis.call("hset", KEYS[1], "last_seen", ARGV[2], "ip", ARGV[4])
+end
+return 1`
+)
+
+var ErrSessionNotExist = errors.New("session not exist")
+
+// Session 一个登录会话，对应一台设备上的一次登录
+type Session struct {
+	Id         string
+	UserId     int64
+	DeviceName string
+	Ip         string
+	CreateTime time.Time
+	LastSeen   time.Time
+}
+
+// Store 保存在redis中的登录会话，会话在ttl内没有刷新时过期
+type Store struct {
+	client      *redis.Client
+	touch       *redis.Script
+	ttl         time.Duration
+	maxSessions int
+}
+
+// NewStore 创建一个会话存储，maxSessions为每个用户同时存在的会话数上限，为0时不限制
+func NewStore(client *redis.Client, ttl time.Duration, maxSessions int) *Store {
+	return &Store{
+		client:      client,
+		touch:       redis.NewScript(touchScript),
+		ttl:         ttl,
+		maxSessions: maxSessions,
+	}
+}
+
+// Create 为用户创建一个会话，超过会话数上限时使最早创建的会话失效
+func (s *Store) Create(ctx context.Context, userId int64, deviceName, ip string) (*Session, error) {
+	now := time.Now()
+	session := &Session{
+		Id:         uuid.New().String(),
+		UserId:     userId,
+		DeviceName: deviceName,
+		Ip:         ip,
+		CreateTime: now,
+		LastSeen:   now,
+	}
+	key, userKey := sessionPrefix+session.Id, userSessionsKey(userId)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key, map[string]interface{}{
+		"user_id":     userId,
+		"device":      deviceName,
+		"ip":          ip,
+		"create_time": now.UnixMilli(),
+		"last_seen":   now.UnixMilli(),
+	})
+	pipe.Expire(ctx, key, s.ttl)
+	pipe.ZAdd(ctx, userKey, &redis.Z{Score: float64(now.UnixMilli()), Member: session.Id})
+	pipe.Expire(ctx, userKey, s.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	if s.maxSessions > 0 {
+		sessions, err := s.List(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(sessions)-s.maxSessions; i++ {
+			if err = s.Revoke(ctx, userId, sessions[i].Id); err != nil && err != ErrSessionNotExist {
+				return nil, err
+			}
+		}
+	}
+	return session, nil
+}
+
+// Touch 检查会话是否属于该用户且没有失效，并更新最近活跃时间与IP
+func (s *Store) Touch(ctx context.Context, sessionId string, userId int64, ip string) (bool, error) {
+	n, err := s.touch.Run(ctx, s.client, []string{sessionPrefix + sessionId},
+		strconv.FormatInt(userId, 10), time.Now().UnixMilli(), touchInterval.Milliseconds(), ip).Int()
+	return n == 1, err
+}
+
+// Extend 刷新token时延长会话的有效期
+func (s *Store) Extend(ctx context.Context, sessionId string, userId int64) error {
+	pipe := s.client.TxPipeline()
+	pipe.Expire(ctx, sessionPrefix+sessionId, s.ttl)
+	pipe.Expire(ctx, userSessionsKey(userId), s.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// List 按创建时间获取用户所有未失效的会话，并清理已经过期的会话ID
+func (s *Store) List(ctx context.Context, userId int64) ([]*Session, error) {
+	userKey := userSessionsKey(userId)
+	ids, err := s.client.ZRange(ctx, userKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, sessionPrefix+id)
+	}
+	if len(ids) > 0 {
+		if _, err = pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	sessions := make([]*Session, 0, len(ids))
+	expired := make([]interface{}, 0)
+	for i, cmd := range cmds {
+		session := parseSession(ids[i], cmd.Val())
+		if session == nil || session.UserId != userId {
+			expired = append(expired, ids[i])
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		s.client.ZRem(ctx, userKey, expired...)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].CreateTime.Before(sessions[j].CreateTime)
+	})
+	return sessions, nil
+}
+
+// Revoke 使用户的一个会话失效，会话不存在或不属于该用户时返回ErrSessionNotExist
+func (s *Store) Revoke(ctx context.Context, userId int64, sessionId string) error {
+	owner, err := s.client.HGet(ctx, sessionPrefix+sessionId, "user_id").Result()
+	if err == redis.Nil || (err == nil && owner != strconv.FormatInt(userId, 10)) {
+		return ErrSessionNotExist
+	} else if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, sessionPrefix+sessionId)
+	pipe.ZRem(ctx, userSessionsKey(userId), sessionId)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeOthers 使用户除current之外的所有会话失效，返回失效的会话数
+func (s *Store) RevokeOthers(ctx context.Context, userId int64, current string) (int, error) {
+	sessions, err := s.List(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, session := range sessions {
+		if session.Id == current {
+			continue
+		}
+		if err = s.Revoke(ctx, userId, session.Id); err != nil && err != ErrSessionNotExist {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+func userSessionsKey(userId int64) string {
+	return userSessionsPrefix + strconv.FormatInt(userId, 10)
+}
+
+// parseSession 解析redis中的会话，会话已经过期时返回nil
+func parseSession(id string, fields map[string]string) *Session {
+	if len(fields) == 0 {
+		return nil
+	}
+	userId, err := strconv.ParseInt(fields["user_id"], 10, 64)
+	if err != nil {
+		return nil
+	}
+	createTime, _ := strconv.ParseInt(fields["create_time"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(fields["last_seen"], 10, 64)
+	return &Session{
+		Id:         id,
+		UserId:     userId,
+		DeviceName: fields["device"],
+		Ip:         fields["ip"],
+		CreateTime: time.UnixMilli(createTime),
+		LastSeen:   time.UnixMilli(lastSeen),
+	}
+}
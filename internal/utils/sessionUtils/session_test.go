@@ -0,0 +1,107 @@
+package sessionUtils
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, maxSessions int) (*miniredis.Miniredis, *Store) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return mr, NewStore(client, time.Hour, maxSessions)
+}
+
+func TestSessionCreateAndTouch(t *testing.T) {
+	mr, store := newTestStore(t, 0)
+	ctx := context.Background()
+	session, err := store.Create(ctx, 1, "iPhone", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if ok, err := store.Touch(ctx, session.Id, 1, "10.0.0.2"); err != nil || !ok {
+		t.Fatalf("Touch = %v, %v, want true", ok, err)
+	}
+	// 其他用户不能使用该会话
+	if ok, _ := store.Touch(ctx, session.Id, 2, "10.0.0.2"); ok {
+		t.Fatalf("session used by another user")
+	}
+	// 超过更新间隔后更新最近活跃时间与IP
+	mr.HSet(sessionPrefix+session.Id, "last_seen", "0")
+	store.Touch(ctx, session.Id, 1, "10.0.0.3")
+	sessions, err := store.List(ctx, 1)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("List = %v, %v", sessions, err)
+	}
+	if got := sessions[0]; got.DeviceName != "iPhone" || got.Ip != "10.0.0.3" || got.LastSeen.Before(session.CreateTime) {
+		t.Fatalf("session after touch = %+v", got)
+	}
+}
+
+func TestSessionRevoke(t *testing.T) {
+	_, store := newTestStore(t, 0)
+	ctx := context.Background()
+	first, _ := store.Create(ctx, 1, "iPhone", "10.0.0.1")
+	second, _ := store.Create(ctx, 1, "Android", "10.0.0.2")
+	third, _ := store.Create(ctx, 1, "Web", "10.0.0.3")
+
+	if err := store.Revoke(ctx, 2, first.Id); err != ErrSessionNotExist {
+		t.Fatalf("Revoke session of another user = %v, want ErrSessionNotExist", err)
+	}
+	if err := store.Revoke(ctx, 1, first.Id); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if ok, _ := store.Touch(ctx, first.Id, 1, ""); ok {
+		t.Fatalf("revoked session still active")
+	}
+	if n, err := store.RevokeOthers(ctx, 1, third.Id); err != nil || n != 1 {
+		t.Fatalf("RevokeOthers = %v, %v, want 1", n, err)
+	}
+	if ok, _ := store.Touch(ctx, second.Id, 1, ""); ok {
+		t.Fatalf("other session still active")
+	}
+	if ok, _ := store.Touch(ctx, third.Id, 1, ""); !ok {
+		t.Fatalf("current session revoked")
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+	_, store := newTestStore(t, 2)
+	ctx := context.Background()
+	ids := make([]string, 3)
+	for i := range ids {
+		session, err := store.Create(ctx, 1, "device", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		ids[i] = session.Id
+		time.Sleep(2 * time.Millisecond)
+	}
+	sessions, _ := store.List(ctx, 1)
+	if len(sessions) != 2 || sessions[0].Id != ids[1] || sessions[1].Id != ids[2] {
+		t.Fatalf("sessions after exceeding limit = %+v", sessions)
+	}
+	if ok, _ := store.Touch(ctx, ids[0], 1, ""); ok {
+		t.Fatalf("oldest session not revoked")
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	mr, store := newTestStore(t, 0)
+	ctx := context.Background()
+	session, _ := store.Create(ctx, 1, "iPhone", "10.0.0.1")
+	mr.Del(sessionPrefix + session.Id)
+	sessions, err := store.List(ctx, 1)
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("List after expired = %v, %v", sessions, err)
+	}
+	if members, _ := mr.ZMembers(userSessionsPrefix + "1"); len(members) != 0 {
+		t.Fatalf("expired session id not removed: %v", members)
+	}
+}
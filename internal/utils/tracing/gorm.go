@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin 为每一次数据库操作记录一个span，使用db.WithContext(ctx)时作为ctx中span的子span
+// span中记录带占位符的SQL，不记录参数
+type GormPlugin struct{}
+
+var _ gorm.Plugin = GormPlugin{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", beforeGorm("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", afterGorm),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", beforeGorm("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", afterGorm),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", beforeGorm("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", afterGorm),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", beforeGorm("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", afterGorm),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", beforeGorm("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", afterGorm),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", beforeGorm("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", afterGorm),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func beforeGorm(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := Tracer().Start(db.Statement.Context, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperation(operation)))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// afterGorm 结束beforeGorm开始的span，没有对应的span时(例如插件注册之前开始的操作)不做处理
+func afterGorm(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		semconv.DBSQLTable(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,23 @@
+package tracing
+
+import (
+	"context"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const messagingSystem = "kafka"
+
+// StartProducerSpan 开始发送消息的span，返回的ctx用于InjectHeaders
+func StartProducerSpan(ctx context.Context, topic string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, topic+" publish", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystem(messagingSystem), semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationPublish))
+}
+
+// StartConsumerSpan 从消息的headers中恢复发送者的链路，开始处理消息的span
+func StartConsumerSpan(ctx context.Context, topic string, headers map[string]string) (context.Context, trace.Span) {
+	return Tracer().Start(ExtractHeaders(ctx, headers), topic+" process", trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(semconv.MessagingSystem(messagingSystem), semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationProcess))
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterOtlp   = "otlp"   // 通过OTLP gRPC导出，例如导出到jaeger
+	ExporterStdout = "stdout" // 输出到标准输出，用于本地调试
+	ExporterNone   = "none"   // 不记录链路，只在各层之间传递上游的链路
+
+	instrumentationName = "github.com/YOJIA-yukino/simple-douyin-backend"
+)
+
+// Options 链路追踪的配置
+type Options struct {
+	Exporter    string  // ExporterOtlp、ExporterStdout或ExporterNone
+	Endpoint    string  // OTLP gRPC的地址
+	SampleRatio float64 // 新链路的采样比例，已采样的上游链路总是继续采样
+	ServiceName string  // 上报的服务名
+}
+
+// Init 按配置设置全局的TracerProvider与W3C TraceContext传播器，返回的shutdown在退出前调用以导出剩余的span
+// 不导出时仍然设置传播器，使链路可以穿过不上报的程序
+func Init(opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterOtlp:
+		exporter, err = otlptracegrpc.New(context.Background(),
+			otlptracegrpc.WithEndpoint(opts.Endpoint), otlptracegrpc.WithInsecure())
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// InitInMemory 将所有span同步写入内存，用于测试中检查记录的链路
+func InitInMemory() *tracetest.InMemoryExporter {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	return exporter
+}
+
+// Tracer 获取本项目的Tracer，每次从全局的TracerProvider获取，Init之前创建的span不会被记录
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Detach 返回一个不会随请求结束而取消的context，携带ctx中的span，用于请求返回后仍在运行的异步任务
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+// InjectHeaders 将ctx中的链路写入消息的headers，headers为nil时创建
+func InjectHeaders(ctx context.Context, headers map[string]string) map[string]string {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+	return headers
+}
+
+// ExtractHeaders 从消息的headers中恢复发送者的链路
+func ExtractHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("span %q not found in %v", name, spans)
+	return tracetest.SpanStub{}
+}
+
+func TestMessageHeadersPropagation(t *testing.T) {
+	exporter := InitInMemory()
+	ctx, root := Tracer().Start(context.Background(), "request")
+	producerCtx, producer := StartProducerSpan(Detach(ctx), "douyin_favorite")
+	headers := InjectHeaders(producerCtx, nil)
+	producer.End()
+	root.End()
+	if headers["traceparent"] == "" {
+		t.Fatalf("headers = %v, want traceparent", headers)
+	}
+
+	_, consumer := StartConsumerSpan(context.Background(), "douyin_favorite", headers)
+	consumer.End()
+
+	spans := exporter.GetSpans()
+	rootStub := findSpan(t, spans, "request")
+	producerStub := findSpan(t, spans, "douyin_favorite publish")
+	consumerStub := findSpan(t, spans, "douyin_favorite process")
+	if producerStub.Parent.SpanID() != rootStub.SpanContext.SpanID() {
+		t.Fatalf("producer parent = %v, want %v", producerStub.Parent.SpanID(), rootStub.SpanContext.SpanID())
+	}
+	if consumerStub.Parent.SpanID() != producerStub.SpanContext.SpanID() {
+		t.Fatalf("consumer parent = %v, want %v", consumerStub.Parent.SpanID(), producerStub.SpanContext.SpanID())
+	}
+	if consumerStub.SpanKind != trace.SpanKindConsumer || consumerStub.SpanContext.TraceID() != rootStub.SpanContext.TraceID() {
+		t.Fatalf("consumer span = %+v, want consumer in trace %v", consumerStub, rootStub.SpanContext.TraceID())
+	}
+}
+
+func TestExtractHeadersWithoutTrace(t *testing.T) {
+	InitInMemory()
+	ctx := ExtractHeaders(context.Background(), nil)
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("nil headers should not carry a trace")
+	}
+	ctx = ExtractHeaders(context.Background(), map[string]string{"event_id": "1"})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("headers without traceparent should not carry a trace")
+	}
+}
+
+func TestDetach(t *testing.T) {
+	InitInMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, span := Tracer().Start(ctx, "request")
+	defer span.End()
+	detached := Detach(ctx)
+	cancel()
+	if detached.Err() != nil {
+		t.Fatalf("detached context cancelled: %v", detached.Err())
+	}
+	if !trace.SpanContextFromContext(detached).Equal(span.SpanContext()) {
+		t.Fatal("detached context lost the span")
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	exporter := InitInMemory()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	client.AddHook(RedisHook{})
+
+	ctx, root := Tracer().Start(context.Background(), "request")
+	client.Set(ctx, "user_1", "alice", 0)
+	if err := client.Get(ctx, "user_2").Err(); err != redis.Nil {
+		t.Fatalf("Get missing key err = %v, want redis.Nil", err)
+	}
+	client.HIncrBy(ctx, "user_1", "count", 1)
+	pipe := client.Pipeline()
+	pipe.Incr(ctx, "counter")
+	pipe.Incr(ctx, "counter")
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	spans := exporter.GetSpans()
+	rootId := findSpan(t, spans, "request").SpanContext.SpanID()
+	for _, name := range []string{"redis.set", "redis.get", "redis.hincrby", "redis.pipeline"} {
+		span := findSpan(t, spans, name)
+		if span.Parent.SpanID() != rootId {
+			t.Fatalf("%v parent = %v, want %v", name, span.Parent.SpanID(), rootId)
+		}
+	}
+	if status := findSpan(t, spans, "redis.get").Status; status.Code == codes.Error {
+		t.Fatalf("redis.Nil recorded as error: %+v", status)
+	}
+	// 对字符串执行HINCRBY返回WRONGTYPE错误
+	if status := findSpan(t, spans, "redis.hincrby").Status; status.Code != codes.Error {
+		t.Fatalf("redis.hincrby status = %+v, want error", status)
+	}
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// 测试用户不在配置的管理员列表中，管理接口应当拒绝访问
+func TestAdminJobsPermission(t *testing.T) {
+	e := newExpect(t)
+
+	_, token := getTestUserToken(testUserA, e)
+
+	listResp := e.GET("/douyin/admin/jobs/").
+		WithQuery("token", token).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	listResp.Value("status_code").Number().Equal(10206)
+
+	triggerResp := e.POST("/douyin/admin/jobs/trigger/").
+		WithQuery("token", token).WithQuery("name", "favorite_flush").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	triggerResp.Value("status_code").Number().Equal(10206)
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"fmt"
+	"github.com/gavv/httpexpect/v2"
 	"math/rand"
 	"net/http"
 	"testing"
@@ -24,6 +25,48 @@ func TestFeed(t *testing.T) {
 	}
 }
 
+func TestFeedRevokedToken(t *testing.T) {
+	e := newExpect(t)
+	_, token := getTestUserToken(testUserA, e)
+
+	e.GET("/douyin/feed/").WithQuery("token", token).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().Equal(0)
+
+	// 退出登录后的token与refresh token都不能用于获取个性化的视频流，只返回一次token无效的响应
+	e.POST("/douyin/user/logout/").WithQuery("token", token).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().Equal(0)
+	for _, path := range []string{"/douyin/feed/", "/douyin/feed/hot/"} {
+		feedResp := e.GET(path).WithQuery("token", token).
+			Expect().Status(http.StatusOK).
+			JSON().Object()
+		feedResp.Value("status_code").Number().Equal(10107)
+		feedResp.NotContainsKey("video_list")
+	}
+
+	refreshToken := e.POST("/douyin/user/login/").
+		WithQuery("username", testUserA).WithQuery("password", testUserA).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("refresh_token").String().NotEmpty().Raw()
+	e.GET("/douyin/feed/").WithQuery("token", refreshToken).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().Equal(10107)
+}
+
+func TestRequestId(t *testing.T) {
+	e := newExpect(t)
+
+	// 合法的请求ID原样返回
+	e.GET("/douyin/feed/").WithHeader("X-Request-Id", "test-request-1").
+		Expect().Status(http.StatusOK).Header("X-Request-Id").Equal("test-request-1")
+
+	// 没有请求ID或请求ID不合法时生成一个新的
+	e.GET("/douyin/feed/").Expect().Status(http.StatusOK).Header("X-Request-Id").NotEmpty()
+	e.GET("/douyin/feed/").WithHeader("X-Request-Id", "bad id!").
+		Expect().Status(http.StatusOK).Header("X-Request-Id").NotEqual("bad id!")
+}
+
 func TestUserAction(t *testing.T) {
 	e := newExpect(t)
 
@@ -63,6 +106,120 @@ func TestUserAction(t *testing.T) {
 	userInfo.Value("name").String().Length().Gt(0)
 }
 
+func TestLoginLockout(t *testing.T) {
+	e := newExpect(t)
+
+	rand.Seed(time.Now().UnixNano())
+	username := fmt.Sprintf("lockout%d", rand.Intn(65536))
+	login := func() *httpexpect.Object {
+		return e.POST("/douyin/user/login/").
+			WithQuery("username", username).WithQuery("password", "wrong-password").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+	}
+	// 默认配置下同一用户名连续失败5次后锁定
+	for i := 0; i < 5; i++ {
+		login().Value("status_code").Number().Equal(10108)
+	}
+	login().Value("status_code").Number().Equal(10207)
+}
+
+func TestTokenRefreshAndLogout(t *testing.T) {
+	e := newExpect(t)
+	getTestUserToken(testUserA, e)
+
+	loginResp := e.POST("/douyin/user/login/").
+		WithQuery("username", testUserA).WithQuery("password", testUserA).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	loginResp.Value("status_code").Number().Equal(0)
+	refreshToken := loginResp.Value("refresh_token").String().NotEmpty().Raw()
+
+	// refresh token不能代替access token使用
+	e.GET("/douyin/user/").WithQuery("token", refreshToken).
+		Expect().Status(http.StatusOK).
+		JSON().Object().NotContainsKey("user")
+
+	refreshResp := e.POST("/douyin/user/refresh/").
+		WithQuery("refresh_token", refreshToken).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	refreshResp.Value("status_code").Number().Equal(0)
+	token := refreshResp.Value("token").String().NotEmpty().Raw()
+	newRefreshToken := refreshResp.Value("refresh_token").String().NotEmpty().Raw()
+
+	// 轮换后旧的refresh token失效
+	e.POST("/douyin/user/refresh/").
+		WithQuery("refresh_token", refreshToken).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().NotEqual(0)
+
+	// 重复使用旧的refresh token后，同一次登录签发的token全部失效
+	e.GET("/douyin/user/").WithQuery("token", token).
+		Expect().Status(http.StatusOK).
+		JSON().Object().NotContainsKey("user")
+	e.POST("/douyin/user/refresh/").
+		WithQuery("refresh_token", newRefreshToken).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().NotEqual(0)
+
+	// 退出登录后token失效
+	_, token = getTestUserToken(testUserA, e)
+	e.POST("/douyin/user/logout/").WithQuery("token", token).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().Equal(0)
+	e.GET("/douyin/user/").WithQuery("token", token).
+		Expect().Status(http.StatusOK).
+		JSON().Object().NotContainsKey("user")
+}
+
+func TestUserSessions(t *testing.T) {
+	e := newExpect(t)
+	getTestUserToken(testUserB, e)
+
+	login := func(device string) string {
+		return e.POST("/douyin/user/login/").
+			WithQuery("username", testUserB).WithQuery("password", testUserB).
+			WithQuery("device_name", device).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("token").String().NotEmpty().Raw()
+	}
+	phoneToken := login("phone")
+	webToken := login("web")
+
+	sessionsResp := e.GET("/douyin/user/sessions/").WithQuery("token", webToken).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	sessionsResp.Value("status_code").Number().Equal(0)
+	var phoneSession string
+	for _, element := range sessionsResp.Value("session_list").Array().Iter() {
+		session := element.Object()
+		if session.Value("device_name").String().Raw() == "phone" {
+			session.Value("current").Boolean().False()
+			phoneSession = session.Value("session_id").String().Raw()
+		}
+	}
+	if phoneSession == "" {
+		t.Fatalf("session of phone not listed")
+	}
+
+	// 在web上使phone的登录失效
+	e.POST("/douyin/user/sessions/revoke/").WithQuery("token", webToken).WithQuery("session_id", phoneSession).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().Equal(0)
+	e.GET("/douyin/user/sessions/").WithQuery("token", phoneToken).
+		Expect().Status(http.StatusOK).
+		JSON().Object().NotContainsKey("session_list")
+	e.GET("/douyin/user/sessions/").WithQuery("token", webToken).
+		Expect().Status(http.StatusOK).
+		JSON().Object().Value("status_code").Number().Equal(0)
+}
+
 func TestPublish(t *testing.T) {
 	e := newExpect(t)
 
@@ -94,3 +251,48 @@ func TestPublish(t *testing.T) {
 		video.Value("cover_url").String().NotEmpty()
 	}
 }
+
+func TestPublishListPaging(t *testing.T) {
+	e := newExpect(t)
+
+	userId, token := getTestUserToken(testUserA, e)
+
+	for i := 0; i < 2; i++ {
+		publishResp := e.POST("/douyin/publish/action/").
+			WithMultipart().
+			WithFile("data", "../public/bear.mp4").
+			WithFormField("token", token).
+			WithFormField("title", "Bear").
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		publishResp.Value("status_code").Number().Equal(0)
+	}
+
+	firstPage := e.GET("/douyin/publish/list/").
+		WithQuery("user_id", userId).WithQuery("token", token).WithQuery("limit", 1).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	firstPage.Value("status_code").Number().Equal(0)
+	firstPage.Value("video_list").Array().Length().Equal(1)
+	firstPage.Value("has_more").Boolean().True()
+	firstId := firstPage.Value("video_list").Array().First().Object().Value("id").Number().Raw()
+	cursor := firstPage.Value("next_cursor").String().NotEmpty().Raw()
+
+	secondPage := e.GET("/douyin/publish/list/").
+		WithQuery("user_id", userId).WithQuery("token", token).WithQuery("limit", 1).WithQuery("cursor", cursor).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	secondPage.Value("status_code").Number().Equal(0)
+	secondPage.Value("video_list").Array().Length().Equal(1)
+	secondPage.Value("video_list").Array().First().Object().Value("id").Number().NotEqual(firstId)
+
+	badResp := e.GET("/douyin/publish/list/").
+		WithQuery("user_id", userId).WithQuery("token", token).WithQuery("cursor", "not-a-cursor").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	badResp.Value("status_code").Number().NotEqual(0)
+}
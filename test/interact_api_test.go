@@ -1,6 +1,7 @@
 package test
 
 import (
+	"github.com/YOJIA-yukino/simple-douyin-backend/api"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
@@ -41,6 +42,23 @@ func TestFavorite(t *testing.T) {
 	}
 }
 
+func TestFavoriteUnknownAction(t *testing.T) {
+	e := newExpect(t)
+
+	feedResp := e.GET("/douyin/feed/").Expect().Status(http.StatusOK).JSON().Object()
+	feedResp.Value("status_code").Number().Equal(0)
+	videoId := feedResp.Value("video_list").Array().First().Object().Value("id").Number().Raw()
+
+	_, token := getTestUserToken(testUserA, e)
+
+	favoriteResp := e.POST("/douyin/favorite/action/").
+		WithQuery("token", token).WithQuery("video_id", videoId).WithQuery("action_type", 3).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	favoriteResp.Value("status_code").Number().NotEqual(0)
+}
+
 func TestComment(t *testing.T) {
 	e := newExpect(t)
 
@@ -91,3 +109,95 @@ func TestComment(t *testing.T) {
 		JSON().Object()
 	delCommentResp.Value("status_code").Number().Equal(0)
 }
+
+func TestHotFeed(t *testing.T) {
+	e := newExpect(t)
+
+	hotResp := e.GET("/douyin/feed/hot/").WithQuery("offset", 0).WithQuery("count", 1).
+		Expect().Status(http.StatusOK).JSON().Object()
+	hotResp.Value("status_code").Number().Equal(0)
+	hotResp.Value("video_list").Array().Length().Equal(1)
+	hotResp.Value("next_offset").Number().Equal(1)
+	hotResp.ContainsKey("has_more")
+
+	for _, element := range hotResp.Value("video_list").Array().Iter() {
+		video := element.Object()
+		video.ContainsKey("id")
+		video.ContainsKey("author")
+		video.Value("play_url").String().NotEmpty()
+		video.Value("cover_url").String().NotEmpty()
+	}
+
+	badResp := e.GET("/douyin/feed/hot/").WithQuery("offset", -1).
+		Expect().Status(http.StatusOK).JSON().Object()
+	badResp.Value("status_code").Number().NotEqual(0)
+}
+
+func TestPlay(t *testing.T) {
+	e := newExpect(t)
+
+	feedResp := e.GET("/douyin/feed/").Expect().Status(http.StatusOK).JSON().Object()
+	feedResp.Value("status_code").Number().Equal(0)
+	feedResp.Value("video_list").Array().Length().Gt(0)
+	firstVideo := feedResp.Value("video_list").Array().First().Object()
+	videoId := firstVideo.Value("id").Number().Raw()
+
+	_, token := getTestUserToken(testUserA, e)
+
+	for _, event := range []struct {
+		eventType     int
+		watchDuration int
+	}{{1, 0}, {2, 3000}, {3, 15000}} {
+		playResp := e.POST("/douyin/video/play/").
+			WithQuery("token", token).WithQuery("video_id", videoId).
+			WithQuery("event_type", event.eventType).WithQuery("watch_duration", event.watchDuration).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		playResp.Value("status_code").Number().Equal(0)
+	}
+
+	unknownResp := e.POST("/douyin/video/play/").
+		WithQuery("token", token).WithQuery("video_id", videoId).WithQuery("event_type", 9).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	unknownResp.Value("status_code").Number().NotEqual(0)
+
+	outOfRangeResp := e.POST("/douyin/video/play/").
+		WithQuery("token", token).WithQuery("video_id", videoId).
+		WithQuery("event_type", 2).WithQuery("watch_duration", -1).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	outOfRangeResp.Value("status_code").Number().Equal(api.InputFormatCheckErr)
+}
+
+func TestCreatorStats(t *testing.T) {
+	e := newExpect(t)
+
+	_, token := getTestUserToken(testUserA, e)
+
+	statsResp := e.GET("/douyin/creator/stats/").
+		WithQuery("token", token).WithQuery("start_date", "2023-01-01").WithQuery("end_date", "2023-01-07").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	statsResp.Value("status_code").Number().Equal(0)
+	statsResp.Value("creator_series").Array().Length().Equal(7)
+	statsResp.Value("creator_series").Array().First().Object().Value("date").String().Equal("2023-01-01")
+
+	csvResp := e.GET("/douyin/creator/stats/").
+		WithQuery("token", token).WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK)
+	csvResp.Header("Content-Type").Contains("text/csv")
+	csvResp.Body().Contains("scope,video_id,date")
+
+	badResp := e.GET("/douyin/creator/stats/").
+		WithQuery("token", token).WithQuery("start_date", "2023-01-07").WithQuery("end_date", "2023-01-01").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	badResp.Value("status_code").Number().NotEqual(0)
+}